@@ -0,0 +1,229 @@
+// Command couponctl inspects, verifies and compiles coupon files offline.
+//
+//	couponctl stats                 per-file line and token counts
+//	couponctl common [-limit N]     codes found in at least -required files
+//	couponctl check CODE            per-file breakdown for one code (exit 1 if invalid)
+//	couponctl compile [-o FILE]     write the index the server loads with PROMO_INDEX_FILE
+//
+// Files default to COUPON_DIR and PROMO_FILES, as for the server.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/config"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/promovalidator"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "stats":
+		err = runStats(args)
+	case "common":
+		err = runCommon(ctx, args)
+	case "check":
+		err = runCheck(ctx, args)
+	case "compile":
+		err = runCompile(args)
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "couponctl: unknown command %q\n\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+
+	var invalid errInvalid
+	switch {
+	case errors.As(err, &invalid):
+		os.Exit(1)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "couponctl: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage: couponctl <command> [flags]
+
+commands:
+  stats              per-file line count and distinct valid-length tokens
+  common             list codes found in at least -required files
+  check CODE         show which files contain CODE (exit status 1 if invalid)
+  compile            compile the files into an index for PROMO_INDEX_FILE
+
+run "couponctl <command> -h" for the command's flags
+`)
+}
+
+// errInvalid signals a negative check result (exit status 1, no message).
+type errInvalid struct{}
+
+func (errInvalid) Error() string { return "invalid" }
+
+// commonFlags registers the validator settings shared by every command.
+// Defaults match the server (see cmd/server).
+func commonFlags(fs *flag.FlagSet) func() promovalidator.Config {
+	cfg := config.Load()
+	dir := fs.String("dir", cfg.CouponDir, "coupon directory")
+	files := fs.String("files", strings.Join(cfg.PromoFiles, ","), "comma-separated coupon files or glob patterns")
+	minLen := fs.Int("min", 8, "minimum code length")
+	maxLen := fs.Int("max", 10, "maximum code length")
+	required := fs.Int("required", 2, "files a code must appear in")
+	return func() promovalidator.Config {
+		var names []string
+		for _, f := range strings.Split(*files, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				names = append(names, f)
+			}
+		}
+		return promovalidator.Config{Dir: *dir, Files: names, MinLen: *minLen, MaxLen: *maxLen, RequiredHits: *required}
+	}
+}
+
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	cfgFn := commonFlags(fs)
+	asJSON := fs.Bool("json", false, "print JSON")
+	_ = fs.Parse(args)
+	cfg := cfgFn()
+
+	reports := promovalidator.InspectFiles(cfg)
+	if *asJSON {
+		return printJSON(reports)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tFORMAT\tSIZE\tLINES\tTOKENS\tDISTINCT\tERROR")
+	for _, r := range reports {
+		if r.Error != "" && r.Format == "" {
+			fmt.Fprintf(tw, "%s\t-\t%d\t-\t-\t-\t%s\n", r.Name, r.Size, r.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", r.Name, r.Format, r.Size, r.Lines, r.Tokens, r.Distinct, r.Error)
+	}
+	return tw.Flush()
+}
+
+func runCommon(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("common", flag.ExitOnError)
+	cfgFn := commonFlags(fs)
+	limit := fs.Int("limit", 0, "stop after this many codes (0 = all)")
+	_ = fs.Parse(args)
+	cfg := cfgFn()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	n := 0
+	err := promovalidator.CommonCodes(ctx, cfg, func(code string) {
+		if *limit > 0 && n >= *limit {
+			cancel()
+			return
+		}
+		fmt.Fprintln(out, code)
+		n++
+	})
+	if err != nil && !(errors.Is(err, context.Canceled) && *limit > 0 && n >= *limit) {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d codes in at least %d files\n", n, cfg.RequiredHits)
+	return nil
+}
+
+func runCheck(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	cfgFn := commonFlags(fs)
+	asJSON := fs.Bool("json", false, "print JSON")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("check: exactly one CODE is required")
+	}
+	cfg := cfgFn()
+
+	res, matches := promovalidator.LocateCode(ctx, cfg, strings.TrimSpace(fs.Arg(0)))
+	if *asJSON {
+		if err := printJSON(struct {
+			Result promovalidator.Result      `json:"result"`
+			Files  []promovalidator.FileMatch `json:"files"`
+		}{res, matches}); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, m := range matches {
+			status := "not found"
+			switch {
+			case m.Found:
+				status = "found"
+			case m.Error != "":
+				status = "error: " + m.Error
+			case res.Reason == promovalidator.ReasonLength || res.Reason == promovalidator.ReasonCharset:
+				status = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\n", m.Name, status)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		verdict := "INVALID"
+		if res.Valid {
+			verdict = "VALID"
+		}
+		fmt.Printf("%s: %s (%s)\n", res.Code, verdict, res.Message())
+	}
+	if !res.Valid {
+		return errInvalid{}
+	}
+	return nil
+}
+
+func runCompile(args []string) error {
+	fs := flag.NewFlagSet("compile", flag.ExitOnError)
+	cfgFn := commonFlags(fs)
+	out := fs.String("o", "", "output file (default: coupons.idx in -dir)")
+	_ = fs.Parse(args)
+	cfg := cfgFn()
+	if *out == "" {
+		*out = filepath.Join(cfg.Dir, "coupons.idx")
+	}
+
+	start := time.Now()
+	st, err := promovalidator.WriteIndexFile(cfg, *out)
+	if err != nil {
+		return err
+	}
+	for _, f := range st.Files {
+		fmt.Printf("%-30s %12d codes\n", f.Name, f.Codes)
+	}
+	fmt.Printf("wrote %s: %d codes, %d bytes in memory, took %s\n", *out, st.TotalCodes, st.MemoryBytes, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -1,15 +1,24 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+	_ "time/tzdata" // STORE_TIMEZONE works in images without a zoneinfo database
 
 	"github.com/Niraj-Shaw/orderfoodonline/internal/config"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/discount"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/idempotency"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/promoguard"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/promovalidator"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/repository/memory"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/service"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/slots"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/tax"
 	transporthttp "github.com/Niraj-Shaw/orderfoodonline/internal/transport/http"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/util"
 )
@@ -21,31 +30,181 @@ func main() {
 	// config
 	cfg := config.Load()
 
+	// amounts without an explicit currency (product seeds, JSON numbers) use this
+	if err := money.ValidCurrency(cfg.Currency); err != nil {
+		log.Fatalf("currency configuration error: %v", err)
+	}
+	money.DefaultCurrency = cfg.Currency
+	taxRounding, err := money.ParseRoundingMode(cfg.TaxRounding)
+	if err != nil {
+		log.Fatalf("tax configuration error: %v", err)
+	}
+
 	// repositories (in-memory)
 	productRepo := memory.NewProductRepo(seedProducts()) // expects []models.Product
 	orderRepo := memory.NewOrderRepo()
+	redemptionRepo := memory.NewRedemptionRepo()
 
 	// promo validator (case-sensitive)
-	validator := promovalidator.NewValidatorService(promovalidator.Config{
+	validator, err := promovalidator.New(promovalidator.Config{
 		Dir:                      cfg.CouponDir,
-		Files:                    []string{"couponbase1.gz", "couponbase2.gz", "couponbase3.gz"},
+		Files:                    cfg.PromoFiles,
 		MinLen:                   8,
 		MaxLen:                   10,
 		RequiredHits:             2,
 		MaxConcurrentValidations: 2,
+		Mode:                     promovalidator.Mode(cfg.PromoMode),
+		MaxIndexBytes:            int64(cfg.PromoMaxIndexMiB) << 20,
+		IndexFile:                cfg.PromoIndexFile,
+		Prefilter:                cfg.PromoPrefilter,
+		PrefilterFPRate:          cfg.PromoPrefilterFPRate,
+		PrefilterMaxBytes:        int64(cfg.PromoPrefilterMaxMiB) << 20,
+		ReloadInterval:           cfg.PromoReloadInterval,
+		FilePolicy:               promovalidator.FilePolicy(cfg.PromoFilePolicy),
+		ScheduleFile:             cfg.PromoScheduleFile,
+		PositiveCacheSize:        cfg.PromoCacheSize,
+		NegativeCacheSize:        cfg.PromoNegativeCacheSize,
+		PositiveCacheTTL:         cfg.PromoCacheTTL,
+		NegativeCacheTTL:         cfg.PromoNegativeCacheTTL,
 	})
+	if err != nil {
+		log.Fatalf("validator configuration error: %v", err)
+	}
 
 	if err := validator.LoadCouponFiles(); err != nil {
 		log.Fatalf("validator configuration error: %v", err)
 	}
-	log.Infof("validator configured for directory: %s (files will be scanned on-demand)", cfg.CouponDir)
+	if ix, ok := validator.(promovalidator.IndexReporter); ok {
+		st := ix.IndexStats()
+		if st.SourceError != "" {
+			log.Warnf("compiled index %s not used: %s", cfg.PromoIndexFile, st.SourceError)
+		}
+		source := "coupon files"
+		if st.Source != "" {
+			source = st.Source
+		}
+		log.Infof("validator index built for directory: %s from %s (%d codes, %d bytes, took %s)",
+			cfg.CouponDir, source, st.TotalCodes, st.MemoryBytes, st.BuildTime)
+	} else {
+		log.Infof("validator configured for directory: %s (files will be scanned on-demand)", cfg.CouponDir)
+	}
+	if sr, ok := validator.(promovalidator.StatusReporter); ok {
+		if pf := sr.Status().Prefilter; pf != nil {
+			log.Infof("promo prefilter built: %d files, %d bytes", len(pf.Files), pf.MemoryBytes)
+		}
+	}
+	if hr, ok := validator.(promovalidator.HealthReporter); ok {
+		for _, f := range hr.Health() {
+			if f.Status != promovalidator.FileOK {
+				log.Warnf("coupon file %s is %s: %s (policy: %s)", f.Name, f.Status, f.LastError, cfg.PromoFilePolicy)
+			}
+		}
+	}
+
+	// hot reload: poll coupon files and swap state when they change
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if rl, ok := validator.(promovalidator.Reloader); ok && cfg.PromoReloadInterval > 0 {
+		log.Infof("watching coupon files every %s", cfg.PromoReloadInterval)
+		go rl.Watch(watchCtx)
+	}
+
+	if cfg.TaxPercent < 0 {
+		log.Fatalf("tax configuration error: TAX_PERCENT must be >= 0, got %g", cfg.TaxPercent)
+	}
+	taxMode, err := tax.ParseMode(cfg.TaxMode)
+	if err != nil {
+		log.Fatalf("tax configuration error: TAX_MODE: %v", err)
+	}
+	taxRules := tax.Rules{Mode: taxMode, DefaultRate: cfg.TaxPercent, Rounding: taxRounding}
+	if cfg.TaxRulesFile != "" {
+		rules, err := tax.LoadFile(cfg.TaxRulesFile, taxRules)
+		if err != nil {
+			log.Fatalf("tax configuration error: %v", err)
+		}
+		log.Infof("loaded %d tax classes from %s (%s prices)", len(rules.Classes), cfg.TaxRulesFile, rules.Mode)
+		taxRules = *rules
+	}
+	products, err := productRepo.GetAll()
+	if err != nil {
+		log.Fatalf("tax configuration error: %v", err)
+	}
+	if err := taxRules.CheckProducts(products); err != nil {
+		log.Fatalf("tax configuration error: %v", err)
+	}
+	limits := service.OrderLimits{
+		MergeDuplicates: cfg.OrderMergeDuplicates,
+		MaxQuantity:     cfg.OrderMaxQuantity,
+		MaxItems:        cfg.OrderMaxItems,
+	}
+	if cfg.OrderMaxTotal != "" {
+		if limits.MaxTotal, err = money.Parse(cfg.OrderMaxTotal, ""); err != nil {
+			log.Fatalf("order limits configuration error: ORDER_MAX_TOTAL: %v", err)
+		}
+	}
+
+	// discount rules attached to promo codes (optional)
+	orderOpts := []service.OrderOption{
+		service.WithTaxRules(taxRules),
+		service.WithInventory(productRepo), // stock is reserved per order
+		service.WithOrderLimits(limits),
+	}
+	if cfg.DiscountRulesFile != "" {
+		engine, err := discount.LoadFile(cfg.DiscountRulesFile)
+		if err != nil {
+			log.Fatalf("discount rules error: %v", err)
+		}
+		log.Infof("loaded %d discount rules from %s", len(engine.Rules()), cfg.DiscountRulesFile)
+		orderOpts = append(orderOpts, service.WithDiscounts(engine), service.WithRedemptions(redemptionRepo))
+	}
+
+	// time slots for scheduled orders (optional)
+	if cfg.SlotLength > 0 {
+		hours, err := slots.ParseHours(cfg.OpeningHours)
+		if err != nil {
+			log.Fatalf("slot configuration error: OPENING_HOURS: %v", err)
+		}
+		loc, err := time.LoadLocation(cfg.StoreTimezone)
+		if err != nil {
+			log.Fatalf("slot configuration error: STORE_TIMEZONE: %v", err)
+		}
+		catalogue, err := slots.New(slots.Config{
+			Hours:    hours,
+			Length:   cfg.SlotLength,
+			Capacity: cfg.SlotCapacity,
+			LeadTime: cfg.SlotLeadTime,
+			Horizon:  cfg.SlotHorizon,
+			Location: loc,
+		})
+		if err != nil {
+			log.Fatalf("slot configuration error: %v", err)
+		}
+		orderOpts = append(orderOpts, service.WithSlots(catalogue))
+	}
 
 	// services
 	productSvc := service.NewProductService(productRepo)
-	orderSvc := service.NewOrderService(productSvc, orderRepo, validator)
+	orderSvc := service.NewOrderService(productSvc, orderRepo, validator, orderOpts...)
+
+	// brute-force protection: lock out API keys / client IPs guessing promo codes
+	guard := promoguard.New(promoguard.Config{
+		MaxFailures:    cfg.PromoGuardMaxFailures,
+		MaxKeyFailures: cfg.PromoGuardKeyFailures,
+		Window:         cfg.PromoGuardWindow,
+		Lockout:        cfg.PromoGuardLockout,
+		MaxLockout:     cfg.PromoGuardMaxLockout,
+		OnLockout: func(ev promoguard.LockoutEvent) {
+			log.Warnf("promo lockout: %s %s after %d failures (level %d, %s)",
+				ev.Kind, ev.Subject, ev.Failures, ev.Level, ev.Duration)
+		},
+	})
 
 	// http server
-	srv := transporthttp.NewServer(&cfg, productSvc, orderSvc, log)
+	handlerOpts := []transporthttp.HandlerOption{transporthttp.WithPromoGuard(guard, cfg.PromoGuardTrustProxy)}
+	if cfg.IdempotencyTTL > 0 {
+		handlerOpts = append(handlerOpts, transporthttp.WithIdempotency(idempotency.NewStore(cfg.IdempotencyTTL)))
+	}
+	srv := transporthttp.NewServer(&cfg, productSvc, orderSvc, validator, log, handlerOpts...)
 
 	// start
 	go func() {
@@ -64,6 +223,7 @@ func main() {
 	<-quit
 
 	log.Infof("shutting down…")
+	stopWatch()
 	if err := srv.Stop(); err != nil {
 		log.Errorf("shutdown error: %v", err)
 	}
@@ -73,15 +233,37 @@ func main() {
 // seedProducts returns your initial menu.
 func seedProducts() []models.Product {
 	return []models.Product{
-		{ID: "1", Name: "Chicken Waffle", Price: 12.99, Category: "Waffle"},
-		{ID: "2", Name: "Belgian Waffle", Price: 9.99, Category: "Waffle"},
-		{ID: "3", Name: "Caesar Salad", Price: 8.99, Category: "Salad"},
-		{ID: "4", Name: "Grilled Chicken", Price: 15.99, Category: "Main Course"},
-		{ID: "5", Name: "Pasta Carbonara", Price: 13.99, Category: "Pasta"},
-		{ID: "6", Name: "Chocolate Cake", Price: 6.99, Category: "Dessert"},
-		{ID: "7", Name: "Coffee", Price: 3.99, Category: "Beverage"},
-		{ID: "8", Name: "Orange Juice", Price: 4.99, Category: "Beverage"},
-		{ID: "9", Name: "Fish Tacos", Price: 11.99, Category: "Mexican"},
-		{ID: "10", Name: "Burger Deluxe", Price: 14.99, Category: "Burger"},
+		{ID: "1", Name: "Chicken Waffle", Price: money.MustParse("12.99", ""), Category: "Waffle"},
+		{ID: "2", Name: "Belgian Waffle", Price: money.MustParse("9.99", ""), Category: "Waffle"},
+		{ID: "3", Name: "Caesar Salad", Price: money.MustParse("8.99", ""), Category: "Salad"},
+		{ID: "4", Name: "Grilled Chicken", Price: money.MustParse("15.99", ""), Category: "Main Course"},
+		{ID: "5", Name: "Pasta Carbonara", Price: money.MustParse("13.99", ""), Category: "Pasta"},
+		{ID: "6", Name: "Chocolate Cake", Price: money.MustParse("6.99", ""), Category: "Dessert", Stock: units(12)},
+		{ID: "7", Name: "Coffee", Price: money.MustParse("3.99", ""), Category: "Beverage", Options: []models.OptionGroup{
+			{ID: "size", Name: "Size", Max: 1, Options: []models.Option{
+				{ID: "small", Name: "Small", Price: money.MustParse("-0.50", "")},
+				{ID: "large", Name: "Large", Price: money.MustParse("1.00", "")},
+			}},
+			{ID: "milk", Name: "Milk", Max: 1, Options: []models.Option{
+				{ID: "whole", Name: "Whole milk"},
+				{ID: "oat", Name: "Oat milk", Price: money.MustParse("0.60", "")},
+			}},
+		}},
+		{ID: "8", Name: "Orange Juice", Price: money.MustParse("4.99", ""), Category: "Beverage", Stock: units(40)},
+		{ID: "9", Name: "Fish Tacos", Price: money.MustParse("11.99", ""), Category: "Mexican"},
+		{ID: "10", Name: "Burger Deluxe", Price: money.MustParse("14.99", ""), Category: "Burger", Options: []models.OptionGroup{
+			{ID: "extras", Name: "Extras", Max: 3, Options: []models.Option{
+				{ID: "cheese", Name: "Extra cheese", Price: money.MustParse("1.50", "")},
+				{ID: "bacon", Name: "Bacon", Price: money.MustParse("2.00", "")},
+				{ID: "egg", Name: "Fried egg", Price: money.MustParse("1.25", "")},
+			}},
+			{ID: "remove", Name: "Remove", Options: []models.Option{
+				{ID: "onions", Name: "No onions"},
+				{ID: "pickles", Name: "No pickles"},
+			}},
+		}},
 	}
 }
+
+// units is a stock count for seedProducts; products without one are made to order.
+func units(n int) *int { return &n }
@@ -3,21 +3,116 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all application configuration values.
 type Config struct {
 	ServerAddr string // e.g. ":8080"
 	APIKey     string // API key required for /order requests
-	CouponDir  string // directory containing coupon .gz files
+	CouponDir  string // directory containing coupon files
+
+	PromoFiles []string // coupon file names or glob patterns, relative to CouponDir
+
+	PromoMode        string // promo validator: "streaming" (scan on demand) or "indexed" (load into memory)
+	PromoMaxIndexMiB int    // indexed mode: refuse to start if the index exceeds this many MiB (0 = unbounded)
+	PromoIndexFile   string // indexed mode: compiled index (couponctl compile) in CouponDir, used while current
+
+	PromoPrefilter       bool    // streaming mode: per-file Bloom filters to skip scans for absent codes
+	PromoPrefilterFPRate float64 // target false-positive rate of the filters
+	PromoPrefilterMaxMiB int     // cap on the filters' total size in MiB (0 = unbounded)
+
+	PromoReloadInterval time.Duration // poll coupon files for changes this often (0 = no hot reload)
+	PromoFilePolicy     string        // unreadable coupon files: "tolerate", "fail_closed" or "require_all"
+	PromoScheduleFile   string        // promotion schedule (JSON) in CouponDir; a missing file means no restrictions
+
+	PromoCacheSize         int           // streaming mode: max cached valid codes
+	PromoNegativeCacheSize int           // streaming mode: max cached invalid codes
+	PromoCacheTTL          time.Duration // streaming mode: TTL for cached valid codes (0 = until reload)
+	PromoNegativeCacheTTL  time.Duration // streaming mode: TTL for cached invalid codes
+
+	PromoGuardMaxFailures int           // failed promo codes per client IP before a lockout (0 = no lockouts)
+	PromoGuardKeyFailures int           // failed promo codes per api_key before a lockout (0 = as per IP)
+	PromoGuardWindow      time.Duration // failures are counted over this window
+	PromoGuardLockout     time.Duration // first lockout; doubles on each repeat lockout
+	PromoGuardMaxLockout  time.Duration // cap on the escalating lockout
+	PromoGuardTrustProxy  bool          // take the client IP from X-Forwarded-For (only behind a trusted proxy)
+
+	IdempotencyTTL time.Duration // how long Idempotency-Key responses are replayed (0 = header ignored)
+
+	OrderMergeDuplicates bool   // merge lines of the same product into one
+	OrderMaxQuantity     int    // max quantity per line (0 = unlimited)
+	OrderMaxItems        int    // max distinct products per order (0 = unlimited)
+	OrderMaxTotal        string // max order total, e.g. "500.00" ("" = unlimited)
+
+	OpeningHours  string        // weekly hours for scheduled orders, e.g. "mon-fri 11:00-22:00; sat 12:00-23:00"
+	SlotLength    time.Duration // length of a scheduling slot (0 = orders cannot be scheduled)
+	SlotCapacity  int           // scheduled orders per slot (0 = unlimited)
+	SlotLeadTime  time.Duration // earliest slot starts at least this long from now
+	SlotHorizon   time.Duration // latest slot starts at most this long from now
+	StoreTimezone string        // IANA time zone of the opening hours
+
+	DiscountRulesFile string  // JSON discount rules for promo codes ("" = codes carry no discount)
+	TaxPercent        float64 // tax rate of products without a tax class, in percent
+	TaxRounding       string  // rounding of each line's tax: "half_up", "half_even", "down" or "up"
+	TaxMode           string  // "exclusive" (tax added to prices) or "inclusive" (prices contain tax)
+	TaxRulesFile      string  // JSON tax classes and category mapping ("" = TAX_PERCENT on everything)
+	Currency          string  // ISO 4217 currency of prices and amounts given without one
 }
 
 // Load builds a Config struct using environment variables with fallbacks.
 func Load() Config {
 	cfg := Config{
-		ServerAddr: getEnv("SERVER_ADDR", ":8080"),
-		APIKey:     getEnv("API_KEY", "apitest"),
-		CouponDir:  getEnv("COUPON_DIR", "./data"),
+		ServerAddr:       getEnv("SERVER_ADDR", ":8080"),
+		APIKey:           getEnv("API_KEY", "apitest"),
+		CouponDir:        getEnv("COUPON_DIR", "./data"),
+		PromoFiles:       getEnvList("PROMO_FILES", []string{"couponbase1.gz", "couponbase2.gz", "couponbase3.gz"}),
+		PromoMode:        getEnv("PROMO_MODE", "streaming"),
+		PromoMaxIndexMiB: getEnvInt("PROMO_MAX_INDEX_MIB", 0),
+		PromoIndexFile:   getEnv("PROMO_INDEX_FILE", ""),
+
+		PromoPrefilter:       getEnvBool("PROMO_PREFILTER", false),
+		PromoPrefilterFPRate: getEnvFloat("PROMO_PREFILTER_FP_RATE", 0.01),
+		PromoPrefilterMaxMiB: getEnvInt("PROMO_PREFILTER_MAX_MIB", 0),
+
+		PromoReloadInterval: getEnvDuration("PROMO_RELOAD_INTERVAL", 30*time.Second),
+		PromoFilePolicy:     getEnv("PROMO_FILE_POLICY", "tolerate"),
+		PromoScheduleFile:   getEnv("PROMO_SCHEDULE_FILE", "schedule.json"),
+
+		PromoCacheSize:         getEnvInt("PROMO_CACHE_SIZE", 10000),
+		PromoNegativeCacheSize: getEnvInt("PROMO_NEGATIVE_CACHE_SIZE", 10000),
+		PromoCacheTTL:          getEnvDuration("PROMO_CACHE_TTL", 0),
+		PromoNegativeCacheTTL:  getEnvDuration("PROMO_NEGATIVE_CACHE_TTL", 10*time.Minute),
+
+		PromoGuardMaxFailures: getEnvInt("PROMO_GUARD_MAX_FAILURES", 5),
+		PromoGuardKeyFailures: getEnvInt("PROMO_GUARD_KEY_FAILURES", 25),
+		PromoGuardWindow:      getEnvDuration("PROMO_GUARD_WINDOW", 10*time.Minute),
+		PromoGuardLockout:     getEnvDuration("PROMO_GUARD_LOCKOUT", time.Minute),
+		PromoGuardMaxLockout:  getEnvDuration("PROMO_GUARD_MAX_LOCKOUT", time.Hour),
+		PromoGuardTrustProxy:  getEnvBool("PROMO_GUARD_TRUST_PROXY", false),
+
+		IdempotencyTTL: getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		OrderMergeDuplicates: getEnvBool("ORDER_MERGE_DUPLICATES", true),
+		OrderMaxQuantity:     getEnvInt("ORDER_MAX_QUANTITY", 0),
+		OrderMaxItems:        getEnvInt("ORDER_MAX_ITEMS", 0),
+		OrderMaxTotal:        getEnv("ORDER_MAX_TOTAL", ""),
+
+		OpeningHours:  getEnv("OPENING_HOURS", "mon-sun 11:00-22:00"),
+		SlotLength:    getEnvDuration("SLOT_LENGTH", 15*time.Minute),
+		SlotCapacity:  getEnvInt("SLOT_CAPACITY", 10),
+		SlotLeadTime:  getEnvDuration("SLOT_LEAD_TIME", 30*time.Minute),
+		SlotHorizon:   getEnvDuration("SLOT_HORIZON", 7*24*time.Hour),
+		StoreTimezone: getEnv("STORE_TIMEZONE", "UTC"),
+
+		DiscountRulesFile: getEnv("DISCOUNT_RULES_FILE", ""),
+		TaxPercent:        getEnvFloat("TAX_PERCENT", 0),
+		TaxRounding:       getEnv("TAX_ROUNDING", "half_up"),
+		TaxMode:           getEnv("TAX_MODE", "exclusive"),
+		TaxRulesFile:      getEnv("TAX_RULES_FILE", ""),
+		Currency:          getEnv("CURRENCY", "USD"),
 	}
 	return cfg
 }
@@ -29,3 +124,57 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// helper: returns env var parsed as int if set and valid, otherwise fallback default.
+func getEnvInt(key string, fallback int) int {
+	if val := os.Getenv(key); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+// helper: returns env var parsed as a bool (1/true/yes etc.) if set and valid, otherwise fallback default.
+func getEnvBool(key string, fallback bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+// helper: returns env var parsed as a float if set and valid, otherwise fallback default.
+func getEnvFloat(key string, fallback float64) float64 {
+	if val := os.Getenv(key); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+// helper: returns env var parsed as a duration (e.g. "30s", "0") if set and valid, otherwise fallback default.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
+// helper: returns env var split on commas (blank entries dropped) if set, otherwise fallback default.
+func getEnvList(key string, fallback []string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	if len(out) == 0 {
+		return fallback
+	}
+	return out
+}
@@ -0,0 +1,312 @@
+// Package discount turns validated promo codes into discount lines on an order.
+package discount
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
+)
+
+// Type selects how a Rule computes its discount.
+type Type string
+
+const (
+	TypePercentage Type = "percentage"  // Percent off the eligible lines
+	TypeFixed      Type = "fixed"       // Amount off the eligible lines
+	TypeBuyXGetY   Type = "buy_x_get_y" // for every BuyQty+GetQty units of ProductID, GetQty are free
+	TypeNone       Type = ""            // no discount; the rule only limits redemptions
+)
+
+// Rule maps coupon codes to a discount. Exactly one of Code, Prefix or Pattern
+// selects the codes it applies to; Category and ProductID narrow the lines it
+// discounts (percentage and fixed rules apply to the whole basket otherwise).
+type Rule struct {
+	ID string `json:"id"`
+
+	Code    string `json:"code,omitempty"`    // exact code (case-sensitive)
+	Prefix  string `json:"prefix,omitempty"`  // codes starting with Prefix
+	Pattern string `json:"pattern,omitempty"` // path.Match pattern, e.g. "SUMMER??"
+
+	Type      Type        `json:"type"`
+	Percent   float64     `json:"percent,omitempty"`   // TypePercentage: 0 < Percent <= 100
+	Amount    money.Money `json:"amount"`              // TypeFixed
+	BuyQty    int         `json:"buyQty,omitempty"`    // TypeBuyXGetY
+	GetQty    int         `json:"getQty,omitempty"`    // TypeBuyXGetY
+	ProductID string      `json:"productId,omitempty"` // required for TypeBuyXGetY
+	Category  string      `json:"category,omitempty"`  // only discount products in this category
+
+	MinBasket   money.Money `json:"minBasket"`   // order subtotal needed to use the code
+	MaxDiscount money.Money `json:"maxDiscount"` // cap on this rule's discount (0 = none)
+
+	// Redemption limits, enforced per code (not per rule); 0 = unlimited.
+	MaxUses       int  `json:"maxUses,omitempty"`       // across all customers
+	MaxUsesPerKey int  `json:"maxUsesPerKey,omitempty"` // per API key
+	SingleUse     bool `json:"singleUse,omitempty"`     // shorthand for MaxUses 1
+
+	Description string `json:"description,omitempty"` // shown on the discount line; generated if empty
+}
+
+// matches reports whether the rule's selector covers code.
+func (r *Rule) matches(code string) bool {
+	switch {
+	case r.Code != "":
+		return code == r.Code
+	case r.Prefix != "":
+		return strings.HasPrefix(code, r.Prefix)
+	default:
+		ok, _ := path.Match(r.Pattern, code) // pattern validated in NewEngine
+		return ok
+	}
+}
+
+func (r *Rule) validate() error {
+	selectors := 0
+	for _, s := range []string{r.Code, r.Prefix, r.Pattern} {
+		if s != "" {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return errors.New("exactly one of code, prefix or pattern is required")
+	}
+	if r.Pattern != "" {
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %w", r.Pattern, err)
+		}
+	}
+	if r.MinBasket.IsNegative() || r.MaxDiscount.IsNegative() {
+		return errors.New("minBasket and maxDiscount must be >= 0")
+	}
+	if !r.Amount.SameCurrency(r.MinBasket) || !r.Amount.SameCurrency(r.MaxDiscount) || !r.MinBasket.SameCurrency(r.MaxDiscount) {
+		return errors.New("amount, minBasket and maxDiscount must use the same currency")
+	}
+	if r.MaxUses < 0 || r.MaxUsesPerKey < 0 {
+		return errors.New("maxUses and maxUsesPerKey must be >= 0")
+	}
+	switch r.Type {
+	case TypeNone:
+		if maxUses, perKey := r.UseLimits(); maxUses == 0 && perKey == 0 {
+			return errors.New("type is required unless the rule sets a redemption limit")
+		}
+	case TypePercentage:
+		if r.Percent <= 0 || r.Percent > 100 {
+			return errors.New("percent must be in (0, 100]")
+		}
+	case TypeFixed:
+		if !r.Amount.IsPositive() {
+			return errors.New("amount must be > 0")
+		}
+	case TypeBuyXGetY:
+		if r.ProductID == "" || r.BuyQty <= 0 || r.GetQty <= 0 {
+			return errors.New("productId, buyQty and getQty are required")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", r.Type)
+	}
+	return nil
+}
+
+// NotApplicableError reports a code that has a rule whose conditions the order
+// does not meet (e.g. basket below MinBasket, no eligible products).
+type NotApplicableError struct {
+	RuleID  string `json:"ruleId"`
+	Message string `json:"message"`
+}
+
+func (e *NotApplicableError) Error() string { return e.Message }
+
+// Engine applies rules to orders. It is immutable and safe for concurrent use.
+type Engine struct {
+	rules []Rule
+}
+
+// NewEngine validates rules and returns an Engine. Rules are tried in order
+// and the first whose selector matches a code is applied, so specific codes
+// should come before broad prefixes or patterns.
+func NewEngine(rules []Rule) (*Engine, error) {
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("discount: rule %d (%s): %w", i+1, rules[i].ID, err)
+		}
+	}
+	return &Engine{rules: append([]Rule(nil), rules...)}, nil
+}
+
+// LoadFile reads a rules file of the form {"rules": [...]}.
+func LoadFile(filename string) (*Engine, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("discount: %w", err)
+	}
+	var doc struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("discount: %s: %w", filename, err)
+	}
+	return NewEngine(doc.Rules)
+}
+
+// Rules returns a copy of the engine's rules.
+func (e *Engine) Rules() []Rule { return append([]Rule(nil), e.rules...) }
+
+// Match returns the rule applied to code, if any.
+func (e *Engine) Match(code string) (Rule, bool) {
+	for _, r := range e.rules {
+		if r.matches(code) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Apply computes the discount lines for code on an order whose items and
+// products are index-aligned. A code without a rule yields no lines; a rule
+// whose conditions are not met yields a *NotApplicableError.
+func (e *Engine) Apply(code string, items []models.OrderItem, products []models.Product) ([]models.DiscountLine, error) {
+	r, ok := e.Match(code)
+	if !ok {
+		return nil, nil
+	}
+
+	var subtotal money.Money
+	for i, it := range items {
+		subtotal = subtotal.Add(products[i].Price.Mul(int64(it.Quantity)))
+	}
+	for _, m := range []money.Money{r.Amount, r.MinBasket, r.MaxDiscount} {
+		if !m.IsZero() && !m.SameCurrency(subtotal) {
+			return nil, fmt.Errorf("discount: rule %s is in %s but the order is in %s", r.ID, m.Currency, subtotal.Currency)
+		}
+	}
+	if subtotal.Cmp(r.MinBasket) < 0 {
+		return nil, &NotApplicableError{
+			RuleID:  r.ID,
+			Message: "promo code requires a minimum order of " + r.MinBasket.Decimal(),
+		}
+	}
+
+	var amount money.Money
+	switch r.Type {
+	case TypeNone:
+		return nil, nil
+	case TypePercentage, TypeFixed:
+		var eligible money.Money
+		for i, it := range items {
+			if r.eligible(products[i]) {
+				eligible = eligible.Add(products[i].Price.Mul(int64(it.Quantity)))
+			}
+		}
+		if eligible.IsZero() {
+			return nil, r.noEligibleItems()
+		}
+		if r.Type == TypePercentage {
+			amount = eligible.Percent(r.Percent, money.HalfUp)
+		} else {
+			amount = r.Amount.Min(eligible)
+		}
+	case TypeBuyXGetY:
+		// Lines of the product can differ in price (options), so the free
+		// units are the cheapest ones.
+		qty, lines := 0, []int(nil)
+		for i, it := range items {
+			if products[i].ID == r.ProductID && r.eligible(products[i]) {
+				qty += it.Quantity
+				lines = append(lines, i)
+			}
+		}
+		free := qty / (r.BuyQty + r.GetQty) * r.GetQty
+		if free == 0 {
+			return nil, &NotApplicableError{
+				RuleID:  r.ID,
+				Message: fmt.Sprintf("promo code requires at least %d of product %s", r.BuyQty+r.GetQty, r.ProductID),
+			}
+		}
+		slices.SortStableFunc(lines, func(a, b int) int { return products[a].Price.Cmp(products[b].Price) })
+		for _, i := range lines {
+			n := min(free, items[i].Quantity)
+			amount = amount.Add(products[i].Price.Mul(int64(n)))
+			if free -= n; free == 0 {
+				break
+			}
+		}
+	}
+	if r.MaxDiscount.IsPositive() {
+		amount = amount.Min(r.MaxDiscount)
+	}
+
+	return []models.DiscountLine{{
+		RuleID:      r.ID,
+		Code:        code,
+		Type:        string(r.Type),
+		Description: r.describe(),
+		ProductID:   r.ProductID,
+		Category:    r.Category,
+		Amount:      amount,
+	}}, nil
+}
+
+// UseLimits returns the rule's redemption caps (0 = unlimited), with
+// SingleUse folded into maxUses.
+func (r *Rule) UseLimits() (maxUses, perKey int) {
+	maxUses = r.MaxUses
+	if r.SingleUse {
+		maxUses = 1
+	}
+	return maxUses, r.MaxUsesPerKey
+}
+
+func (r *Rule) eligible(p models.Product) bool {
+	if r.Category != "" && !strings.EqualFold(p.Category, r.Category) {
+		return false
+	}
+	return r.Type == TypeBuyXGetY || r.ProductID == "" || p.ID == r.ProductID
+}
+
+func (r *Rule) noEligibleItems() error {
+	target := "the order"
+	switch {
+	case r.ProductID != "":
+		target = "product " + r.ProductID
+	case r.Category != "":
+		target = "category " + r.Category
+	}
+	return &NotApplicableError{RuleID: r.ID, Message: "promo code only applies to " + target + ", which is not in the order"}
+}
+
+func (r *Rule) describe() string {
+	if r.Description != "" {
+		return r.Description
+	}
+	var s string
+	switch r.Type {
+	case TypePercentage:
+		s = fmt.Sprintf("%g%% off", r.Percent)
+	case TypeFixed:
+		s = r.Amount.Decimal() + " off"
+	case TypeBuyXGetY:
+		return fmt.Sprintf("buy %d get %d free on product %s", r.BuyQty, r.GetQty, r.ProductID)
+	}
+	switch {
+	case r.ProductID != "":
+		s += " product " + r.ProductID
+	case r.Category != "":
+		s += " " + r.Category
+	}
+	return s
+}
+
+// Total sums the amounts of lines.
+func Total(lines []models.DiscountLine) money.Money {
+	var total money.Money
+	for _, l := range lines {
+		total = total.Add(l.Amount)
+	}
+	return total
+}
@@ -0,0 +1,176 @@
+package discount
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
+)
+
+func usd(s string) money.Money { return money.MustParse(s, "USD") }
+
+var menu = []models.Product{
+	{ID: "1", Name: "Chicken Waffle", Price: usd("12.99"), Category: "Waffle"},
+	{ID: "3", Name: "Caesar Salad", Price: usd("8.99"), Category: "Salad"},
+	{ID: "7", Name: "Coffee", Price: usd("3.99"), Category: "Beverage"},
+}
+
+func TestEngine_Apply(t *testing.T) {
+	rules := []Rule{
+		{ID: "exact", Code: "TENOFF2024", Type: TypePercentage, Percent: 10},
+		{ID: "capped", Code: "HALFOFF01", Type: TypePercentage, Percent: 50, MaxDiscount: usd("5")},
+		{ID: "fixed", Code: "FIVEOFF01", Type: TypeFixed, Amount: usd("5"), MinBasket: usd("20")},
+		{ID: "salad", Code: "SALAD2024", Type: TypePercentage, Percent: 20, Category: "salad"},
+		{ID: "coffee", Code: "COFFEE321", Type: TypeBuyXGetY, ProductID: "7", BuyQty: 2, GetQty: 1},
+		{ID: "big", Code: "BIGFIXED1", Type: TypeFixed, Amount: usd("100")},
+		{ID: "staff", Prefix: "STAFF", Type: TypePercentage, Percent: 15},
+		{ID: "summer", Pattern: "SUMMER??", Type: TypeFixed, Amount: usd("2")},
+		{ID: "once", Code: "ONETIME01", SingleUse: true},
+	}
+	e, err := NewEngine(rules)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		code    string
+		items   []models.OrderItem // index-aligned with menu
+		want    string             // discount amount; "0.00" means no lines
+		wantErr bool               // NotApplicableError
+	}{
+		{"percentage of basket", "TENOFF2024", qty(2, 1, 0), "3.50", false}, // 10% of 34.97
+		{"max discount cap", "HALFOFF01", qty(2, 1, 0), "5.00", false},
+		{"fixed with min basket", "FIVEOFF01", qty(2, 0, 0), "5.00", false},
+		{"below min basket", "FIVEOFF01", qty(1, 0, 0), "0.00", true},
+		{"category only", "SALAD2024", qty(1, 2, 0), "3.60", false}, // 20% of 17.98
+		{"category missing", "SALAD2024", qty(1, 0, 0), "0.00", true},
+		{"buy 2 get 1", "COFFEE321", qty(0, 0, 7), "7.98", false}, // 7 coffees: 2 free
+		{"buy x get y not reached", "COFFEE321", qty(0, 0, 2), "0.00", true},
+		{"fixed never exceeds basket", "BIGFIXED1", qty(0, 1, 0), "8.99", false},
+		{"prefix", "STAFF0042", qty(0, 0, 1), "0.60", false},
+		{"pattern", "SUMMER24", qty(1, 0, 0), "2.00", false},
+		{"no rule", "HAPPYHRS", qty(1, 0, 0), "0.00", false},
+		{"limit-only rule", "ONETIME01", qty(1, 0, 0), "0.00", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, products := basket(tt.items)
+			lines, err := e.Apply(tt.code, items, products)
+
+			var na *NotApplicableError
+			if tt.wantErr != errors.As(err, &na) {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := Total(lines).Decimal(); got != tt.want {
+				t.Errorf("discount = %s, want %s (lines %+v)", got, tt.want, lines)
+			}
+			if tt.want != "0.00" && (len(lines) != 1 || lines[0].Code != tt.code || lines[0].Description == "") {
+				t.Errorf("lines = %+v", lines)
+			}
+		})
+	}
+}
+
+func TestEngine_Apply_BuyXGetYFreesCheapestUnits(t *testing.T) {
+	e, err := NewEngine([]Rule{{ID: "coffee", Code: "COFFEE321", Type: TypeBuyXGetY, ProductID: "7", BuyQty: 2, GetQty: 1}})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	// the same product at different prices with options, most expensive last
+	plain := models.Product{ID: "7", Name: "Coffee", Price: usd("3.99"), Category: "Beverage"}
+	large := plain
+	large.Price = usd("5.59")
+	items := []models.OrderItem{{ProductID: "7", Quantity: 1}, {ProductID: "7", Quantity: 5}}
+
+	lines, err := e.Apply("COFFEE321", items, []models.Product{plain, large})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	// 6 coffees: 2 free, the 3.99 one and a 5.59 one
+	if len(lines) != 1 || lines[0].Amount.Decimal() != "9.58" {
+		t.Fatalf("lines = %+v, want 9.58 off", lines)
+	}
+}
+
+func TestEngine_FirstMatchingRuleWins(t *testing.T) {
+	e, err := NewEngine([]Rule{
+		{ID: "vip", Code: "STAFFVIP1", Type: TypePercentage, Percent: 50},
+		{ID: "staff", Prefix: "STAFF", Type: TypePercentage, Percent: 15},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	for code, want := range map[string]string{"STAFFVIP1": "vip", "STAFF0001": "staff"} {
+		if r, ok := e.Match(code); !ok || r.ID != want {
+			t.Errorf("Match(%q) = %q, want %q", code, r.ID, want)
+		}
+	}
+}
+
+func TestNewEngine_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"no selector", Rule{ID: "x", Type: TypeFixed, Amount: usd("1")}},
+		{"two selectors", Rule{ID: "x", Code: "A", Prefix: "B", Type: TypeFixed, Amount: usd("1")}},
+		{"bad pattern", Rule{ID: "x", Pattern: "[", Type: TypeFixed, Amount: usd("1")}},
+		{"unknown type", Rule{ID: "x", Code: "A", Type: "bogus"}},
+		{"percent over 100", Rule{ID: "x", Code: "A", Type: TypePercentage, Percent: 150}},
+		{"fixed without amount", Rule{ID: "x", Code: "A", Type: TypeFixed}},
+		{"bxgy without product", Rule{ID: "x", Code: "A", Type: TypeBuyXGetY, BuyQty: 1, GetQty: 1}},
+		{"negative cap", Rule{ID: "x", Code: "A", Type: TypeFixed, Amount: usd("1"), MaxDiscount: usd("-1")}},
+		{"mixed currencies", Rule{ID: "x", Code: "A", Type: TypeFixed, Amount: usd("1"), MinBasket: money.MustParse("10", "EUR")}},
+		{"no type and no limit", Rule{ID: "x", Code: "A"}},
+		{"negative max uses", Rule{ID: "x", Code: "A", MaxUses: -1}},
+	}
+	for _, tt := range tests {
+		if _, err := NewEngine([]Rule{tt.rule}); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "discounts.json")
+	data := `{"rules": [{"id": "ten", "code": "TENOFF2024", "type": "percentage", "percent": 10, "maxDiscount": 3}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if r, ok := e.Match("TENOFF2024"); !ok || r.MaxDiscount != usd("3") {
+		t.Fatalf("rule not loaded: %+v", r)
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func qty(q ...int) []models.OrderItem {
+	out := make([]models.OrderItem, len(q))
+	for i, n := range q {
+		out[i] = models.OrderItem{ProductID: menu[i].ID, Quantity: n}
+	}
+	return out
+}
+
+// basket drops zero-quantity entries, keeping items and products aligned.
+func basket(all []models.OrderItem) ([]models.OrderItem, []models.Product) {
+	var items []models.OrderItem
+	var products []models.Product
+	for i, it := range all {
+		if it.Quantity > 0 {
+			items = append(items, it)
+			products = append(products, menu[i])
+		}
+	}
+	return items, products
+}
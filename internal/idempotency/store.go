@@ -0,0 +1,128 @@
+// Package idempotency remembers responses to requests carrying an
+// Idempotency-Key so that retries get the original response instead of
+// repeating the operation.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrConflict is returned when a key is reused with a different request.
+var ErrConflict = errors.New("idempotency key reused with a different request")
+
+// Response is a stored response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type entry struct {
+	fingerprint string
+	done        chan struct{} // closed when the first request completes or aborts
+	resp        *Response     // nil while in flight, and after an abort
+	expires     time.Time     // zero while in flight
+}
+
+// Store is an in-memory response store. It is safe for concurrent use.
+type Store struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+// NewStore keeps completed responses for ttl.
+func NewStore(ttl time.Duration) *Store {
+	return &Store{ttl: ttl, now: time.Now, entries: make(map[string]*entry)}
+}
+
+// Begin claims key for a request whose body hashes to fingerprint.
+//
+//   - If the key is new, Begin returns (nil, true, nil): the caller owns the
+//     key and must call Complete or Abort.
+//   - If an identical request completed within the TTL, it returns its
+//     stored response.
+//   - If an identical request is still in flight, Begin waits for it (or for
+//     ctx) and then behaves as above; if it aborted, the caller takes over.
+//   - A different fingerprint returns ErrConflict.
+func (s *Store) Begin(ctx context.Context, key, fingerprint string) (resp *Response, owner bool, err error) {
+	for {
+		s.mu.Lock()
+		now := s.now()
+		s.sweep(now)
+
+		e := s.entries[key]
+		if e != nil && e.resp != nil && now.After(e.expires) {
+			delete(s.entries, key)
+			e = nil
+		}
+		if e == nil {
+			s.entries[key] = &entry{fingerprint: fingerprint, done: make(chan struct{})}
+			s.mu.Unlock()
+			return nil, true, nil
+		}
+		if e.fingerprint != fingerprint {
+			s.mu.Unlock()
+			return nil, false, ErrConflict
+		}
+		if e.resp != nil {
+			s.mu.Unlock()
+			return e.resp, false, nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-e.done: // completed or aborted; look again
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// Complete stores resp for key and releases requests waiting on it.
+func (s *Store) Complete(key string, resp Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e := s.entries[key]; e != nil && e.resp == nil {
+		e.resp, e.expires = &resp, s.now().Add(s.ttl)
+		close(e.done)
+	}
+}
+
+// Abort forgets key without storing a response, e.g. after a server error,
+// so a retry runs the request again.
+func (s *Store) Abort(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e := s.entries[key]; e != nil && e.resp == nil {
+		delete(s.entries, key)
+		close(e.done)
+	}
+}
+
+// Len returns the number of keys held, in flight or completed.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// sweep drops expired responses, at most once per TTL.
+func (s *Store) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+	for k, e := range s.entries {
+		if e.resp != nil && now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+}
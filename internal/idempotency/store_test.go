@@ -0,0 +1,101 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStore_ReplayAndConflict(t *testing.T) {
+	s := NewStore(time.Hour)
+	ctx := context.Background()
+
+	if _, owner, err := s.Begin(ctx, "k", "body-a"); !owner || err != nil {
+		t.Fatalf("first Begin: owner=%v err=%v", owner, err)
+	}
+	s.Complete("k", Response{Status: 200, Body: []byte("order-1")})
+
+	resp, owner, err := s.Begin(ctx, "k", "body-a")
+	if owner || err != nil || string(resp.Body) != "order-1" {
+		t.Fatalf("repeat: resp=%+v owner=%v err=%v", resp, owner, err)
+	}
+	if _, _, err := s.Begin(ctx, "k", "body-b"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("different body: want ErrConflict, got %v", err)
+	}
+	if _, owner, _ := s.Begin(ctx, "other", "body-b"); !owner {
+		t.Fatal("other keys are independent")
+	}
+}
+
+func TestStore_WaitsForInFlight(t *testing.T) {
+	tests := []struct {
+		name      string
+		finish    func(s *Store)
+		wantOwner bool // the waiter takes over after an abort
+	}{
+		{"completed", func(s *Store) { s.Complete("k", Response{Status: 201}) }, false},
+		{"aborted", func(s *Store) { s.Abort("k") }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStore(time.Hour)
+			if _, owner, _ := s.Begin(context.Background(), "k", "fp"); !owner {
+				t.Fatal("first Begin should own the key")
+			}
+
+			type result struct {
+				resp  *Response
+				owner bool
+				err   error
+			}
+			done := make(chan result)
+			go func() {
+				resp, owner, err := s.Begin(context.Background(), "k", "fp")
+				done <- result{resp, owner, err}
+			}()
+
+			select {
+			case <-done:
+				t.Fatal("duplicate did not wait for the in-flight request")
+			case <-time.After(20 * time.Millisecond):
+			}
+			tt.finish(s)
+
+			r := <-done
+			if r.err != nil || r.owner != tt.wantOwner || (!tt.wantOwner && r.resp.Status != 201) {
+				t.Fatalf("waiter got %+v", r)
+			}
+		})
+	}
+}
+
+func TestStore_WaitHonoursContext(t *testing.T) {
+	s := NewStore(time.Hour)
+	s.Begin(context.Background(), "k", "fp")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := s.Begin(ctx, "k", "fp"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want deadline exceeded, got %v", err)
+	}
+}
+
+func TestStore_Expiry(t *testing.T) {
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	s := NewStore(time.Hour)
+	s.now = func() time.Time { return now }
+
+	s.Begin(context.Background(), "k", "fp")
+	s.Complete("k", Response{Status: 200})
+	s.Begin(context.Background(), "j", "fp")
+	s.Complete("j", Response{Status: 200})
+
+	now = now.Add(2 * time.Hour)
+	if _, owner, _ := s.Begin(context.Background(), "k", "other"); !owner {
+		t.Fatal("expired key should be reusable")
+	}
+	if s.Len() != 1 {
+		t.Fatalf("expired entries should be swept, have %d", s.Len())
+	}
+}
@@ -1,30 +1,239 @@
 package models
 
+import (
+	"time"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
+)
+
 // Product represents a food item
 type Product struct {
-	ID       string  `json:"id"`
-	Name     string  `json:"name"`
-	Price    float64 `json:"price"`
-	Category string  `json:"category"`
+	ID       string      `json:"id"`
+	Name     string      `json:"name"`
+	Price    money.Money `json:"price"`
+	Category string      `json:"category"`
+	TaxClass string      `json:"taxClass,omitempty"` // overrides the category's tax class
+
+	Stock   *int `json:"stock,omitempty"`   // units left; nil = not counted (e.g. made to order)
+	SoldOut bool `json:"soldOut,omitempty"` // not orderable, whatever the stock
+
+	Options []OptionGroup `json:"options,omitempty"` // choices offered with the product
+}
+
+// OptionGroup is a set of choices offered with a product, e.g. size or extras
+type OptionGroup struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Min     int      `json:"min"` // selections required
+	Max     int      `json:"max"` // selections allowed (0 = any number)
+	Options []Option `json:"options"`
+}
+
+// Option is one choice of an OptionGroup
+type Option struct {
+	ID    string      `json:"id"`
+	Name  string      `json:"name"`
+	Price money.Money `json:"price"` // added to the product's unit price; may be zero or negative
 }
 
 // OrderItem represents an item in an order
 type OrderItem struct {
-	ProductID string `json:"productId"`
-	Quantity  int    `json:"quantity"`
+	ProductID string           `json:"productId"`
+	Quantity  int              `json:"quantity"`
+	Options   []SelectedOption `json:"options,omitempty"`
+}
+
+// SelectedOption is an option chosen for an order item
+type SelectedOption struct {
+	Group  string `json:"group"`  // OptionGroup.ID
+	Option string `json:"option"` // Option.ID
 }
 
 // OrderRequest represents the request body for creating an order
 type OrderRequest struct {
 	CouponCode string      `json:"couponCode,omitempty"`
 	Items      []OrderItem `json:"items"`
+
+	Fulfilment Fulfilment `json:"fulfilment"`      // type defaults to pickup
+	Customer   Customer   `json:"customer"`        // contact details
+	Notes      string     `json:"notes,omitempty"` // instructions for the kitchen
+
+	ScheduledFor *time.Time `json:"scheduledFor,omitempty"` // start of the requested slot; nil = as soon as possible
+
+	APIKey string `json:"-"` // caller's api_key, set by the transport for redemption limits
+}
+
+// FulfilmentType is how an order reaches the customer
+type FulfilmentType string
+
+const (
+	FulfilmentPickup   FulfilmentType = "pickup"
+	FulfilmentDelivery FulfilmentType = "delivery"
+	FulfilmentDineIn   FulfilmentType = "dine_in"
+)
+
+// Fulfilment is how and where an order is handed over
+type Fulfilment struct {
+	Type    FulfilmentType `json:"type"`
+	Address *Address       `json:"address,omitempty"` // delivery only
+	Table   string         `json:"table,omitempty"`   // dine_in only
+}
+
+// Address is a delivery address
+type Address struct {
+	Line1        string `json:"line1"`
+	Line2        string `json:"line2,omitempty"`
+	City         string `json:"city"`
+	PostalCode   string `json:"postalCode"`
+	Instructions string `json:"instructions,omitempty"` // for the driver, e.g. "ring twice"
+}
+
+// TimeSlot is the period a scheduled order is to be handed over in
+type TimeSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Customer holds the contact details given with an order
+type Customer struct {
+	Name  string `json:"name,omitempty"`
+	Phone string `json:"phone,omitempty"` // required for delivery
+}
+
+// DiscountLine is a discount applied to an order by a promo code rule
+type DiscountLine struct {
+	RuleID      string      `json:"ruleId"`
+	Code        string      `json:"code"`
+	Type        string      `json:"type"`
+	Description string      `json:"description"`
+	ProductID   string      `json:"productId,omitempty"`
+	Category    string      `json:"category,omitempty"`
+	Amount      money.Money `json:"amount"` // positive; subtracted from the order total
+}
+
+// PricingLine is the price of one order item
+type PricingLine struct {
+	ProductID string         `json:"productId"`
+	Quantity  int            `json:"quantity"`
+	Options   []PricedOption `json:"options,omitempty"` // selected options, in item order
+	UnitPrice money.Money    `json:"unitPrice"`         // product price plus options
+	LineTotal money.Money    `json:"lineTotal"`         // UnitPrice × Quantity
+	Discount  money.Money    `json:"discount"`          // the line's share of the order's discounts
+	TaxClass  string         `json:"taxClass,omitempty"`
+	TaxRate   float64        `json:"taxRate"` // percent
+	Tax       money.Money    `json:"tax"`     // on LineTotal - Discount; contained in it for inclusive prices
+}
+
+// PricedOption is a selected option with its name and price at order time
+type PricedOption struct {
+	Group  string      `json:"group"`
+	Option string      `json:"option"`
+	Name   string      `json:"name"`
+	Price  money.Money `json:"price"`
+}
+
+// TaxLine is the tax of all lines taxed at one class and rate
+type TaxLine struct {
+	Class   string      `json:"class,omitempty"` // "" = default rate
+	Rate    float64     `json:"rate"`            // percent
+	Taxable money.Money `json:"taxable"`         // net of tax and discounts
+	Tax     money.Money `json:"tax"`             // sum of the lines' rounded tax
+}
+
+// Pricing is the server-computed price breakdown of an order
+type Pricing struct {
+	Lines         []PricingLine `json:"lines"` // index-aligned with Order.Items
+	Subtotal      money.Money   `json:"subtotal"`
+	DiscountTotal money.Money   `json:"discountTotal"`
+	TaxMode       string        `json:"taxMode"` // "exclusive" or "inclusive" (prices contain tax)
+	Taxes         []TaxLine     `json:"taxes"`   // per tax class, in order of first line
+	Tax           money.Money   `json:"tax"`
+	Total         money.Money   `json:"total"` // Subtotal - DiscountTotal, + Tax if exclusive
+}
+
+// OrderStatus is a step in an order's lifecycle
+type OrderStatus string
+
+const (
+	StatusPlaced         OrderStatus = "placed"
+	StatusAccepted       OrderStatus = "accepted"
+	StatusPreparing      OrderStatus = "preparing"
+	StatusReady          OrderStatus = "ready"
+	StatusOutForDelivery OrderStatus = "out_for_delivery"
+	StatusDelivered      OrderStatus = "delivered"
+	StatusCancelled      OrderStatus = "cancelled" // by the customer
+	StatusRejected       OrderStatus = "rejected"  // by the restaurant
+)
+
+// StatusChange records when an order entered a status
+type StatusChange struct {
+	Status OrderStatus `json:"status"`
+	At     time.Time   `json:"at"`
+	Reason string      `json:"reason,omitempty"` // cancellations and rejections
+}
+
+// OrderModification is the body of PATCH /api/order/{orderId}. Changes apply
+// in the order remove, update, add.
+type OrderModification struct {
+	Add      []OrderItem `json:"add,omitempty"`      // new lines
+	Remove   []string    `json:"remove,omitempty"`   // product IDs to drop
+	Update   []OrderItem `json:"update,omitempty"`   // new quantity per line, matched by product ID and options (0 removes)
+	Revision int         `json:"revision,omitempty"` // if set, the order must still be at this revision
+}
+
+// OrderRevision is a version of an order's items
+type OrderRevision struct {
+	Revision int                `json:"revision"`
+	At       time.Time          `json:"at"`
+	Items    []OrderItem        `json:"items"`
+	Total    money.Money        `json:"total"`
+	Changes  *OrderModification `json:"changes,omitempty"` // what produced it; nil for the original order
 }
 
 // Order represents a completed order
 type Order struct {
-	ID       string      `json:"id"`
-	Items    []OrderItem `json:"items"`
-	Products []Product   `json:"products"`
+	ID            string         `json:"id"`
+	Items         []OrderItem    `json:"items"`
+	Products      []Product      `json:"products"`
+	CouponCode    string         `json:"couponCode,omitempty"`
+	Discounts     []DiscountLine `json:"discounts,omitempty"`
+	DiscountTotal *money.Money   `json:"discountTotal,omitempty"` // nil without discounts
+	Pricing       Pricing        `json:"pricing"`
+
+	Fulfilment Fulfilment `json:"fulfilment"`
+	Customer   Customer   `json:"customer"`
+	Notes      string     `json:"notes,omitempty"`
+	Slot       *TimeSlot  `json:"slot,omitempty"` // nil for as-soon-as-possible orders
+
+	Revision  int             `json:"revision"`  // 1 when placed, +1 per modification
+	Revisions []OrderRevision `json:"revisions"` // oldest first; the last entry is the current one
+
+	Status        OrderStatus    `json:"status"`
+	StatusHistory []StatusChange `json:"statusHistory"` // oldest first; the last entry is Status
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+
+	APIKey string `json:"-"` // api_key the order was placed with, for listing "my" orders
+}
+
+// OrderList is a page of orders
+type OrderList struct {
+	Orders     []Order `json:"orders"`
+	NextCursor string  `json:"nextCursor,omitempty"` // pass as ?cursor= for the next page; empty on the last
+}
+
+// OrderStatusRequest is the optional body of the advance and cancel endpoints
+type OrderStatusRequest struct {
+	Status OrderStatus `json:"status,omitempty"` // advance only; default: the normal next status
+	Reason string      `json:"reason,omitempty"`
+}
+
+// Redemption records one use of a promo code
+type Redemption struct {
+	Code       string    `json:"code"`
+	OrderID    string    `json:"orderId"`
+	APIKey     string    `json:"-"`
+	RedeemedAt time.Time `json:"redeemedAt"`
 }
 
 // ApiResponse represents a standard API response
@@ -32,9 +241,10 @@ type ApiResponse struct {
 	Code    int    `json:"code"`
 	Type    string `json:"type"`
 	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
 }
 
 // HealthResponse represents health check response
 type HealthResponse struct {
-	Status string `json:"status"`
+	Status string `json:"status"` // "ok", "degraded" or "failed"
 }
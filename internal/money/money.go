@@ -0,0 +1,309 @@
+// Package money represents amounts exactly, as integer minor units (cents)
+// of an ISO 4217 currency.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for amounts given without a currency (plain JSON
+// numbers, Parse with ""). Set it once at startup, before decoding anything.
+var DefaultCurrency = "USD"
+
+// exponents maps supported ISO 4217 codes to their number of minor-unit digits.
+var exponents = map[string]int{
+	"AED": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2,
+	"CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2,
+	"INR": 2, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "MYR": 2,
+	"NOK": 2, "NZD": 2, "OMR": 3, "PHP": 2, "PLN": 2, "SAR": 2, "SEK": 2, "SGD": 2,
+	"THB": 2, "TND": 3, "TRY": 2, "UGX": 0, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// Money is an amount of a currency. The zero value is zero with no currency
+// yet; it takes the currency of whatever it is combined with.
+type Money struct {
+	Amount   int64  // minor units, e.g. cents
+	Currency string // ISO 4217 code
+}
+
+// New returns minor units of currency ("" = DefaultCurrency).
+func New(minor int64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return Money{Amount: minor, Currency: currency}
+}
+
+// ValidCurrency reports whether code is a supported ISO 4217 currency.
+func ValidCurrency(code string) error {
+	if _, ok := exponents[code]; !ok {
+		return fmt.Errorf("unsupported currency %q", code)
+	}
+	return nil
+}
+
+// Parse reads a decimal amount such as "12.99" or "-3" in currency
+// ("" = DefaultCurrency). More decimal places than the currency has is an
+// error rather than being rounded away.
+func Parse(s, currency string) (Money, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	exp, ok := exponents[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("unsupported currency %q", currency)
+	}
+
+	digits, neg := strings.TrimSpace(s), false
+	if rest, ok := strings.CutPrefix(digits, "-"); ok {
+		digits, neg = rest, true
+	}
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" && frac == "" || !allDigits(whole) || !allDigits(frac) {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places for %s", s, exp, currency)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	n, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if neg {
+		n = -n
+	}
+	return Money{Amount: n, Currency: currency}, nil
+}
+
+// MustParse is Parse for literals; it panics on error.
+func MustParse(s, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrOverflow is returned by CheckedAdd and CheckedMul when the result does
+// not fit in an amount.
+var ErrOverflow = errors.New("money: amount out of range")
+
+// Add returns m + o. Mixing currencies or overflowing is a programming error
+// and panics; check SameCurrency first where amounts come from different
+// sources, and use CheckedAdd where they can be arbitrarily large.
+func (m Money) Add(o Money) Money {
+	sum, err := m.CheckedAdd(o)
+	if err != nil {
+		panic(fmt.Sprintf("%v: %s + %s", err, m, o))
+	}
+	return sum
+}
+
+// CheckedAdd returns m + o, or ErrOverflow. Mixing currencies panics as in Add.
+func (m Money) CheckedAdd(o Money) (Money, error) {
+	cur := m.merge(o)
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: sum, Currency: cur}, nil
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) Money { return m.Add(o.Neg()) }
+
+// Mul returns m × n. Overflowing panics; use CheckedMul where n or m can be
+// arbitrarily large.
+func (m Money) Mul(n int64) Money {
+	p, err := m.CheckedMul(n)
+	if err != nil {
+		panic(fmt.Sprintf("%v: %s × %d", err, m, n))
+	}
+	return p
+}
+
+// CheckedMul returns m × n, or ErrOverflow.
+func (m Money) CheckedMul(n int64) (Money, error) {
+	p := m.Amount * n
+	if m.Amount != 0 && (p/m.Amount != n || m.Amount == -1 && n == math.MinInt64) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: p, Currency: m.Currency}, nil
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	if m.Amount == math.MinInt64 {
+		panic(fmt.Sprintf("%v: -%s", ErrOverflow, m))
+	}
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Cmp returns -1, 0 or +1 as m is less than, equal to or greater than o.
+func (m Money) Cmp(o Money) int {
+	m.merge(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Min returns the smaller of m and o.
+func (m Money) Min(o Money) Money {
+	if m.Cmp(o) <= 0 {
+		return m
+	}
+	return o
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+
+// SameCurrency reports whether m and o can be combined (a zero value without
+// currency combines with anything).
+func (m Money) SameCurrency(o Money) bool {
+	return m.Currency == "" || o.Currency == "" || m.Currency == o.Currency
+}
+
+func (m Money) merge(o Money) string {
+	if !m.SameCurrency(o) {
+		panic(fmt.Sprintf("money: currency mismatch: %s and %s", m.Currency, o.Currency))
+	}
+	if m.Currency != "" {
+		return m.Currency
+	}
+	return o.Currency
+}
+
+// Percent returns pct percent of m, rounded to minor units with mode.
+// pct is taken as the shortest decimal that represents it (8.25 is exactly
+// 8.25), so the only rounding is the final one.
+func (m Money) Percent(pct float64, mode RoundingMode) Money {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(pct, 'f', -1, 64))
+	if !ok {
+		panic(fmt.Sprintf("money: invalid percentage %v", pct))
+	}
+	r.Mul(r, new(big.Rat).SetInt64(m.Amount))
+	r.Quo(r, big.NewRat(100, 1))
+	return Money{Amount: mode.round(r), Currency: m.Currency}
+}
+
+// IncludedPercent returns the pct percent surcharge contained in m, i.e.
+// m × pct / (100 + pct), rounded to minor units with mode. It extracts the
+// tax from a tax-inclusive amount.
+func (m Money) IncludedPercent(pct float64, mode RoundingMode) Money {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(pct, 'f', -1, 64))
+	if !ok || r.Sign() < 0 {
+		panic(fmt.Sprintf("money: invalid percentage %v", pct))
+	}
+	gross := new(big.Rat).Add(r, big.NewRat(100, 1))
+	r.Mul(r, new(big.Rat).SetInt64(m.Amount))
+	r.Quo(r, gross)
+	return Money{Amount: mode.round(r), Currency: m.Currency}
+}
+
+// Sum adds ms; the result of no amounts is zero of currency ("" = DefaultCurrency).
+func Sum(currency string, ms ...Money) Money {
+	total := New(0, currency)
+	for _, m := range ms {
+		total = total.Add(m)
+	}
+	return total
+}
+
+// Decimal formats the amount without currency, e.g. "12.99" or "-0.50".
+func (m Money) Decimal() string {
+	exp := exponents[m.currency()]
+	n := m.Amount
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	s := strconv.FormatInt(n, 10)
+	if exp == 0 {
+		return sign + s
+	}
+	if len(s) <= exp {
+		s = strings.Repeat("0", exp-len(s)+1) + s
+	}
+	return sign + s[:len(s)-exp] + "." + s[len(s)-exp:]
+}
+
+// String formats m as e.g. "12.99 USD".
+func (m Money) String() string { return m.Decimal() + " " + m.currency() }
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// jsonMoney is the wire form: an exact decimal string plus the currency.
+type jsonMoney struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// MarshalJSON writes {"amount": "12.99", "currency": "USD"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	amount, _ := json.Marshal(m.Decimal())
+	return json.Marshal(jsonMoney{Amount: amount, Currency: m.currency()})
+}
+
+// UnmarshalJSON accepts the object form written by MarshalJSON (amount as a
+// string or number) as well as a bare number or decimal string in
+// DefaultCurrency, so clients and files written before amounts carried a
+// currency keep working. Numbers are read from their literal text, never via
+// float64.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	currency := ""
+	if len(b) > 0 && b[0] == '{' {
+		var obj jsonMoney
+		if err := json.Unmarshal(b, &obj); err != nil {
+			return err
+		}
+		if obj.Amount == nil {
+			return fmt.Errorf("money: missing amount")
+		}
+		b, currency = bytes.TrimSpace(obj.Amount), obj.Currency
+	}
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	}
+	v, err := Parse(s, currency)
+	if err != nil {
+		return fmt.Errorf("money: %w", err)
+	}
+	*m = v
+	return nil
+}
@@ -0,0 +1,178 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in, currency string
+		want         Money
+		wantErr      bool
+	}{
+		{"12.99", "USD", Money{1299, "USD"}, false},
+		{"12.9", "USD", Money{1290, "USD"}, false},
+		{"-0.5", "EUR", Money{-50, "EUR"}, false},
+		{".25", "USD", Money{25, "USD"}, false},
+		{"1500", "JPY", Money{1500, "JPY"}, false},
+		{"1.234", "KWD", Money{1234, "KWD"}, false},
+		{"7", "", Money{700, DefaultCurrency}, false},
+		{"12.999", "USD", Money{}, true}, // more decimals than the currency has
+		{"1.5", "JPY", Money{}, true},
+		{"1e2", "USD", Money{}, true},
+		{"", "USD", Money{}, true},
+		{"1", "XXX", Money{}, true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, tt.currency)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Parse(%q, %q) = %v, %v; want %v (err %v)", tt.in, tt.currency, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMoney_Percent(t *testing.T) {
+	tests := []struct {
+		amount int64
+		pct    float64
+		mode   RoundingMode
+		want   int64
+	}{
+		{3497, 10, HalfUp, 350},   // 349.7
+		{2977, 8.25, HalfUp, 246}, // 245.6025
+		{50, 5, HalfUp, 3},        // 2.5
+		{50, 5, HalfEven, 2},      // 2.5
+		{70, 5, HalfEven, 4},      // 3.5
+		{-50, 5, HalfUp, -3},      // halves away from zero
+		{1999, 0.1, Down, 1},      // 1.999
+		{1001, 0.1, Up, 2},        // 1.001
+		{1000, 8.1, HalfUp, 81},   // 8.1 must not drift to 80.99999…
+		{12345, 100, HalfEven, 12345},
+	}
+	for _, tt := range tests {
+		got := New(tt.amount, "USD").Percent(tt.pct, tt.mode)
+		if got.Amount != tt.want {
+			t.Errorf("%d × %g%% (%s) = %d, want %d", tt.amount, tt.pct, tt.mode, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestMoney_IncludedPercent(t *testing.T) {
+	tests := []struct {
+		amount int64
+		pct    float64
+		mode   RoundingMode
+		want   int64
+	}{
+		{1200, 20, HalfUp, 200},   // exactly 1/6
+		{399, 20, HalfUp, 67},     // 66.5
+		{399, 20, Down, 66},       // 66.5
+		{1099, 8.25, HalfUp, 84},  // 83.81…
+		{1000, 0, HalfUp, 0},      // untaxed
+		{-1200, 20, HalfUp, -200}, // refunds
+	}
+	for _, tt := range tests {
+		got := New(tt.amount, "USD").IncludedPercent(tt.pct, tt.mode)
+		if got.Amount != tt.want {
+			t.Errorf("%g%% included in %d (%s) = %d, want %d", tt.pct, tt.amount, tt.mode, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a, b := MustParse("12.99", "USD"), MustParse("0.01", "USD")
+	if got := a.Mul(3).Add(b).Decimal(); got != "38.98" {
+		t.Errorf("12.99×3 + 0.01 = %s", got)
+	}
+	if got := b.Sub(a).String(); got != "-12.98 USD" {
+		t.Errorf("0.01 - 12.99 = %s", got)
+	}
+	if got := (Money{}).Add(b); got != b {
+		t.Errorf("zero value should take the other currency, got %v", got)
+	}
+	if got := Sum("JPY").String(); got != "0 JPY" {
+		t.Errorf("empty sum = %s", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("mixing currencies should panic")
+		}
+	}()
+	a.Add(MustParse("1", "EUR"))
+}
+
+func TestMoney_Overflow(t *testing.T) {
+	big := Money{math.MaxInt64 / 1000, "USD"}
+	if _, err := big.CheckedMul(1001); !errors.Is(err, ErrOverflow) {
+		t.Errorf("CheckedMul overflow: err = %v", err)
+	}
+	if got, err := big.CheckedMul(1000); err != nil || got.Amount != math.MaxInt64/1000*1000 {
+		t.Errorf("CheckedMul = %v, %v", got, err)
+	}
+	if _, err := (Money{math.MinInt64, "USD"}).CheckedMul(-1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("CheckedMul(-1) of MinInt64: err = %v", err)
+	}
+	if _, err := (Money{math.MaxInt64, "USD"}).CheckedAdd(Money{1, "USD"}); !errors.Is(err, ErrOverflow) {
+		t.Errorf("CheckedAdd overflow: err = %v", err)
+	}
+	if _, err := (Money{math.MinInt64, "USD"}).CheckedAdd(Money{-1, "USD"}); !errors.Is(err, ErrOverflow) {
+		t.Errorf("CheckedAdd underflow: err = %v", err)
+	}
+
+	for name, f := range map[string]func(){
+		"Add":     func() { Money{math.MaxInt64, "USD"}.Add(Money{1, "USD"}) },
+		"Mul":     func() { big.Mul(1 << 20) },
+		"Percent": func() { Money{math.MaxInt64, "USD"}.Percent(200, HalfUp) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s overflow should panic rather than wrap", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	out, err := json.Marshal(MustParse("12.5", "EUR"))
+	if err != nil || string(out) != `{"amount":"12.50","currency":"EUR"}` {
+		t.Fatalf("Marshal = %s, %v", out, err)
+	}
+
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{`12.99`, Money{1299, DefaultCurrency}, false},
+		{`"12.99"`, Money{1299, DefaultCurrency}, false},
+		{`{"amount":"12.50","currency":"EUR"}`, Money{1250, "EUR"}, false},
+		{`{"amount":1500,"currency":"JPY"}`, Money{1500, "JPY"}, false},
+		{`0.1`, Money{10, DefaultCurrency}, false},
+		{`12.999`, Money{}, true},
+		{`{"currency":"EUR"}`, Money{}, true},
+		{`true`, Money{}, true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, %v; want %v (err %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	if m, err := ParseRoundingMode(""); err != nil || m != HalfUp {
+		t.Errorf(`ParseRoundingMode("") = %q, %v`, m, err)
+	}
+	if _, err := ParseRoundingMode("nearest"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
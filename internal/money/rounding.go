@@ -0,0 +1,63 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// RoundingMode says how an exact result is rounded to minor units.
+type RoundingMode string
+
+const (
+	HalfUp   RoundingMode = "half_up"   // to nearest; halves away from zero (commercial rounding)
+	HalfEven RoundingMode = "half_even" // to nearest; halves to the even neighbour (banker's rounding)
+	Down     RoundingMode = "down"      // towards zero (truncate)
+	Up       RoundingMode = "up"        // away from zero
+)
+
+// ParseRoundingMode validates s ("" = HalfUp).
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch m := RoundingMode(s); m {
+	case "":
+		return HalfUp, nil
+	case HalfUp, HalfEven, Down, Up:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown rounding mode %q (want half_up, half_even, down or up)", s)
+}
+
+// round rounds r to an integer; a result outside int64 panics with ErrOverflow.
+func (mode RoundingMode) round(r *big.Rat) int64 {
+	num, den := r.Num(), r.Denom() // den > 0
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return toInt64(q)
+	}
+
+	away := false
+	switch mode {
+	case Down:
+	case Up:
+		away = true
+	default: // HalfUp, HalfEven
+		twice := new(big.Int).Abs(rem)
+		twice.Lsh(twice, 1)
+		switch c := twice.Cmp(den); {
+		case c > 0:
+			away = true
+		case c == 0:
+			away = mode != HalfEven || q.Bit(0) == 1
+		}
+	}
+	if away {
+		q.Add(q, big.NewInt(int64(num.Sign())))
+	}
+	return toInt64(q)
+}
+
+func toInt64(q *big.Int) int64 {
+	if !q.IsInt64() {
+		panic(fmt.Sprintf("%v: %s", ErrOverflow, q))
+	}
+	return q.Int64()
+}
@@ -0,0 +1,316 @@
+// Package promoguard tracks failed promo code attempts per client and locks
+// out clients that look like they are guessing codes.
+package promoguard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Subject kinds.
+const (
+	KindAPIKey = "api_key"
+	KindIP     = "ip"
+)
+
+// Subject is a client attempts are attributed to.
+type Subject struct {
+	Kind  string
+	Value string
+}
+
+// APIKey and IP build Subjects; empty values are ignored by the Guard.
+func APIKey(key string) Subject { return Subject{Kind: KindAPIKey, Value: key} }
+func IP(addr string) Subject    { return Subject{Kind: KindIP, Value: addr} }
+
+// label is how the subject appears in events: API keys are fingerprinted so
+// they never end up in logs.
+func (s Subject) label() string {
+	if s.Kind != KindAPIKey {
+		return s.Value
+	}
+	sum := sha256.Sum256([]byte(s.Value))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// Config sets the lockout policy. A lockout starts after MaxFailures failed
+// attempts within Window (successes in between don't reset the count, so a
+// client holding one good code can't launder its guesses) and lasts Lockout,
+// doubling with every further lockout up to MaxLockout. The escalation level
+// is forgotten after ResetAfter without a lockout.
+type Config struct {
+	MaxFailures    int           // <= 0 disables the guard
+	MaxKeyFailures int           // threshold for API key subjects; default MaxFailures
+	Window         time.Duration // default 10m
+	Lockout        time.Duration // first lockout; default 1m
+	MaxLockout     time.Duration // default 1h
+	ResetAfter     time.Duration // default 24h
+	MaxEvents      int           // lockout events kept for Events; default 100
+
+	// OnLockout, if set, is called (without locks held) for every new lockout.
+	OnLockout func(LockoutEvent)
+	// Now returns the current time; nil uses time.Now.
+	Now func() time.Time
+}
+
+// LockoutEvent records one client being locked out.
+type LockoutEvent struct {
+	Time     time.Time     `json:"time"`
+	Kind     string        `json:"kind"`
+	Subject  string        `json:"subject"` // IP, or a fingerprint of the API key
+	Failures int           `json:"failures"`
+	Level    int           `json:"level"` // 1 for the first lockout, then escalating
+	Duration time.Duration `json:"-"`     // encoded as a string such as "2m0s"
+	Until    time.Time     `json:"until"`
+}
+
+// MarshalJSON writes Duration in time.Duration notation rather than nanoseconds.
+func (ev LockoutEvent) MarshalJSON() ([]byte, error) {
+	type plain LockoutEvent
+	return json.Marshal(struct {
+		plain
+		Duration string `json:"duration"`
+	}{plain(ev), ev.Duration.String()})
+}
+
+type entry struct {
+	failures    int
+	pending     int // attempts let through by Check and not settled yet
+	windowStart time.Time
+	level       int
+	lastLockout time.Time
+	lockedUntil time.Time
+}
+
+// Guard is safe for concurrent use.
+type Guard struct {
+	cfg Config
+
+	mu        sync.Mutex
+	entries   map[Subject]*entry
+	events    []LockoutEvent // ring of the last cfg.MaxEvents
+	nextEvent int
+	lastSweep time.Time
+}
+
+// New returns a Guard for cfg, filling in defaults. It returns nil when
+// cfg.MaxFailures <= 0; a nil *Guard allows everything.
+func New(cfg Config) *Guard {
+	if cfg.MaxFailures <= 0 {
+		return nil
+	}
+	if cfg.MaxKeyFailures <= 0 {
+		cfg.MaxKeyFailures = cfg.MaxFailures
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Minute
+	}
+	if cfg.Lockout <= 0 {
+		cfg.Lockout = time.Minute
+	}
+	if cfg.MaxLockout < cfg.Lockout {
+		cfg.MaxLockout = max(cfg.Lockout, time.Hour)
+	}
+	if cfg.ResetAfter <= 0 {
+		cfg.ResetAfter = 24 * time.Hour
+	}
+	if cfg.MaxEvents <= 0 {
+		cfg.MaxEvents = 100
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Guard{cfg: cfg, entries: make(map[Subject]*entry)}
+}
+
+// busyRetry is the wait reported while a subject's unsettled attempts
+// could still use up its remaining failures.
+const busyRetry = time.Second
+
+// Check returns how long the caller must wait if any subject is locked out,
+// or has as many attempts in flight as it has failures left. Otherwise it
+// reserves the attempt for every subject, and the caller must settle it with
+// Failure or Release; this way concurrent attempts can't all pass Check
+// before the first failure is counted.
+func (g *Guard) Check(subjects ...Subject) (retryAfter time.Duration, locked bool) {
+	if g == nil {
+		return 0, false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.cfg.Now()
+	for _, s := range subjects {
+		e := g.entries[s]
+		switch {
+		case e == nil || s.Value == "":
+		case now.Before(e.lockedUntil):
+			retryAfter = max(retryAfter, e.lockedUntil.Sub(now))
+		case g.failures(e, now)+e.pending >= g.threshold(s):
+			retryAfter = max(retryAfter, busyRetry)
+		}
+	}
+	if retryAfter > 0 {
+		return retryAfter, true
+	}
+	for _, s := range subjects {
+		if s.Value != "" {
+			g.entry(s).pending++
+		}
+	}
+	return 0, false
+}
+
+// Release settles attempts reserved by Check that did not fail.
+func (g *Guard) Release(subjects ...Subject) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, s := range subjects {
+		if e := g.entries[s]; e != nil && e.pending > 0 {
+			e.pending--
+		}
+	}
+}
+
+// Failure records a failed attempt for every subject, settling an attempt
+// reserved by Check, and returns the lockouts it triggered.
+func (g *Guard) Failure(subjects ...Subject) []LockoutEvent {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	now := g.cfg.Now()
+	g.sweep(now)
+
+	var events []LockoutEvent
+	for _, s := range subjects {
+		if s.Value == "" {
+			continue
+		}
+		e := g.entry(s)
+		if e.pending > 0 {
+			e.pending--
+		}
+		if now.Before(e.lockedUntil) {
+			continue // already locked out; attempts shouldn't reach here
+		}
+		if e.level > 0 && now.Sub(e.lastLockout) > g.cfg.ResetAfter {
+			e.level = 0
+		}
+		if now.Sub(e.windowStart) > g.cfg.Window {
+			e.failures, e.windowStart = 0, now
+		}
+		e.failures++
+		if e.failures < g.threshold(s) {
+			continue
+		}
+
+		e.level++
+		d := g.cfg.Lockout
+		for i := 1; i < e.level && d < g.cfg.MaxLockout; i++ {
+			d *= 2
+		}
+		d = min(d, g.cfg.MaxLockout)
+		ev := LockoutEvent{
+			Time: now, Kind: s.Kind, Subject: s.label(),
+			Failures: e.failures, Level: e.level, Duration: d, Until: now.Add(d),
+		}
+		e.failures, e.lastLockout, e.lockedUntil = 0, now, ev.Until
+		g.record(ev)
+		events = append(events, ev)
+	}
+	g.mu.Unlock()
+
+	if g.cfg.OnLockout != nil {
+		for _, ev := range events {
+			g.cfg.OnLockout(ev)
+		}
+	}
+	return events
+}
+
+// Events returns the most recent lockouts, oldest first.
+func (g *Guard) Events() []LockoutEvent {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.events) < g.cfg.MaxEvents {
+		return append([]LockoutEvent(nil), g.events...)
+	}
+	out := make([]LockoutEvent, 0, len(g.events))
+	out = append(out, g.events[g.nextEvent:]...)
+	return append(out, g.events[:g.nextEvent]...)
+}
+
+// Locked returns the number of subjects currently locked out.
+func (g *Guard) Locked() int {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now, n := g.cfg.Now(), 0
+	for _, e := range g.entries {
+		if now.Before(e.lockedUntil) {
+			n++
+		}
+	}
+	return n
+}
+
+// entry returns s's entry, creating it if needed.
+func (g *Guard) entry(s Subject) *entry {
+	e := g.entries[s]
+	if e == nil {
+		e = &entry{}
+		g.entries[s] = e
+	}
+	return e
+}
+
+// failures returns e's failures in the current window.
+func (g *Guard) failures(e *entry, now time.Time) int {
+	if now.Sub(e.windowStart) > g.cfg.Window {
+		return 0
+	}
+	return e.failures
+}
+
+// threshold returns the failures that lock s out.
+func (g *Guard) threshold(s Subject) int {
+	if s.Kind == KindAPIKey {
+		return g.cfg.MaxKeyFailures
+	}
+	return g.cfg.MaxFailures
+}
+
+func (g *Guard) record(ev LockoutEvent) {
+	if len(g.events) < g.cfg.MaxEvents {
+		g.events = append(g.events, ev)
+		return
+	}
+	g.events[g.nextEvent] = ev
+	g.nextEvent = (g.nextEvent + 1) % g.cfg.MaxEvents
+}
+
+// sweep drops entries with nothing left to remember, at most once per Window.
+func (g *Guard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < g.cfg.Window {
+		return
+	}
+	g.lastSweep = now
+	for s, e := range g.entries {
+		idle := now.Sub(e.windowStart) > g.cfg.Window && !now.Before(e.lockedUntil) && e.pending == 0
+		if idle && (e.level == 0 || now.Sub(e.lastLockout) > g.cfg.ResetAfter) {
+			delete(g.entries, s)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package promoguard
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time      { return c.t }
+func (c *fakeClock) add(d time.Duration) { c.t = c.t.Add(d) }
+func newClock() *fakeClock               { return &fakeClock{t: time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)} }
+
+// fail records n failures and returns the number of lockouts they caused.
+func fail(g *Guard, n int, s ...Subject) int {
+	total := 0
+	for i := 0; i < n; i++ {
+		total += len(g.Failure(s...))
+	}
+	return total
+}
+
+func TestGuard_EscalatingLockouts(t *testing.T) {
+	clock := newClock()
+	g := New(Config{MaxFailures: 3, Window: time.Minute, Lockout: time.Minute, MaxLockout: 3 * time.Minute, Now: clock.now})
+	ip := IP("10.0.0.1")
+
+	tests := []struct {
+		name     string
+		wantWait time.Duration
+	}{
+		{"first lockout", time.Minute},
+		{"second doubles", 2 * time.Minute},
+		{"capped at max", 3 * time.Minute},
+		{"stays capped", 3 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, locked := g.Check(ip); locked {
+				t.Fatal("locked before reaching MaxFailures")
+			}
+			if n := fail(g, 3, ip); n != 1 {
+				t.Fatalf("want 1 lockout event, got %d", n)
+			}
+			wait, locked := g.Check(ip)
+			if !locked || wait != tt.wantWait {
+				t.Fatalf("want locked for %s, got %s (locked=%v)", tt.wantWait, wait, locked)
+			}
+			clock.add(wait)
+		})
+	}
+
+	if got := len(g.Events()); got != len(tests) {
+		t.Fatalf("want %d events, got %d", len(tests), got)
+	}
+}
+
+func TestGuard_WindowAndReset(t *testing.T) {
+	clock := newClock()
+	g := New(Config{MaxFailures: 3, Window: time.Minute, Lockout: time.Minute, ResetAfter: time.Hour, Now: clock.now})
+	key := APIKey("secret")
+
+	// failures outside the window don't add up
+	fail(g, 2, key)
+	clock.add(2 * time.Minute)
+	if n := fail(g, 2, key); n != 0 {
+		t.Fatal("failures from an expired window were counted")
+	}
+
+	// the third failure in the window locks out
+	if n := fail(g, 1, key); n != 1 {
+		t.Fatal("want a lockout on the third failure in the window")
+	}
+	clock.add(time.Minute)
+
+	// escalation is forgotten after ResetAfter without lockouts
+	clock.add(2 * time.Hour)
+	fail(g, 3, key)
+	if wait, _ := g.Check(key); wait != time.Minute {
+		t.Fatalf("want escalation reset to 1m, got %s", wait)
+	}
+}
+
+func TestGuard_SubjectsAreIndependent(t *testing.T) {
+	g := New(Config{MaxFailures: 2, Now: newClock().now})
+	a, b := IP("10.0.0.1"), IP("10.0.0.2")
+
+	fail(g, 2, a, APIKey(""))
+	if _, locked := g.Check(b); locked {
+		t.Fatal("lockout leaked to another subject")
+	}
+	if _, locked := g.Check(b, a); !locked {
+		t.Fatal("want locked when any subject is locked")
+	}
+	if g.Locked() != 1 {
+		t.Fatalf("want 1 locked subject (empty values ignored), got %d", g.Locked())
+	}
+}
+
+func TestGuard_EventsHideAPIKeys(t *testing.T) {
+	var logged []LockoutEvent
+	g := New(Config{MaxFailures: 1, MaxEvents: 2, Now: newClock().now,
+		OnLockout: func(ev LockoutEvent) { logged = append(logged, ev) }})
+
+	g.Failure(APIKey("key-1"))
+	g.Failure(APIKey("key-2"))
+	g.Failure(IP("10.0.0.3"))
+
+	events := g.Events()
+	if len(events) != 2 || len(logged) != 3 {
+		t.Fatalf("want 2 retained / 3 logged events, got %d / %d", len(events), len(logged))
+	}
+	if events[1].Subject != "10.0.0.3" || !strings.HasPrefix(events[0].Subject, "sha256:") {
+		t.Fatalf("unexpected event order or subjects: %+v", events)
+	}
+	b, _ := json.Marshal(events)
+	if strings.Contains(string(b), "key-2") || !strings.Contains(string(b), `"duration":"1m0s"`) {
+		t.Fatalf("unexpected event JSON: %s", b)
+	}
+}
+
+func TestGuard_CheckReservesAttempts(t *testing.T) {
+	g := New(Config{MaxFailures: 3, MaxKeyFailures: 10, Now: newClock().now})
+	ip, key := IP("10.0.0.1"), APIKey("secret")
+
+	// a burst of concurrent attempts: only as many pass as there are failures left
+	passed := 0
+	for i := 0; i < 5; i++ {
+		if _, locked := g.Check(key, ip); !locked {
+			passed++
+		}
+	}
+	if passed != 3 {
+		t.Fatalf("%d attempts passed Check, want 3", passed)
+	}
+	if wait, locked := g.Check(ip); !locked || wait != busyRetry {
+		t.Fatalf("Check with attempts in flight = %s, %v; want %s, true", wait, locked, busyRetry)
+	}
+
+	// released attempts free their slot; failed ones count
+	g.Release(key, ip)
+	if _, locked := g.Check(key, ip); locked {
+		t.Fatal("released attempt still holds its slot")
+	}
+	if n := fail(g, 3, key, ip); n != 1 {
+		t.Fatalf("want the IP locked out on the third failure, got %d lockouts", n)
+	}
+	if _, locked := g.Check(key); locked {
+		t.Fatal("key locked out below MaxKeyFailures")
+	}
+}
+
+func TestGuard_Disabled(t *testing.T) {
+	g := New(Config{})
+	if g != nil {
+		t.Fatal("want nil guard when MaxFailures is 0")
+	}
+	g.Failure(IP("10.0.0.1"))
+	if _, locked := g.Check(IP("10.0.0.1")); locked || g.Events() != nil {
+		t.Fatal("nil guard should allow everything")
+	}
+}
@@ -0,0 +1,82 @@
+package promovalidator
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Defaults for the result cache when the Config fields are left at zero.
+const (
+	defaultPositiveCacheSize = 10_000
+	defaultNegativeCacheSize = 10_000
+	defaultNegativeCacheTTL  = 10 * time.Minute
+)
+
+// CacheStats reports result cache effectiveness. A lookup is a hit when
+// either cache holds the code.
+type CacheStats struct {
+	Hits     uint64   `json:"hits"`
+	Misses   uint64   `json:"misses"`
+	Positive LRUStats `json:"positive"`
+	Negative LRUStats `json:"negative"`
+}
+
+// resultCache keeps valid and invalid codes in separate bounded LRUs, so
+// guesses (which are nearly always invalid) cannot push out real coupons.
+type resultCache struct {
+	pos, neg     *LRU[Result]
+	hits, misses atomic.Uint64
+}
+
+func newResultCache(cfg Config) *resultCache {
+	posSize, negSize := cfg.PositiveCacheSize, cfg.NegativeCacheSize
+	if posSize <= 0 {
+		posSize = defaultPositiveCacheSize
+	}
+	if negSize <= 0 {
+		negSize = defaultNegativeCacheSize
+	}
+	negTTL := cfg.NegativeCacheTTL
+	if negTTL == 0 {
+		negTTL = defaultNegativeCacheTTL
+	}
+	return &resultCache{
+		pos: NewLRUWithTTL[Result](posSize, cfg.PositiveCacheTTL),
+		neg: NewLRUWithTTL[Result](negSize, negTTL),
+	}
+}
+
+func (c *resultCache) get(code string) (Result, bool) {
+	if res, ok := c.pos.Get(code); ok {
+		c.hits.Add(1)
+		return res, true
+	}
+	if res, ok := c.neg.Get(code); ok {
+		c.hits.Add(1)
+		return res, true
+	}
+	c.misses.Add(1)
+	return Result{}, false
+}
+
+func (c *resultCache) put(res Result) {
+	if res.Valid {
+		c.pos.Add(res.Code, res)
+	} else {
+		c.neg.Add(res.Code, res)
+	}
+}
+
+func (c *resultCache) purge() {
+	c.pos.Purge()
+	c.neg.Purge()
+}
+
+func (c *resultCache) stats() CacheStats {
+	return CacheStats{
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+		Positive: c.pos.Stats(),
+		Negative: c.neg.Stats(),
+	}
+}
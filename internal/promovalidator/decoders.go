@@ -0,0 +1,183 @@
+package promovalidator
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Decoder wraps a compressed stream in a reader of its plain contents.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+// Format describes a coupon file encoding. Files are matched by Magic (the
+// leading bytes) and, if set, Match; a file whose extension belongs to a format but whose content
+// doesn't start with its magic is reported as corrupt. Files matching no
+// format are read as plain text.
+type Format struct {
+	Name  string // e.g. "gzip"
+	Magic []byte // leading bytes identifying the format
+	// Match, if set, must also accept the leading bytes (up to sniffLen of
+	// them). Formats with a short Magic use it so plain text that happens to
+	// start with the same letters isn't taken for them.
+	Match      func(head []byte) bool
+	Extensions []string // e.g. [".gz"]; lower-case, with the dot
+	Decode     Decoder
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   []Format
+)
+
+func init() {
+	RegisterFormat(Format{
+		Name:       "gzip",
+		Magic:      []byte{0x1f, 0x8b},
+		Extensions: []string{".gz", ".gzip"},
+		Decode: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	})
+	RegisterFormat(Format{
+		Name:       "bzip2",
+		Magic:      []byte("BZh"),
+		Match:      bzip2Header,
+		Extensions: []string{".bz2"},
+		Decode: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(bzip2.NewReader(r)), nil
+		},
+	})
+	RegisterFormat(Format{
+		Name:       "zstd",
+		Magic:      []byte{0x28, 0xb5, 0x2f, 0xfd},
+		Extensions: []string{".zst", ".zstd"},
+		Decode: func(r io.Reader) (io.ReadCloser, error) {
+			d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		},
+	})
+}
+
+// sniffLen is the least number of leading bytes read to detect a format.
+const sniffLen = 16
+
+// bzip2Header checks the full stream header: "BZh", a block size '1'-'9' and
+// the magic of the first block (or of the end of an empty stream).
+func bzip2Header(head []byte) bool {
+	if len(head) < 10 || head[3] < '1' || head[3] > '9' {
+		return false
+	}
+	block := head[4:10]
+	return bytes.Equal(block, []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}) ||
+		bytes.Equal(block, []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90})
+}
+
+// RegisterFormat adds (or replaces, by Name) a coupon file format. It is safe
+// to call concurrently with validation, typically from an init function.
+func RegisterFormat(f Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for i := range formats {
+		if formats[i].Name == f.Name {
+			formats[i] = f
+			return
+		}
+	}
+	formats = append(formats, f)
+}
+
+// detectFormat picks the format for a file given its leading bytes.
+// A nil format with nil error means plain text.
+func detectFormat(filename string, head []byte) (*Format, error) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	for i := range formats {
+		if len(formats[i].Magic) > 0 && bytes.HasPrefix(head, formats[i].Magic) &&
+			(formats[i].Match == nil || formats[i].Match(head)) {
+			f := formats[i]
+			return &f, nil
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	for i := range formats {
+		for _, e := range formats[i].Extensions {
+			if e == ext {
+				return nil, fmt.Errorf("%s: not a valid %s file", filepath.Base(filename), formats[i].Name)
+			}
+		}
+	}
+	return nil, nil
+}
+
+func maxMagicLen() int {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	n := sniffLen
+	for _, f := range formats {
+		n = max(n, len(f.Magic))
+	}
+	return n
+}
+
+// openCouponFile opens filename for reading, transparently decompressing it
+// according to its detected format. Closing the returned reader closes the
+// underlying file as well.
+func openCouponFile(filename string) (io.ReadCloser, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	head, err := br.Peek(maxMagicLen())
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		f.Close()
+		return nil, err
+	}
+
+	format, err := detectFormat(filename, head)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if format == nil {
+		return &decodedFile{Reader: br, f: f}, nil
+	}
+
+	rc, err := format.Decode(br)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", format.Name, err)
+	}
+	return &decodedFile{Reader: rc, dec: rc, f: f}, nil
+}
+
+// decodedFile closes both the decoder (if any) and the file beneath it.
+type decodedFile struct {
+	io.Reader
+	dec io.Closer
+	f   *os.File
+}
+
+func (d *decodedFile) Close() error {
+	var err error
+	if d.dec != nil {
+		err = d.dec.Close()
+	}
+	if cerr := d.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+package promovalidator
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// bzip2Fixture is `printf 'BZIPCODE1\nSHARED001\n' | bzip2 -9`; the standard
+// library can only decompress bzip2.
+const bzip2Fixture = "425a6839314159265359894792fe000003ce00001060003e60d81020002221a623190a6000388ea91e6a5a7b04112705dc914e14242251e4bf80"
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+}
+
+func writeZstdFile(t *testing.T, path string, lines []string) {
+	t.Helper()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("zstd writer: %v", err)
+	}
+	defer enc.Close()
+	writeFile(t, path, enc.EncodeAll([]byte(strings.Join(lines, "\n")+"\n"), nil))
+}
+
+func writeBzip2File(t *testing.T, path string) {
+	t.Helper()
+	data, err := hex.DecodeString(bzip2Fixture)
+	if err != nil {
+		t.Fatalf("decode fixture: %v", err)
+	}
+	writeFile(t, path, data)
+}
+
+func TestValidatePromoCode_Formats(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"GZIPCODE1", "SHARED001"})
+	writeBzip2File(t, filepath.Join(dir, "b.bz2"))
+	writeZstdFile(t, filepath.Join(dir, "c.zst"), []string{"ZSTDCODE1", "SHARED001"})
+	writeFile(t, filepath.Join(dir, "d.txt"), []byte("PLAINCODE1\nSHARED001\n"))
+	// gzip content without the extension is still detected by its magic bytes
+	writeGzipFile(t, filepath.Join(dir, "e.dat"), []string{"NOSUFFIX1"})
+
+	for _, mode := range []Mode{ModeStreaming, ModeIndexed} {
+		t.Run(string(mode), func(t *testing.T) {
+			v, err := New(Config{
+				Dir:          dir,
+				Files:        []string{"a.gz", "b.bz2", "c.zst", "d.txt", "e.dat"},
+				MinLen:       8,
+				MaxLen:       10,
+				RequiredHits: 1,
+				Mode:         mode,
+			})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if err := v.LoadCouponFiles(); err != nil {
+				t.Fatalf("LoadCouponFiles: %v", err)
+			}
+			for _, code := range []string{"GZIPCODE1", "BZIPCODE1", "ZSTDCODE1", "PLAINCODE1", "NOSUFFIX1"} {
+				if res := v.CheckPromoCode(context.Background(), code); !res.Valid {
+					t.Errorf("CheckPromoCode(%q) = %+v, want valid", code, res)
+				}
+			}
+			if res := v.CheckPromoCode(context.Background(), "SHARED001"); res.Hits < 1 || len(res.UnreadableFiles) != 0 {
+				t.Errorf("SHARED001: %+v", res)
+			}
+		})
+	}
+}
+
+func TestValidatePromoCode_PlainTextWithMagicLetters(t *testing.T) {
+	dir := t.TempDir()
+	// starts with bzip2's "BZh" but is no bzip2 stream
+	writeFile(t, filepath.Join(dir, "a.txt"), []byte("BZhABCDEF1\nHELLOWORLD\n"))
+	writeFile(t, filepath.Join(dir, "b.txt"), []byte("HELLOWORLD\n"))
+
+	v := NewValidatorService(Config{Dir: dir, Files: []string{"a.txt", "b.txt"}, MinLen: 8, MaxLen: 10, RequiredHits: 2})
+	if err := v.LoadCouponFiles(); err != nil {
+		t.Fatalf("LoadCouponFiles: %v", err)
+	}
+	if res := v.CheckPromoCode(context.Background(), "HELLOWORLD"); !res.Valid || len(res.UnreadableFiles) != 0 {
+		t.Errorf("CheckPromoCode = %+v, want valid with a.txt read as plain text", res)
+	}
+}
+
+func TestOpenCouponFile_ExtensionMismatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.zst")
+	writeFile(t, path, []byte("PLAINCODE1\n"))
+
+	_, err := openCouponFile(path)
+	if err == nil || !strings.Contains(err.Error(), "not a valid zstd file") {
+		t.Fatalf("openCouponFile err = %v, want zstd mismatch", err)
+	}
+
+	v := NewValidatorService(Config{Dir: dir, Files: []string{"broken.zst"}, MinLen: 8, MaxLen: 10, RequiredHits: 1})
+	_ = v.LoadCouponFiles()
+	if h := v.(HealthReporter).Health(); len(h) != 1 || h[0].Status != FileError {
+		t.Errorf("Health = %+v, want one file in error", h)
+	}
+}
+
+func TestValidatePromoCode_Globs(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"2025-01-01", "2025-02-01", "2025-03-01"} {
+		if err := os.MkdirAll(filepath.Join(dir, "drops", d), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	writeGzipFile(t, filepath.Join(dir, "drops", "2025-01-01", "a.gz"), []string{"JANCODE01", "BOTHCODE1"})
+	writeZstdFile(t, filepath.Join(dir, "drops", "2025-02-01", "b.zst"), []string{"FEBCODE01", "BOTHCODE1"})
+	writeFile(t, filepath.Join(dir, "drops", "2025-02-01", "README.md"), []byte("NOTACODE1\n"))
+
+	for _, mode := range []Mode{ModeStreaming, ModeIndexed} {
+		t.Run(string(mode), func(t *testing.T) {
+			v, err := New(Config{
+				Dir:          dir,
+				Files:        []string{"drops/2025-*/*.gz", "drops/2025-*/*.zst", "extra.txt"},
+				MinLen:       8,
+				MaxLen:       10,
+				RequiredHits: 2,
+				Mode:         mode,
+			})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			_ = v.LoadCouponFiles() // extra.txt is missing; tolerated
+
+			res := v.CheckPromoCode(context.Background(), "BOTHCODE1")
+			if !res.Valid || res.Files != 3 {
+				t.Errorf("BOTHCODE1 = %+v, want valid across 3 files", res)
+			}
+			if res := v.CheckPromoCode(context.Background(), "NOTACODE1"); res.Valid {
+				t.Errorf("NOTACODE1 from an unmatched file was accepted")
+			}
+
+			var names []string
+			for _, h := range v.(HealthReporter).Health() {
+				names = append(names, h.Name)
+			}
+			want := []string{
+				filepath.Join("drops", "2025-01-01", "a.gz"),
+				filepath.Join("drops", "2025-02-01", "b.zst"),
+				"extra.txt",
+			}
+			if strings.Join(names, ",") != strings.Join(want, ",") {
+				t.Errorf("Health names = %v, want %v", names, want)
+			}
+
+			// A new drop matching a pattern is picked up on reload.
+			drop := filepath.Join(dir, "drops", "2025-03-01", "c.gz")
+			writeGzipFile(t, drop, []string{"FEBCODE01"})
+			defer os.Remove(drop)
+			if v.ValidatePromoCode("FEBCODE01") {
+				t.Fatalf("FEBCODE01 valid before reload")
+			}
+			if err := v.(Reloader).Reload(); err != nil {
+				t.Fatalf("Reload: %v", err)
+			}
+			if res := v.CheckPromoCode(context.Background(), "FEBCODE01"); !res.Valid || res.Files != 4 {
+				t.Errorf("FEBCODE01 after reload = %+v, want valid across 4 files", res)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_BadPattern(t *testing.T) {
+	err := validateConfig(Config{Files: []string{"drops/[.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1})
+	if err == nil {
+		t.Fatal("want error for malformed pattern")
+	}
+}
+
+func TestRegisterFormat_Custom(t *testing.T) {
+	// "ROT0" files: a 4-byte header followed by plain text.
+	RegisterFormat(Format{
+		Name:       "rot0",
+		Magic:      []byte("ROT0"),
+		Extensions: []string{".rot0"},
+		Decode: func(r io.Reader) (io.ReadCloser, error) {
+			if _, err := io.ReadFull(r, make([]byte, 4)); err != nil {
+				return nil, err
+			}
+			return io.NopCloser(r), nil
+		},
+	})
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "x.rot0"), []byte("ROT0CUSTOMCD1\n"))
+	writeFile(t, filepath.Join(dir, "y.bin"), []byte("ROT0CUSTOMCD1\n"))
+
+	v := NewValidatorService(Config{Dir: dir, Files: []string{"*.rot0", "*.bin"}, MinLen: 8, MaxLen: 10, RequiredHits: 2})
+	if err := v.LoadCouponFiles(); err != nil {
+		t.Fatalf("LoadCouponFiles: %v", err)
+	}
+	if !v.ValidatePromoCode("CUSTOMCD1") {
+		t.Error("CUSTOMCD1 not found through the registered decoder")
+	}
+}
@@ -0,0 +1,44 @@
+package promovalidator
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// isGlob reports whether a Config.Files entry is a pattern rather than a name.
+func isGlob(entry string) bool {
+	return strings.ContainsAny(entry, "*?[")
+}
+
+// expandFiles resolves Config.Files against Dir: plain names are kept as-is
+// (so a missing file is still reported), patterns such as "drops/2025-*/*.zst"
+// expand to the matching files, sorted. Duplicates are dropped. Names are
+// relative to Dir.
+func expandFiles(cfg Config) []string {
+	out := make([]string, 0, len(cfg.Files))
+	seen := make(map[string]bool, len(cfg.Files))
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+
+	for _, entry := range cfg.Files {
+		if !isGlob(entry) {
+			add(entry)
+			continue
+		}
+		matches, _ := filepath.Glob(filepath.Join(cfg.Dir, entry)) // pattern validated in validateConfig
+		slices.Sort(matches)
+		for _, m := range matches {
+			rel, err := filepath.Rel(cfg.Dir, m)
+			if err != nil {
+				rel = m
+			}
+			add(rel)
+		}
+	}
+	return out
+}
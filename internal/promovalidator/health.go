@@ -0,0 +1,211 @@
+package promovalidator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FilePolicy decides how unreadable coupon files affect validation.
+type FilePolicy string
+
+const (
+	// PolicyTolerate skips missing/unreadable files; a code only needs
+	// RequiredHits among the files that could be read. This is the default.
+	PolicyTolerate FilePolicy = "tolerate"
+	// PolicyFailClosed rejects every code (ReasonFileError) while any
+	// configured file is known to be missing or unreadable.
+	PolicyFailClosed FilePolicy = "fail_closed"
+	// PolicyRequireAll is PolicyFailClosed, and additionally makes
+	// LoadCouponFiles (and reloads) fail unless every file can be read.
+	PolicyRequireAll FilePolicy = "require_all"
+)
+
+// strict reports whether file errors must reject codes.
+func (p FilePolicy) strict() bool {
+	return p == PolicyFailClosed || p == PolicyRequireAll
+}
+
+func (p FilePolicy) valid() bool {
+	switch p {
+	case "", PolicyTolerate, PolicyFailClosed, PolicyRequireAll:
+		return true
+	}
+	return false
+}
+
+// File health states.
+const (
+	FileOK      = "ok"
+	FileMissing = "missing"
+	FileError   = "error"   // exists but could not be opened, decompressed or scanned
+	FileUnknown = "unknown" // not read yet
+)
+
+// FileHealth describes the last known state of one coupon file.
+type FileHealth struct {
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`
+	Size        int64     `json:"size"`
+	LastScan    time.Time `json:"lastScan,omitempty"` // last successful read
+	LastError   string    `json:"lastError,omitempty"`
+	LastErrorAt time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// HealthReporter is implemented by validators that track per-file health.
+type HealthReporter interface {
+	// Health lists every coupon file in config order (patterns expanded).
+	Health() []FileHealth
+}
+
+// Healthy reports whether every file in hs is FileOK.
+func Healthy(hs []FileHealth) bool {
+	for _, h := range hs {
+		if h.Status != FileOK {
+			return false
+		}
+	}
+	return true
+}
+
+// Aggregate health states, as reported by HealthStatus.
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // some files are not FileOK
+	HealthFailed   = "failed"   // no file is FileOK
+)
+
+// HealthStatus sums hs up without naming files: HealthOK when every file is
+// FileOK, HealthFailed when none is, else HealthDegraded.
+func HealthStatus(hs []FileHealth) string {
+	ok := 0
+	for _, h := range hs {
+		if h.Status == FileOK {
+			ok++
+		}
+	}
+	switch {
+	case ok == len(hs):
+		return HealthOK
+	case ok == 0:
+		return HealthFailed
+	}
+	return HealthDegraded
+}
+
+// fileHealthRegistry records scan outcomes per coupon file.
+type fileHealthRegistry struct {
+	cfg Config
+
+	mu    sync.RWMutex
+	names []string // current file set (Config.Files with patterns expanded)
+	files map[string]*FileHealth
+}
+
+func newFileHealthRegistry(cfg Config) *fileHealthRegistry {
+	r := &fileHealthRegistry{cfg: cfg, files: make(map[string]*FileHealth)}
+	r.setFiles(cfg.Files)
+	return r
+}
+
+// setFiles replaces the tracked file set, keeping history for files that stay.
+func (r *fileHealthRegistry) setFiles(names []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	files := make(map[string]*FileHealth, len(names))
+	for _, name := range names {
+		if h, ok := r.files[name]; ok {
+			files[name] = h
+		} else {
+			files[name] = &FileHealth{Name: name, Status: FileUnknown}
+		}
+	}
+	r.names = append([]string(nil), names...)
+	r.files = files
+}
+
+// record stores the outcome of reading name; err == nil means success.
+// scanned marks a successful read of the contents (not just the header).
+func (r *fileHealthRegistry) record(name string, err error, scanned bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.files[name]
+	if !ok {
+		h = &FileHealth{Name: name}
+		r.files[name] = h
+	}
+	now := time.Now()
+	if err == nil {
+		h.Status = FileOK
+		h.LastError = ""
+		if scanned {
+			h.LastScan = now
+		}
+		return
+	}
+	h.Status = FileError
+	if errors.Is(err, fs.ErrNotExist) {
+		h.Status = FileMissing
+	}
+	h.LastError = err.Error()
+	h.LastErrorAt = now
+}
+
+// failed lists the files currently known to be missing or unreadable.
+func (r *fileHealthRegistry) failed() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []string
+	for _, name := range r.names {
+		if h := r.files[name]; h != nil && (h.Status == FileMissing || h.Status == FileError) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// snapshot returns health in config order, with sizes refreshed from disk.
+func (r *fileHealthRegistry) snapshot() []FileHealth {
+	r.mu.RLock()
+	out := make([]FileHealth, 0, len(r.names))
+	for _, name := range r.names {
+		out = append(out, *r.files[name])
+	}
+	r.mu.RUnlock()
+
+	for i := range out {
+		if fi, err := os.Stat(filepath.Join(r.cfg.Dir, out[i].Name)); err == nil {
+			out[i].Size = fi.Size()
+		}
+	}
+	return out
+}
+
+// probe expands the file set, opens every file (reading just enough to check
+// its compression header) and records the outcome. It returns the files and
+// the first error seen.
+func (r *fileHealthRegistry) probe() ([]string, error) {
+	names := expandFiles(r.cfg)
+	r.setFiles(names)
+	var first error
+	for _, name := range names {
+		err := probeFile(filepath.Join(r.cfg.Dir, name))
+		r.record(name, err, false)
+		if err != nil && first == nil {
+			first = fmt.Errorf("validator: coupon file %s: %w", name, err)
+		}
+	}
+	return names, first
+}
+
+func probeFile(filename string) error {
+	rc, err := openCouponFile(filename)
+	if err != nil {
+		return err
+	}
+	return rc.Close()
+}
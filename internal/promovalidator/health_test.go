@@ -0,0 +1,158 @@
+package promovalidator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilePolicy_MissingFile(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "couponbase1.gz"), []string{"HAPPYHRS"})
+	writeGzipFile(t, filepath.Join(dir, "couponbase2.gz"), []string{"HAPPYHRS"})
+	// couponbase3.gz intentionally missing
+
+	base := Config{
+		Dir:          dir,
+		Files:        []string{"couponbase1.gz", "couponbase2.gz", "couponbase3.gz"},
+		MinLen:       8,
+		MaxLen:       10,
+		RequiredHits: 2,
+	}
+
+	tests := []struct {
+		policy    FilePolicy
+		wantLoad  bool // LoadCouponFiles succeeds
+		wantValid bool
+	}{
+		{policy: "", wantLoad: true, wantValid: true},
+		{policy: PolicyTolerate, wantLoad: true, wantValid: true},
+		{policy: PolicyFailClosed, wantLoad: true, wantValid: false},
+		{policy: PolicyRequireAll, wantLoad: false, wantValid: false},
+	}
+	for _, mode := range []Mode{ModeStreaming, ModeIndexed} {
+		for _, tt := range tests {
+			cfg := base
+			cfg.Mode, cfg.FilePolicy = mode, tt.policy
+			v, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if err := v.LoadCouponFiles(); (err == nil) != tt.wantLoad {
+				t.Errorf("%s/%q: LoadCouponFiles err=%v, want success=%v", mode, tt.policy, err, tt.wantLoad)
+			}
+			res := v.CheckPromoCode(context.Background(), "HAPPYHRS")
+			if res.Valid != tt.wantValid {
+				t.Errorf("%s/%q: valid=%v want %v (%+v)", mode, tt.policy, res.Valid, tt.wantValid, res)
+			}
+			if tt.policy == PolicyFailClosed && res.Reason != ReasonFileError {
+				t.Errorf("%s/%q: reason=%s want %s", mode, tt.policy, res.Reason, ReasonFileError)
+			}
+		}
+	}
+}
+
+func TestFilePolicy_FailClosedRecoversAfterReload(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS"})
+	bad := filepath.Join(dir, "b.gz")
+	if err := os.WriteFile(bad, []byte("corrupt"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	v := NewValidatorService(Config{
+		Dir: dir, Files: []string{"a.gz", "b.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1,
+		FilePolicy: PolicyFailClosed,
+	})
+	if err := v.LoadCouponFiles(); err != nil {
+		t.Fatalf("LoadCouponFiles: %v", err)
+	}
+	if v.ValidatePromoCode("HAPPYHRS") {
+		t.Fatalf("expected fail-closed rejection while b.gz is corrupt")
+	}
+
+	writeGzipFile(t, bad, []string{"OTHERCODE"})
+	if err := v.(Reloader).Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if !v.ValidatePromoCode("HAPPYHRS") {
+		t.Fatalf("expected HAPPYHRS to validate once b.gz is fixed")
+	}
+}
+
+func TestIndexed_FailedFirstLoadRecoversAfterReload(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS"})
+
+	v := NewIndexedValidatorService(Config{
+		Dir: dir, Files: []string{"a.gz", "b.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 2,
+		FilePolicy: PolicyRequireAll,
+	})
+	if err := v.LoadCouponFiles(); err == nil {
+		t.Fatal("expected LoadCouponFiles to fail while b.gz is missing")
+	}
+	if res := v.CheckPromoCode(context.Background(), "HAPPYHRS"); res.Reason != ReasonMisconfigured {
+		t.Fatalf("reason = %s, want %s", res.Reason, ReasonMisconfigured)
+	}
+
+	writeGzipFile(t, filepath.Join(dir, "b.gz"), []string{"HAPPYHRS"})
+	if err := v.(Reloader).Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if err := v.LoadCouponFiles(); err != nil {
+		t.Fatalf("LoadCouponFiles after reload: %v", err)
+	}
+	if res := v.CheckPromoCode(context.Background(), "HAPPYHRS"); !res.Valid {
+		t.Fatalf("expected HAPPYHRS to validate after a successful reload, got %+v", res)
+	}
+}
+
+func TestHealth_ReportsFileStates(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "ok.gz"), []string{"HAPPYHRS"})
+	if err := os.WriteFile(filepath.Join(dir, "corrupt.gz"), []byte("corrupt"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	v := NewValidatorService(Config{
+		Dir: dir, Files: []string{"ok.gz", "corrupt.gz", "missing.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1,
+	})
+	if err := v.LoadCouponFiles(); err != nil {
+		t.Fatalf("LoadCouponFiles: %v", err)
+	}
+	_ = v.ValidatePromoCode("NOTTHERE1") // full scan of ok.gz
+
+	hs := v.(HealthReporter).Health()
+	want := []string{FileOK, FileError, FileMissing}
+	if len(hs) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(hs), len(want))
+	}
+	for i, h := range hs {
+		if h.Status != want[i] {
+			t.Errorf("%s: status=%s want %s", h.Name, h.Status, want[i])
+		}
+	}
+	if hs[0].Size == 0 || hs[0].LastScan.IsZero() {
+		t.Errorf("expected size and last scan for ok.gz, got %+v", hs[0])
+	}
+	if hs[1].LastError == "" {
+		t.Errorf("expected last error for corrupt.gz")
+	}
+	if Healthy(hs) {
+		t.Errorf("Healthy() = true, want false")
+	}
+	if got := HealthStatus(hs); got != HealthDegraded {
+		t.Errorf("HealthStatus() = %q, want %q", got, HealthDegraded)
+	}
+	if got := HealthStatus(hs[1:]); got != HealthFailed {
+		t.Errorf("HealthStatus(no ok file) = %q, want %q", got, HealthFailed)
+	}
+}
+
+func TestValidateConfig_UnknownPolicy(t *testing.T) {
+	v := NewValidatorService(Config{Files: []string{"a.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1, FilePolicy: "strict"})
+	if err := v.LoadCouponFiles(); err == nil {
+		t.Fatalf("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,373 @@
+package promovalidator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// IndexStats describes the in-memory footprint of an indexed validator.
+type IndexStats struct {
+	Files       []FileIndexStats `json:"files"`
+	TotalCodes  int              `json:"totalCodes"`
+	MemoryBytes int64            `json:"memoryBytes"`
+	BuiltAt     time.Time        `json:"builtAt"`
+	BuildTime   time.Duration    `json:"buildTime"`
+	Source      string           `json:"source,omitempty"`      // compiled index file it was loaded from, if any
+	SourceError string           `json:"sourceError,omitempty"` // why Config.IndexFile was not used
+}
+
+// FileIndexStats describes the index built for a single coupon file.
+type FileIndexStats struct {
+	Name  string `json:"name"`
+	Codes int    `json:"codes"` // distinct tokens within MinLen..MaxLen
+	Bytes int64  `json:"bytes"`
+	Error string `json:"error,omitempty"` // set when the file was skipped
+}
+
+// IndexReporter is implemented by validators that keep an in-memory index.
+type IndexReporter interface {
+	IndexStats() IndexStats
+}
+
+// fileIndex is the set of distinct eligible tokens of one file, stored as a
+// sorted slice of tokenKey values (8 bytes per code).
+type fileIndex struct {
+	name    string
+	keys    []uint64
+	err     error
+	size    int64 // of the coupon file, stat'ed before it was read
+	modTime int64 // unix ns, ditto
+}
+
+func (fi *fileIndex) contains(key uint64) bool {
+	_, ok := slices.BinarySearch(fi.keys, key)
+	return ok
+}
+
+// couponIndex is an immutable snapshot of all configured files.
+type couponIndex struct {
+	files []fileIndex
+	stats IndexStats
+}
+
+// indexedValidator implements ValidatorService by reading every file once in
+// LoadCouponFiles and answering lookups from a compact in-memory index.
+//
+// Tokens are kept packed into 64 bits by tokenKey, which loses nothing for
+// codes of up to maxKeyLen characters, so matching is exact at 8 bytes per
+// code. Config.MaxLen must therefore not exceed maxKeyLen.
+type indexedValidator struct {
+	cfg    Config
+	once   sync.Once
+	cfgErr error                 // invalid configuration; permanent
+	init   atomic.Pointer[error] // why the last (re)build failed while no index was serving
+
+	// idx is swapped atomically on reload; lookups never block on a rebuild.
+	idx    atomic.Pointer[couponIndex]
+	rl     *reloader
+	health *fileHealthRegistry
+	sched  *schedules
+}
+
+var (
+	_ IndexReporter  = (*indexedValidator)(nil)
+	_ Reloader       = (*indexedValidator)(nil)
+	_ StatusReporter = (*indexedValidator)(nil)
+	_ HealthReporter = (*indexedValidator)(nil)
+)
+
+// NewIndexedValidatorService creates a validator that builds its index in LoadCouponFiles.
+func NewIndexedValidatorService(cfg Config) ValidatorService {
+	v := &indexedValidator{cfg: cfg, health: newFileHealthRegistry(cfg), sched: newSchedules(cfg)}
+	v.rl = newReloader(cfg, v.rebuild)
+	return v
+}
+
+// LoadCouponFiles validates configuration and builds the index (file IO happens here).
+// Missing/unreadable files are skipped, matching the streaming validator, unless
+// FilePolicy is PolicyRequireAll.
+// A failed first load is not final: a later successful Reload (or Watch
+// noticing the files change) brings the validator up.
+func (v *indexedValidator) LoadCouponFiles() error {
+	v.once.Do(func() {
+		if v.cfgErr = validateIndexConfig(v.cfg); v.cfgErr != nil {
+			return
+		}
+		if err := v.rebuild(); err != nil {
+			return
+		}
+		v.rl.prime()
+	})
+	if v.cfgErr != nil {
+		return v.cfgErr
+	}
+	if err := v.init.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// Reload builds a fresh index in the background of ongoing lookups and swaps
+// it in. On failure the previous index keeps serving.
+func (v *indexedValidator) Reload() error {
+	_ = v.LoadCouponFiles() // the first load, if it hasn't happened yet
+	if v.cfgErr != nil {
+		return v.cfgErr
+	}
+	return v.rl.reload()
+}
+
+// Watch polls the coupon files and reloads when they change, until ctx is done.
+func (v *indexedValidator) Watch(ctx context.Context) { v.rl.watch(ctx) }
+
+// ReloadStatus reports the outcome of the most recent (re)load.
+func (v *indexedValidator) ReloadStatus() ReloadStatus { return v.rl.snapshot() }
+
+// Health lists the state of every configured file as of the last (re)build.
+func (v *indexedValidator) Health() []FileHealth { return v.health.snapshot() }
+
+// Status reports the validator's operational state.
+func (v *indexedValidator) Status() Status {
+	st := Status{Mode: ModeIndexed, Reload: v.ReloadStatus(), Health: v.Health(), Schedule: v.sched.cur.Load()}
+	if idx := v.idx.Load(); idx != nil {
+		stats := idx.stats
+		st.Index = &stats
+	}
+	return st
+}
+
+func (v *indexedValidator) rebuild() error {
+	err := v.build()
+	if err != nil && v.idx.Load() == nil {
+		v.init.Store(&err)
+	} else if err == nil {
+		v.init.Store(nil)
+	}
+	return err
+}
+
+func (v *indexedValidator) build() error {
+	if err := v.sched.load(); err != nil {
+		return err
+	}
+	idx, err := v.loadIndex()
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(idx.files))
+	for i := range idx.files {
+		names[i] = idx.files[i].name
+	}
+	v.health.setFiles(names)
+
+	var broken error
+	for i := range idx.files {
+		fi := &idx.files[i]
+		v.health.record(fi.name, fi.err, fi.err == nil)
+		if fi.err != nil && broken == nil {
+			broken = fmt.Errorf("validator: coupon file %s: %w", fi.name, fi.err)
+		}
+	}
+	if broken != nil && v.cfg.FilePolicy == PolicyRequireAll {
+		return broken
+	}
+	v.idx.Store(idx)
+	return nil
+}
+
+// ValidatePromoCode checks code (case-sensitive) against the in-memory index.
+func (v *indexedValidator) ValidatePromoCode(code string) bool {
+	return v.ValidatePromoCodeContext(context.Background(), code)
+}
+
+// ValidatePromoCodeContext reports CheckPromoCode(ctx, code).Valid.
+func (v *indexedValidator) ValidatePromoCodeContext(ctx context.Context, code string) bool {
+	return v.CheckPromoCode(ctx, code).Valid
+}
+
+// CheckPromoCode checks code against the in-memory index. Lookups never touch
+// the disk, so ctx is only checked up front and every file is consulted.
+func (v *indexedValidator) CheckPromoCode(ctx context.Context, code string) Result {
+	res := v.lookup(ctx, code)
+	v.sched.apply(&res)
+	return res
+}
+
+// lookup decides index membership of code.
+func (v *indexedValidator) lookup(ctx context.Context, code string) Result {
+	code = strings.TrimSpace(code)
+	if v.LoadCouponFiles() != nil {
+		res := newResult(v.cfg, code)
+		res.Reason = ReasonMisconfigured
+		return res
+	}
+	res, ok := checkFormat(v.cfg, code)
+	if !ok {
+		return res
+	}
+	if ctx.Err() != nil {
+		res.Reason = ReasonAborted
+		return res
+	}
+
+	idx := v.idx.Load()
+	res.Files = len(idx.files)
+	key := tokenKey(code)
+	for i := range idx.files {
+		fi := &idx.files[i]
+		if fi.contains(key) {
+			res.Hits++
+			res.MatchedFiles = append(res.MatchedFiles, fi.name)
+		} else if fi.err != nil {
+			res.UnreadableFiles = append(res.UnreadableFiles, fi.name)
+		}
+	}
+	res.decide()
+	if v.cfg.FilePolicy.strict() && len(res.UnreadableFiles) > 0 {
+		res.failFiles(nil)
+	}
+	return res
+}
+
+// IndexStats reports the size of the loaded index (zero value before loading).
+func (v *indexedValidator) IndexStats() IndexStats {
+	if v.LoadCouponFiles() != nil {
+		return IndexStats{}
+	}
+	return v.idx.Load().stats
+}
+
+// loadIndex reads Config.IndexFile when it is set and up to date, and
+// otherwise builds the index from the coupon files.
+func (v *indexedValidator) loadIndex() (*couponIndex, error) {
+	if v.cfg.IndexFile == "" {
+		return buildIndex(v.cfg)
+	}
+	path := v.cfg.IndexFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(v.cfg.Dir, path)
+	}
+	idx, ferr := readIndexFile(v.cfg, path)
+	if ferr == nil {
+		return idx, nil
+	}
+	idx, err := buildIndex(v.cfg)
+	if err != nil {
+		return nil, err
+	}
+	idx.stats.SourceError = ferr.Error()
+	return idx, nil
+}
+
+// buildIndex reads every configured file and returns the resulting index.
+// It fails only when the index would exceed cfg.MaxIndexBytes.
+func buildIndex(cfg Config) (*couponIndex, error) {
+	start := time.Now()
+	names := expandFiles(cfg)
+	idx := &couponIndex{files: make([]fileIndex, 0, len(names))}
+
+	for _, name := range names {
+		fi := buildFileIndex(filepath.Join(cfg.Dir, name), cfg.MinLen, cfg.MaxLen)
+		fi.name = name
+
+		fs := FileIndexStats{Name: name, Codes: len(fi.keys), Bytes: int64(len(fi.keys)) * 8}
+		if fi.err != nil {
+			fs.Error = fi.err.Error()
+		}
+		idx.stats.Files = append(idx.stats.Files, fs)
+		idx.stats.TotalCodes += fs.Codes
+		idx.stats.MemoryBytes += fs.Bytes
+
+		if cfg.MaxIndexBytes > 0 && idx.stats.MemoryBytes > cfg.MaxIndexBytes {
+			return nil, fmt.Errorf("validator: index exceeds %d bytes after %s", cfg.MaxIndexBytes, name)
+		}
+		idx.files = append(idx.files, fi)
+	}
+
+	idx.stats.BuiltAt = time.Now()
+	idx.stats.BuildTime = idx.stats.BuiltAt.Sub(start)
+	return idx, nil
+}
+
+// buildFileIndex collects the distinct eligible tokens of one file.
+// Errors are recorded on the result rather than returned, so a broken file
+// simply contributes no (or only its readable) codes.
+func buildFileIndex(filename string, minLen, maxLen int) fileIndex {
+	// Stamp the file before reading it: if it changes while being read, the
+	// stamp is the old one and a compiled index of it is stale, not fresh.
+	st, err := os.Stat(filename)
+	if err != nil {
+		return fileIndex{err: err}
+	}
+	r, err := openCouponFile(filename)
+	if err != nil {
+		return fileIndex{err: err}
+	}
+	defer r.Close()
+
+	var keys []uint64
+	err = scanTokens(r, minLen, maxLen, func(w string) bool {
+		keys = append(keys, tokenKey(w))
+		return true
+	})
+	// Like foundInFile, tokens read before a scan error still count.
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+	return fileIndex{keys: slices.Clip(keys), err: err, size: st.Size(), modTime: st.ModTime().UnixNano()}
+}
+
+// maxKeyLen is the longest token tokenKey can pack: 63^10 < 2^64.
+const maxKeyLen = 10
+
+// validateIndexConfig is validateConfig plus the length limit of tokenKey.
+func validateIndexConfig(cfg Config) error {
+	if err := validateConfig(cfg); err != nil {
+		return err
+	}
+	if cfg.MaxLen > maxKeyLen {
+		return fmt.Errorf("validator: indexed mode matches codes of up to %d characters, MaxLen is %d", maxKeyLen, cfg.MaxLen)
+	}
+	return nil
+}
+
+// tokenKey packs an alphanumeric token of up to maxKeyLen bytes into 64 bits
+// without loss. Each byte becomes a digit from 1 to 62 in base 63; with no
+// zero digit, different tokens (of any lengths) get different keys.
+func tokenKey(s string) uint64 {
+	var key uint64
+	for i := 0; i < len(s); i++ {
+		var d byte
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			d = c - '0' + 1
+		case c >= 'A' && c <= 'Z':
+			d = c - 'A' + 11
+		default:
+			d = c - 'a' + 37
+		}
+		key = key*63 + uint64(d)
+	}
+	return key
+}
+
+// hashToken is 64-bit FNV-1a, inlined to avoid allocating a hash.Hash per
+// token. Only approximate structures (the prefilter, Inspect) use it.
+func hashToken(s string) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime64
+	}
+	return h
+}
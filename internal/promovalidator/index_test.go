@@ -0,0 +1,138 @@
+package promovalidator
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexedValidator_MatchesStreamingSemantics(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "couponbase1.gz"), []string{"HAPPYHRS", "random WELCOME10 here", "SHORT", "TOOLONGCODE1"})
+	writeGzipFile(t, filepath.Join(dir, "couponbase2.gz"), []string{"HAPPYHRS,WELCOME10", "ONLYHERE1"})
+	// couponbase3.gz intentionally missing
+
+	cfg := Config{
+		Dir:          dir,
+		Files:        []string{"couponbase1.gz", "couponbase2.gz", "couponbase3.gz"},
+		MinLen:       8,
+		MaxLen:       10,
+		RequiredHits: 2,
+	}
+	streaming := NewValidatorService(cfg)
+	indexed := NewIndexedValidatorService(cfg)
+	if err := indexed.LoadCouponFiles(); err != nil {
+		t.Fatalf("LoadCouponFiles: %v", err)
+	}
+
+	codes := []string{
+		"HAPPYHRS",       // in both files
+		"WELCOME10",      // tokenized in both files
+		"ONLYHERE1",      // only one file
+		"happyhrs",       // case sensitive
+		"SHORT",          // too short
+		"TOOLONGCODE1",   // too long
+		" HAPPYHRS ",     // trimmed
+		"randomWELCOME1", // substring only
+	}
+	for _, c := range codes {
+		want := streaming.ValidatePromoCode(c)
+		if got := indexed.ValidatePromoCode(c); got != want {
+			t.Errorf("indexed.ValidatePromoCode(%q) = %v, streaming = %v", c, got, want)
+		}
+	}
+}
+
+func TestTokenKey_Exact(t *testing.T) {
+	tokens := []string{
+		"0", "00", "A", "0A", "A0", "AA", "a", "Aa", "aA", "z", "zz", "Z9",
+		"HAPPYHRS", "HAPPYHRS0", "0HAPPYHRS", "happyhrs", "zzzzzzzzzz", "ZZZZZZZZZZ", "9999999999",
+	}
+	seen := make(map[uint64]string, len(tokens))
+	for _, tok := range tokens {
+		key := tokenKey(tok)
+		if other, ok := seen[key]; ok {
+			t.Fatalf("tokenKey(%q) = tokenKey(%q) = %d", tok, other, key)
+		}
+		seen[key] = tok
+	}
+	if tokenKey("zzzzzzzzzz") < tokenKey("zzzzzzzzz") {
+		t.Fatal("the longest tokens overflow 64 bits")
+	}
+}
+
+func TestIndexedValidator_MaxLenLimit(t *testing.T) {
+	v := NewIndexedValidatorService(Config{Files: []string{"a.gz"}, MinLen: 8, MaxLen: maxKeyLen + 1, RequiredHits: 1})
+	if err := v.LoadCouponFiles(); err == nil {
+		t.Fatalf("expected an error for MaxLen > %d in indexed mode", maxKeyLen)
+	}
+}
+
+func TestIndexedValidator_Stats(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS HAPPYHRS WELCOME10", "tiny"})
+
+	v := NewIndexedValidatorService(Config{
+		Dir: dir, Files: []string{"a.gz", "missing.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1,
+	})
+	if err := v.LoadCouponFiles(); err != nil {
+		t.Fatalf("LoadCouponFiles: %v", err)
+	}
+
+	st := v.(IndexReporter).IndexStats()
+	if st.TotalCodes != 2 {
+		t.Fatalf("TotalCodes = %d, want 2 (distinct eligible tokens)", st.TotalCodes)
+	}
+	if st.MemoryBytes != 16 {
+		t.Fatalf("MemoryBytes = %d, want 16", st.MemoryBytes)
+	}
+	if len(st.Files) != 2 || st.Files[1].Error == "" {
+		t.Fatalf("expected missing file to be reported, got %+v", st.Files)
+	}
+}
+
+func TestIndexedValidator_MaxIndexBytes(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS", "WELCOME10", "SUMMER2024"})
+
+	v := NewIndexedValidatorService(Config{
+		Dir: dir, Files: []string{"a.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1, MaxIndexBytes: 16,
+	})
+	if err := v.LoadCouponFiles(); err == nil {
+		t.Fatalf("expected error when index exceeds MaxIndexBytes")
+	}
+	if v.ValidatePromoCode("HAPPYHRS") {
+		t.Fatalf("expected validation to fail when the index could not be built")
+	}
+}
+
+func TestNew_SelectsMode(t *testing.T) {
+	base := Config{Files: []string{"a.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1}
+
+	tests := []struct {
+		mode    Mode
+		indexed bool
+		wantErr bool
+	}{
+		{mode: "", indexed: false},
+		{mode: ModeStreaming, indexed: false},
+		{mode: ModeIndexed, indexed: true},
+		{mode: "bogus", wantErr: true},
+	}
+	for _, tt := range tests {
+		cfg := base
+		cfg.Mode = tt.mode
+		v, err := New(cfg)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("New(mode=%q): expected error", tt.mode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("New(mode=%q): %v", tt.mode, err)
+		}
+		if _, ok := v.(IndexReporter); ok != tt.indexed {
+			t.Errorf("New(mode=%q): indexed = %v, want %v", tt.mode, ok, tt.indexed)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package promovalidator
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Compiled index file layout (little-endian), written by WriteIndexFile:
+//
+//	magic "PVIX" | version u32 | minLen u32 | maxLen u32 | files u32
+//	per file: nameLen u16 | name | size i64 | modTime i64 (unix ns) | count u64 | count × key u64
+//	crc32 (IEEE) of everything above, u32
+//
+// Each file entry records the size and mtime the coupon file had before it was
+// read; the index is only used while all of them still match.
+const (
+	indexFileMagic   = "PVIX"
+	indexFileVersion = 2 // 1 held FNV-1a hashes instead of tokenKey values
+)
+
+// errStaleIndex means the index file does not describe the current coupon files.
+var errStaleIndex = errors.New("index file is stale")
+
+// WriteIndexFile builds the index for cfg and writes it to filename (via a
+// temporary file and rename). Unlike LoadCouponFiles it fails if any coupon
+// file cannot be read, so a compiled index is never partial.
+func WriteIndexFile(cfg Config, filename string) (IndexStats, error) {
+	if err := validateIndexConfig(cfg); err != nil {
+		return IndexStats{}, err
+	}
+	idx, err := buildIndex(cfg)
+	if err != nil {
+		return IndexStats{}, err
+	}
+	for i := range idx.files {
+		if fi := &idx.files[i]; fi.err != nil {
+			return IndexStats{}, fmt.Errorf("validator: coupon file %s: %w", fi.name, fi.err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".index-*")
+	if err != nil {
+		return IndexStats{}, err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if err := writeIndex(tmp, cfg, idx); err != nil {
+		tmp.Close()
+		return IndexStats{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return IndexStats{}, err
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return IndexStats{}, err
+	}
+	return idx.stats, nil
+}
+
+func writeIndex(w io.Writer, cfg Config, idx *couponIndex) error {
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	put := func(v any) { _ = binary.Write(bw, binary.LittleEndian, v) } // bufio defers errors to Flush
+
+	bw.WriteString(indexFileMagic)
+	put(uint32(indexFileVersion))
+	put(uint32(cfg.MinLen))
+	put(uint32(cfg.MaxLen))
+	put(uint32(len(idx.files)))
+	for i := range idx.files {
+		fi := &idx.files[i]
+		put(uint16(len(fi.name)))
+		bw.WriteString(fi.name)
+		put(fi.size) // as stat'ed before the file was read
+		put(fi.modTime)
+		put(uint64(len(fi.keys)))
+		put(fi.keys)
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, crc.Sum32())
+}
+
+// readIndexFile loads a compiled index, returning errStaleIndex if it was
+// built with other length bounds or from other versions of the coupon files.
+func readIndexFile(cfg Config, filename string) (*couponIndex, error) {
+	start := time.Now()
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	crc := crc32.NewIEEE()
+	br := bufio.NewReader(f)
+	r := io.TeeReader(br, crc)
+	get := func(v any) error { return binary.Read(r, binary.LittleEndian, v) }
+
+	magic := make([]byte, len(indexFileMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != indexFileMagic {
+		return nil, fmt.Errorf("%s: not an index file", filepath.Base(filename))
+	}
+	var version, minLen, maxLen, n uint32
+	if err := errors.Join(get(&version), get(&minLen), get(&maxLen), get(&n)); err != nil {
+		return nil, err
+	}
+	if version != indexFileVersion {
+		return nil, fmt.Errorf("%s: unsupported index version %d", filepath.Base(filename), version)
+	}
+	if int(minLen) != cfg.MinLen || int(maxLen) != cfg.MaxLen {
+		return nil, fmt.Errorf("%w: built for lengths %d-%d", errStaleIndex, minLen, maxLen)
+	}
+
+	names := expandFiles(cfg)
+	if int(n) != len(names) {
+		return nil, fmt.Errorf("%w: has %d files, config has %d", errStaleIndex, n, len(names))
+	}
+	idx := &couponIndex{files: make([]fileIndex, 0, n)}
+	for i := 0; i < int(n); i++ {
+		var nameLen uint16
+		var size, mod int64
+		var count uint64
+		if err := get(&nameLen); err != nil {
+			return nil, err
+		}
+		name := make([]byte, nameLen)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, err
+		}
+		if err := errors.Join(get(&size), get(&mod), get(&count)); err != nil {
+			return nil, err
+		}
+		if string(name) != names[i] {
+			return nil, fmt.Errorf("%w: file %d is %s, config has %s", errStaleIndex, i+1, name, names[i])
+		}
+		st, err := os.Stat(filepath.Join(cfg.Dir, names[i]))
+		if err != nil || st.Size() != size || st.ModTime().UnixNano() != mod {
+			return nil, fmt.Errorf("%w: %s changed since it was compiled", errStaleIndex, names[i])
+		}
+		if count > uint64(info.Size())/8 { // guards the allocation against a corrupt count
+			return nil, fmt.Errorf("%s: corrupt entry for %s", filepath.Base(filename), names[i])
+		}
+		keys := make([]uint64, count)
+		if err := get(keys); err != nil {
+			return nil, err
+		}
+		if !slices.IsSorted(keys) {
+			return nil, fmt.Errorf("%s: corrupt entry for %s", filepath.Base(filename), names[i])
+		}
+		idx.files = append(idx.files, fileIndex{name: names[i], keys: keys})
+
+		fs := FileIndexStats{Name: names[i], Codes: len(keys), Bytes: int64(len(keys)) * 8}
+		idx.stats.Files = append(idx.stats.Files, fs)
+		idx.stats.TotalCodes += fs.Codes
+		idx.stats.MemoryBytes += fs.Bytes
+		if cfg.MaxIndexBytes > 0 && idx.stats.MemoryBytes > cfg.MaxIndexBytes {
+			return nil, fmt.Errorf("validator: index exceeds %d bytes after %s", cfg.MaxIndexBytes, names[i])
+		}
+	}
+
+	sum := crc.Sum32()
+	var want uint32
+	if err := binary.Read(br, binary.LittleEndian, &want); err != nil || want != sum {
+		return nil, fmt.Errorf("%s: checksum mismatch", filepath.Base(filename))
+	}
+
+	idx.stats.Source = filepath.Base(filename)
+	idx.stats.BuiltAt = time.Now()
+	idx.stats.BuildTime = idx.stats.BuiltAt.Sub(start)
+	return idx, nil
+}
@@ -0,0 +1,169 @@
+package promovalidator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteIndexFile_LoadedByIndexedValidator(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS", "FIFTYOFF"})
+	writeGzipFile(t, filepath.Join(dir, "b.gz"), []string{"HAPPYHRS", "ONLYINB1"})
+	cfg := Config{Dir: dir, Files: []string{"*.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 2}
+
+	st, err := WriteIndexFile(cfg, filepath.Join(dir, "coupons.idx"))
+	if err != nil {
+		t.Fatalf("WriteIndexFile: %v", err)
+	}
+	if st.TotalCodes != 4 {
+		t.Fatalf("TotalCodes = %d, want 4", st.TotalCodes)
+	}
+
+	cfg.Mode, cfg.IndexFile = ModeIndexed, "coupons.idx"
+	v, _ := New(cfg)
+	if err := v.LoadCouponFiles(); err != nil {
+		t.Fatalf("LoadCouponFiles: %v", err)
+	}
+	if got := v.(IndexReporter).IndexStats(); got.Source != "coupons.idx" || got.SourceError != "" {
+		t.Fatalf("index not loaded from file: %+v", got)
+	}
+	if !v.ValidatePromoCode("HAPPYHRS") || v.ValidatePromoCode("ONLYINB1") {
+		t.Fatal("lookups through the compiled index are wrong")
+	}
+}
+
+func TestReadIndexFile_Stale(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS"})
+	cfg := Config{Dir: dir, Files: []string{"a.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1}
+	path := filepath.Join(dir, "coupons.idx")
+	if _, err := WriteIndexFile(cfg, path); err != nil {
+		t.Fatalf("WriteIndexFile: %v", err)
+	}
+
+	other := cfg
+	other.MaxLen = 12
+	if _, err := readIndexFile(other, path); err == nil {
+		t.Error("index built for other length bounds was accepted")
+	}
+
+	// Rewrite the coupon file: the index must fall back to the files.
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"NEWCODE1"})
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "a.gz"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readIndexFile(cfg, path); err == nil {
+		t.Fatal("stale index was accepted")
+	}
+
+	cfg.Mode, cfg.IndexFile = ModeIndexed, path
+	v, _ := New(cfg)
+	if err := v.LoadCouponFiles(); err != nil {
+		t.Fatalf("LoadCouponFiles: %v", err)
+	}
+	if st := v.(IndexReporter).IndexStats(); st.Source != "" || st.SourceError == "" {
+		t.Fatalf("expected fallback to coupon files, got %+v", st)
+	}
+	if !v.ValidatePromoCode("NEWCODE1") {
+		t.Fatal("fallback index does not contain the new code")
+	}
+}
+
+func TestWriteIndex_StampsFilesBeforeReading(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS"})
+	cfg := Config{Dir: dir, Files: []string{"a.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1}
+	idx, err := buildIndex(cfg)
+	if err != nil {
+		t.Fatalf("buildIndex: %v", err)
+	}
+
+	// the file changes after it was read but before the index is written
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"NEWCODE1", "NEWCODE2"})
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "a.gz"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "coupons.idx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeIndex(f, cfg, idx); err != nil {
+		t.Fatalf("writeIndex: %v", err)
+	}
+	f.Close()
+
+	if _, err := readIndexFile(cfg, path); !errors.Is(err, errStaleIndex) {
+		t.Fatalf("readIndexFile err = %v, want the index of the old contents to be stale", err)
+	}
+}
+
+func TestReadIndexFile_Corrupt(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS"})
+	cfg := Config{Dir: dir, Files: []string{"a.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1}
+	path := filepath.Join(dir, "coupons.idx")
+	if _, err := WriteIndexFile(cfg, path); err != nil {
+		t.Fatalf("WriteIndexFile: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	data[len(data)-6] ^= 0xff // flip a hash byte
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readIndexFile(cfg, path); err == nil {
+		t.Fatal("corrupt index was accepted")
+	}
+}
+
+func TestWriteIndexFile_RefusesUnreadableFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS"})
+	cfg := Config{Dir: dir, Files: []string{"a.gz", "missing.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1}
+	if _, err := WriteIndexFile(cfg, filepath.Join(dir, "coupons.idx")); err == nil {
+		t.Fatal("expected error for a missing coupon file")
+	}
+}
+
+func TestInspectAndCommonCodes(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS FIFTYOFF", "HAPPYHRS short"})
+	writeFile(t, filepath.Join(dir, "b.txt"), []byte("FIFTYOFF\nONLYINB1"))
+	writeGzipFile(t, filepath.Join(dir, "c.gz"), []string{"HAPPYHRS"})
+	cfg := Config{Dir: dir, Files: []string{"a.gz", "b.txt", "c.gz", "gone.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 2}
+
+	reps := InspectFiles(cfg)
+	want := []FileReport{
+		{Name: "a.gz", Format: "gzip", Lines: 2, Tokens: 3, Distinct: 2},
+		{Name: "b.txt", Format: "plain", Lines: 2, Tokens: 2, Distinct: 2},
+		{Name: "c.gz", Format: "gzip", Lines: 1, Tokens: 1, Distinct: 1},
+	}
+	for i, w := range want {
+		got := reps[i]
+		if got.Name != w.Name || got.Format != w.Format || got.Lines != w.Lines || got.Tokens != w.Tokens || got.Distinct != w.Distinct {
+			t.Errorf("report %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if reps[3].Error == "" {
+		t.Errorf("missing file should report an error: %+v", reps[3])
+	}
+
+	var codes []string
+	if err := CommonCodes(context.Background(), cfg, func(c string) { codes = append(codes, c) }); err != nil {
+		t.Fatalf("CommonCodes: %v", err)
+	}
+	if len(codes) != 2 || codes[0] != "HAPPYHRS" || codes[1] != "FIFTYOFF" {
+		t.Fatalf("CommonCodes = %v", codes)
+	}
+
+	res, files := LocateCode(context.Background(), cfg, "FIFTYOFF")
+	if !res.Valid || res.Hits != 2 || len(files) != 4 || !files[0].Found || files[2].Found || files[3].Error == "" {
+		t.Fatalf("LocateCode = %+v %+v", res, files)
+	}
+}
@@ -0,0 +1,240 @@
+package promovalidator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Offline inspection of coupon files, used by cmd/couponctl. These read every
+// file in full and are not meant for the request path.
+
+// FileReport summarizes one coupon file.
+type FileReport struct {
+	Name     string `json:"name"`
+	Format   string `json:"format"` // detected encoding, e.g. "gzip" or "plain"
+	Size     int64  `json:"size"`   // on disk
+	Lines    int    `json:"lines"`
+	Tokens   int    `json:"tokens"`   // tokens within MinLen..MaxLen, with repeats
+	Distinct int    `json:"distinct"` // distinct such tokens (by 64-bit hash)
+	Error    string `json:"error,omitempty"`
+}
+
+// InspectFiles reports line and token counts for every file in cfg.
+func InspectFiles(cfg Config) []FileReport {
+	names := expandFiles(cfg)
+	out := make([]FileReport, 0, len(names))
+	for _, name := range names {
+		out = append(out, inspectFile(cfg, name))
+	}
+	return out
+}
+
+func inspectFile(cfg Config, name string) FileReport {
+	rep := FileReport{Name: name}
+	filename := filepath.Join(cfg.Dir, name)
+	if st, err := os.Stat(filename); err == nil {
+		rep.Size = st.Size()
+	}
+	format, err := sniffFormat(filename)
+	if err != nil {
+		rep.Error = err.Error()
+		return rep
+	}
+	rep.Format = format
+
+	r, err := openCouponFile(filename)
+	if err != nil {
+		rep.Error = err.Error()
+		return rep
+	}
+	defer r.Close()
+
+	lc := &lineCounter{r: r}
+	var hashes []uint64
+	err = scanTokens(lc, cfg.MinLen, cfg.MaxLen, func(w string) bool {
+		hashes = append(hashes, hashToken(w))
+		return true
+	})
+	if err != nil {
+		rep.Error = err.Error()
+	}
+	slices.Sort(hashes)
+	rep.Lines = lc.lines()
+	rep.Tokens = len(hashes)
+	rep.Distinct = len(slices.Compact(hashes))
+	return rep
+}
+
+// sniffFormat names the encoding openCouponFile would use for filename.
+func sniffFormat(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head, err := bufio.NewReader(f).Peek(maxMagicLen())
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	format, err := detectFormat(filename, head)
+	if err != nil {
+		return "", err
+	}
+	if format == nil {
+		return "plain", nil
+	}
+	return format.Name, nil
+}
+
+// lineCounter counts lines passing through it; a final line without a
+// trailing newline counts too.
+type lineCounter struct {
+	r        io.Reader
+	newlines int
+	last     byte
+}
+
+func (c *lineCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.newlines += bytes.Count(p[:n], []byte{'\n'})
+		c.last = p[n-1]
+	}
+	return n, err
+}
+
+func (c *lineCounter) lines() int {
+	if c.last != 0 && c.last != '\n' {
+		return c.newlines + 1
+	}
+	return c.newlines
+}
+
+// CommonCodes calls fn, in first-seen order, for every code found in at least
+// cfg.RequiredHits files, i.e. every code the validator would accept.
+// Unreadable files are skipped, as under PolicyTolerate. Files are matched
+// by exact token, as in the indexed validator. fn is called once every file
+// has been read.
+func CommonCodes(ctx context.Context, cfg Config, fn func(code string)) error {
+	if err := validateIndexConfig(cfg); err != nil {
+		return err
+	}
+	idx, err := buildIndex(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Keys occurring in enough files. Each file's keys are distinct, so a
+	// run of n equal keys in the merged, sorted list means n files.
+	var all []uint64
+	for i := range idx.files {
+		all = append(all, idx.files[i].keys...)
+	}
+	slices.Sort(all)
+	var common []uint64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j] == all[i] {
+			j++
+		}
+		if j-i >= cfg.RequiredHits {
+			common = append(common, all[i])
+		}
+		i = j
+	}
+	all = nil // release before the second pass
+
+	// Second pass to recover the codes behind the keys, in first-seen order.
+	// Files skipped here (unreadable by now) don't count towards a code.
+	type candidate struct {
+		files    int
+		lastFile int
+	}
+	seen := make(map[string]*candidate)
+	var order []string
+	for i := range idx.files {
+		if idx.files[i].err != nil {
+			continue
+		}
+		r, err := openCouponFile(filepath.Join(cfg.Dir, idx.files[i].name))
+		if err != nil {
+			continue
+		}
+		err = scanTokens(&ctxReader{ctx: ctx, r: r}, cfg.MinLen, cfg.MaxLen, func(w string) bool {
+			if _, ok := slices.BinarySearch(common, tokenKey(w)); !ok {
+				return true
+			}
+			c := seen[w]
+			if c == nil {
+				c = &candidate{lastFile: -1}
+				seen[w] = c
+				order = append(order, w)
+			}
+			if c.lastFile != i {
+				c.files, c.lastFile = c.files+1, i
+			}
+			return true
+		})
+		r.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		_ = err // a late read error only means fewer codes are recovered from this file
+	}
+	for _, w := range order {
+		if seen[w].files >= cfg.RequiredHits {
+			fn(w)
+		}
+	}
+	return nil
+}
+
+// FileMatch is whether one file contains a code.
+type FileMatch struct {
+	Name  string `json:"name"`
+	Found bool   `json:"found"`
+	Error string `json:"error,omitempty"`
+}
+
+// LocateCode scans every file for code (without stopping early, unlike the
+// validator) and returns the per-file outcome alongside the Result the
+// validator would give, schedules aside.
+func LocateCode(ctx context.Context, cfg Config, code string) (Result, []FileMatch) {
+	res, ok := checkFormat(cfg, code)
+	names := expandFiles(cfg)
+	res.Files = len(names)
+	matches := make([]FileMatch, 0, len(names))
+	for _, name := range names {
+		m := FileMatch{Name: name}
+		if ok {
+			found, err := foundInFile(ctx, filepath.Join(cfg.Dir, name), code, cfg.MinLen, cfg.MaxLen)
+			m.Found = found
+			if err != nil {
+				m.Error = err.Error()
+				res.UnreadableFiles = append(res.UnreadableFiles, name)
+			}
+			if found {
+				res.Hits++
+				res.MatchedFiles = append(res.MatchedFiles, name)
+			}
+		}
+		matches = append(matches, m)
+	}
+	if !ok {
+		return res, matches
+	}
+	if ctx.Err() != nil {
+		res.Reason = ReasonAborted
+		return res, matches
+	}
+	res.decide()
+	if cfg.FilePolicy.strict() && len(res.UnreadableFiles) > 0 {
+		res.failFiles(nil)
+	}
+	return res, matches
+}
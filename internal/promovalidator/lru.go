@@ -3,70 +3,136 @@ package promovalidator
 import (
 	"container/list"
 	"sync"
+	"time"
 )
 
-// LRU is a tiny, goroutine-safe LRU cache for string->bool.
-type LRU struct {
+// LRU is a tiny, goroutine-safe LRU cache for string->V.
+// Entries optionally expire after a fixed TTL.
+type LRU[V any] struct {
 	mu  sync.Mutex
 	max int
+	ttl time.Duration    // 0 = entries never expire
+	now func() time.Time // overridable in tests
 	ll  *list.List
 	m   map[string]*list.Element // key -> list element
+
+	hits, misses, evictions, expired uint64
+}
+
+type kv[V any] struct {
+	k   string
+	v   V
+	exp time.Time // zero when the cache has no TTL
+}
+
+// LRUStats is a point-in-time view of an LRU's size and counters.
+type LRUStats struct {
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"` // dropped to make room
+	Expired   uint64 `json:"expired"`   // dropped because their TTL passed
 }
 
-type kv struct {
-	k string
-	v bool
+func NewLRU[V any](max int) *LRU[V] {
+	return NewLRUWithTTL[V](max, 0)
 }
 
-func NewLRU(max int) *LRU {
+// NewLRUWithTTL is NewLRU where entries expire ttl after they were added (ttl <= 0 disables expiry).
+func NewLRUWithTTL[V any](max int, ttl time.Duration) *LRU[V] {
 	if max < 1 {
 		max = 1
 	}
-	return &LRU{
+	if ttl < 0 {
+		ttl = 0
+	}
+	return &LRU[V]{
 		max: max,
+		ttl: ttl,
+		now: time.Now,
 		ll:  list.New(),
 		m:   make(map[string]*list.Element, max),
 	}
 }
 
 // Get returns (value, ok). Moves the item to the front (most recently used) on hit.
-func (c *LRU) Get(k string) (bool, bool) {
+func (c *LRU[V]) Get(k string) (V, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	var zero V
 	if e, ok := c.m[k]; ok {
+		ent := e.Value.(kv[V])
+		if c.ttl > 0 && !c.now().Before(ent.exp) {
+			delete(c.m, k)
+			c.ll.Remove(e)
+			c.expired++
+			c.misses++
+			return zero, false
+		}
 		c.ll.MoveToFront(e)
-		return e.Value.(kv).v, true
+		c.hits++
+		return ent.v, true
 	}
-	return false, false
+	c.misses++
+	return zero, false
 }
 
 // Add inserts/updates (k,v). If over capacity, evicts the least-recently-used.
-func (c *LRU) Add(k string, v bool) {
+func (c *LRU[V]) Add(k string, v V) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	var exp time.Time
+	if c.ttl > 0 {
+		exp = c.now().Add(c.ttl)
+	}
+
 	if e, ok := c.m[k]; ok {
-		e.Value = kv{k, v}
+		e.Value = kv[V]{k, v, exp}
 		c.ll.MoveToFront(e)
 		return
 	}
 
-	e := c.ll.PushFront(kv{k, v})
+	e := c.ll.PushFront(kv[V]{k, v, exp})
 	c.m[k] = e
 
 	if c.ll.Len() > c.max {
 		last := c.ll.Back()
 		if last != nil {
-			ev := last.Value.(kv)
+			ev := last.Value.(kv[V])
 			delete(c.m, ev.k)
 			c.ll.Remove(last)
+			c.evictions++
 		}
 	}
 }
 
+// Purge removes all entries. Counters are kept.
+func (c *LRU[V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	clear(c.m)
+}
+
 // Len returns current number of entries.
-func (c *LRU) Len() int {
+func (c *LRU[V]) Len() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	return c.ll.Len()
 }
+
+// Stats returns the current size and counters.
+func (c *LRU[V]) Stats() LRUStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return LRUStats{
+		Size:      c.ll.Len(),
+		Capacity:  c.max,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Expired:   c.expired,
+	}
+}
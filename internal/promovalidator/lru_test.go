@@ -0,0 +1,81 @@
+package promovalidator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[bool](2)
+	c.Add("a", true)
+	c.Add("b", false)
+	if _, ok := c.Get("a"); !ok { // a is now most recent
+		t.Fatalf("expected a to be cached")
+	}
+	c.Add("c", true) // evicts b
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+	if v, ok := c.Get("c"); !ok || !v {
+		t.Fatalf("expected c=true, got %v/%v", v, ok)
+	}
+
+	st := c.Stats()
+	if st.Size != 2 || st.Capacity != 2 || st.Evictions != 1 || st.Hits != 2 || st.Misses != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestLRU_TTL(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	c := NewLRUWithTTL[bool](10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Add("a", true)
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("entry should still be live")
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("entry should have expired")
+	}
+	if st := c.Stats(); st.Expired != 1 || st.Size != 0 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestLRU_Purge(t *testing.T) {
+	c := NewLRU[bool](4)
+	c.Add("a", true)
+	c.Add("b", true)
+	c.Purge()
+	if c.Len() != 0 {
+		t.Fatalf("expected empty cache after purge, got %d", c.Len())
+	}
+	c.Add("c", true) // still usable
+	if _, ok := c.Get("c"); !ok {
+		t.Fatalf("expected c after purge")
+	}
+}
+
+func TestResultCache_NegativesCannotEvictPositives(t *testing.T) {
+	c := newResultCache(Config{PositiveCacheSize: 1, NegativeCacheSize: 2})
+	c.put(Result{Code: "HAPPYHRS", Valid: true})
+	for _, guess := range []string{"GUESS0001", "GUESS0002", "GUESS0003", "GUESS0004"} {
+		c.put(Result{Code: guess})
+	}
+
+	if res, ok := c.get("HAPPYHRS"); !ok || !res.Valid {
+		t.Fatalf("valid code should survive a flood of invalid codes")
+	}
+	if _, ok := c.get("GUESS0001"); ok {
+		t.Fatalf("oldest invalid code should have been evicted")
+	}
+
+	st := c.stats()
+	if st.Hits != 1 || st.Misses != 1 || st.Negative.Evictions != 2 || st.Negative.Size != 2 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
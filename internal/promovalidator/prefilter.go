@@ -0,0 +1,217 @@
+package promovalidator
+
+import (
+	"math"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// defaultPrefilterFPRate is used when Config.PrefilterFPRate is not set.
+const defaultPrefilterFPRate = 0.01
+
+// bloomFilter is a standard Bloom filter over the 64-bit token hashes the
+// index already uses. The k probe positions come from double hashing of that
+// one hash (Kirsch-Mitzenmacher), so a lookup costs one FNV pass over the code.
+type bloomFilter struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    uint32
+}
+
+// newBloomFilter sizes a filter for n keys at m bits (rounded up to a whole word).
+func newBloomFilter(n int, m uint64) *bloomFilter {
+	m = max(64, (m+63)/64*64)
+	k := uint32(1)
+	if n > 0 {
+		k = uint32(max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	}
+	return &bloomFilter{bits: make([]uint64, m/64), m: m, k: k}
+}
+
+// bloomBits is the optimal filter size for n keys at false-positive rate p.
+func bloomBits(n int, p float64) uint64 {
+	return uint64(math.Ceil(-float64(max(n, 1)) * math.Log(p) / (math.Ln2 * math.Ln2)))
+}
+
+func (b *bloomFilter) probes(h uint64) (h1, h2 uint64) {
+	h2 = h>>33 ^ h*0x9e3779b97f4a7c15 // decorrelate the second hash from the first
+	return h, h2 | 1
+}
+
+func (b *bloomFilter) add(h uint64) {
+	h1, h2 := b.probes(h)
+	for i := uint64(0); i < uint64(b.k); i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// mayContain is false only if h was never added.
+func (b *bloomFilter) mayContain(h uint64) bool {
+	h1, h2 := b.probes(h)
+	for i := uint64(0); i < uint64(b.k); i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fpRate estimates the false-positive rate with n keys added.
+func (b *bloomFilter) fpRate(n int) float64 {
+	return math.Pow(1-math.Exp(-float64(b.k)*float64(n)/float64(b.m)), float64(b.k))
+}
+
+// PrefilterStats describes the per-file Bloom filters of a streaming validator.
+type PrefilterStats struct {
+	Files       []FilePrefilterStats `json:"files"`
+	MemoryBytes int64                `json:"memoryBytes"`
+	Lookups     uint64               `json:"lookups"`   // file lookups consulted against a filter
+	Negatives   uint64               `json:"negatives"` // of which skipped the scan (definitely absent)
+}
+
+// FilePrefilterStats describes the filter built for one coupon file.
+type FilePrefilterStats struct {
+	Name   string  `json:"name"`
+	Codes  int     `json:"codes"`
+	Bytes  int64   `json:"bytes"`
+	Hashes uint32  `json:"hashes"`          // k
+	FPRate float64 `json:"fpRate"`          // estimated
+	Error  string  `json:"error,omitempty"` // no filter: the file is always scanned
+}
+
+// prefilterSet is an immutable snapshot of filters, keyed by file name.
+type prefilterSet struct {
+	byName map[string]*bloomFilter
+	stats  PrefilterStats
+}
+
+// prefilters builds and serves the Bloom filters of a streaming validator.
+type prefilters struct {
+	cfg Config
+	cur atomic.Pointer[prefilterSet]
+
+	lookups, negatives atomic.Uint64
+}
+
+func newPrefilters(cfg Config) *prefilters { return &prefilters{cfg: cfg} }
+
+// build reads every file in names and returns filters sized for
+// Config.PrefilterFPRate, shrunk to fit Config.PrefilterMaxBytes if needed
+// (raising the effective rate). Files that cannot be read completely get no
+// filter. Tokens are counted first so every filter is sized within the
+// budget before any is allocated; no file's hashes are held in memory.
+func (p *prefilters) build(names []string) *prefilterSet {
+	rate := p.cfg.PrefilterFPRate
+	if rate <= 0 || rate >= 1 {
+		rate = defaultPrefilterFPRate
+	}
+
+	counts := make([]int, len(names))
+	errs := make([]error, len(names))
+	sizes := make([]uint64, len(names)) // bits, whole words
+	var total uint64
+	for i, name := range names {
+		counts[i], errs[i] = countTokens(filepath.Join(p.cfg.Dir, name), p.cfg.MinLen, p.cfg.MaxLen)
+		if errs[i] == nil {
+			sizes[i] = max(64, (bloomBits(counts[i], rate)+63)/64*64)
+			total += sizes[i]
+		}
+	}
+	// Over budget: scale every filter down by the same factor.
+	if budget := uint64(p.cfg.PrefilterMaxBytes) * 8; budget > 0 && total > budget {
+		scale := float64(budget) / float64(total)
+		for i := range sizes {
+			if errs[i] == nil {
+				sizes[i] = max(64, uint64(float64(sizes[i])*scale)/64*64)
+			}
+		}
+	}
+
+	filters := make([]*bloomFilter, len(names))
+	for i, name := range names {
+		if errs[i] == nil {
+			filters[i], errs[i] = fillBloomFilter(filepath.Join(p.cfg.Dir, name), p.cfg.MinLen, p.cfg.MaxLen, counts[i], sizes[i])
+		}
+	}
+
+	set := &prefilterSet{byName: make(map[string]*bloomFilter, len(names))}
+	for i, name := range names {
+		fs := FilePrefilterStats{Name: name, Codes: counts[i]}
+		if f := filters[i]; f != nil {
+			set.byName[name] = f
+			fs.Bytes = int64(len(f.bits)) * 8
+			fs.Hashes = f.k
+			fs.FPRate = f.fpRate(counts[i])
+		} else if errs[i] != nil {
+			fs.Error = errs[i].Error()
+		}
+		set.stats.Files = append(set.stats.Files, fs)
+		set.stats.MemoryBytes += fs.Bytes
+	}
+	return set
+}
+
+// countTokens counts the tokens of filename (duplicates included).
+func countTokens(filename string, minLen, maxLen int) (int, error) {
+	r, err := openCouponFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	n := 0
+	err = scanTokens(r, minLen, maxLen, func(string) bool {
+		n++
+		return true
+	})
+	return n, err
+}
+
+// fillBloomFilter builds an m-bit filter for the n tokens of filename.
+func fillBloomFilter(filename string, minLen, maxLen, n int, m uint64) (*bloomFilter, error) {
+	r, err := openCouponFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	f := newBloomFilter(n, m)
+	if err := scanTokens(r, minLen, maxLen, func(w string) bool {
+		f.add(hashToken(w))
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// absent reports whether the filter for name proves h is not in the file.
+// Files without a filter are never reported absent.
+func (p *prefilters) absent(name string, h uint64) bool {
+	set := p.cur.Load()
+	if set == nil {
+		return false
+	}
+	f := set.byName[name]
+	if f == nil {
+		return false
+	}
+	p.lookups.Add(1)
+	if f.mayContain(h) {
+		return false
+	}
+	p.negatives.Add(1)
+	return true
+}
+
+func (p *prefilters) stats() *PrefilterStats {
+	set := p.cur.Load()
+	if set == nil {
+		return nil
+	}
+	st := set.stats
+	st.Files = append([]FilePrefilterStats(nil), set.stats.Files...)
+	st.Lookups = p.lookups.Load()
+	st.Negatives = p.negatives.Load()
+	return &st
+}
@@ -0,0 +1,94 @@
+package promovalidator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBloomFilter_NoFalseNegativesAndFPRate(t *testing.T) {
+	const n = 20000
+	f := newBloomFilter(n, bloomBits(n, 0.01))
+	for i := 0; i < n; i++ {
+		f.add(hashToken(fmt.Sprintf("CODE%06d", i)))
+	}
+	for i := 0; i < n; i++ {
+		if !f.mayContain(hashToken(fmt.Sprintf("CODE%06d", i))) {
+			t.Fatalf("false negative for CODE%06d", i)
+		}
+	}
+	fp := 0
+	for i := 0; i < n; i++ {
+		if f.mayContain(hashToken(fmt.Sprintf("MISS%06d", i))) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / n; rate > 0.02 {
+		t.Fatalf("false-positive rate %.4f, want about 0.01", rate)
+	}
+	if est := f.fpRate(n); est < 0.005 || est > 0.015 {
+		t.Fatalf("estimated rate %.4f, want about 0.01", est)
+	}
+}
+
+func TestStreamingPrefilter_SkipsDefiniteNegatives(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS", "FIFTYOFF"})
+	writeGzipFile(t, filepath.Join(dir, "b.gz"), []string{"HAPPYHRS"})
+
+	v := NewValidatorService(Config{Dir: dir, Files: []string{"a.gz", "b.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 2, Prefilter: true})
+	if err := v.LoadCouponFiles(); err != nil {
+		t.Fatalf("LoadCouponFiles: %v", err)
+	}
+
+	// Corrupt b.gz without reloading: only codes passing its filter touch it.
+	if err := os.WriteFile(filepath.Join(dir, "b.gz"), []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res := v.CheckPromoCode(context.Background(), "NOTACODE1")
+	if res.Valid || len(res.UnreadableFiles) != 0 {
+		t.Fatalf("NOTACODE1 = %+v, want rejected without reading the files", res)
+	}
+	res = v.CheckPromoCode(context.Background(), "HAPPYHRS")
+	if len(res.UnreadableFiles) != 1 {
+		t.Fatalf("HAPPYHRS = %+v, want b.gz scanned (and unreadable)", res)
+	}
+
+	st := v.(StatusReporter).Status().Prefilter
+	if st == nil || len(st.Files) != 2 || st.Negatives < 2 || st.Lookups < 4 {
+		t.Fatalf("prefilter stats = %+v", st)
+	}
+}
+
+func TestStreamingPrefilter_MemoryBudget(t *testing.T) {
+	dir := t.TempDir()
+	codes := make([]string, 5000)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("CODE%06d", i)
+	}
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), codes)
+	writeGzipFile(t, filepath.Join(dir, "b.gz"), codes)
+
+	const budget = 4096
+	v := NewValidatorService(Config{
+		Dir: dir, Files: []string{"a.gz", "b.gz", "missing.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 2,
+		Prefilter: true, PrefilterFPRate: 0.001, PrefilterMaxBytes: budget,
+	})
+	_ = v.LoadCouponFiles()
+
+	st := v.(StatusReporter).Status().Prefilter
+	if st.MemoryBytes > budget {
+		t.Fatalf("filters use %d bytes, budget %d", st.MemoryBytes, budget)
+	}
+	if st.Files[0].FPRate <= 0.001 {
+		t.Fatalf("FPRate %.4f should exceed the target once squeezed into the budget", st.Files[0].FPRate)
+	}
+	if st.Files[2].Error == "" || st.Files[2].Bytes != 0 {
+		t.Fatalf("missing file should have no filter: %+v", st.Files[2])
+	}
+	if !v.ValidatePromoCode("CODE001234") {
+		t.Fatal("a shrunken filter must never hide real codes")
+	}
+}
@@ -0,0 +1,216 @@
+package promovalidator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultReloadInterval is used by Watch when Config.ReloadInterval is not set.
+const defaultReloadInterval = 30 * time.Second
+
+// FileStatus describes one coupon file as seen by the last (re)load.
+type FileStatus struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	SHA256  string    `json:"sha256,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// ReloadStatus reports when the validator last picked up its coupon files.
+type ReloadStatus struct {
+	LastCheck  time.Time    `json:"lastCheck"`  // last time the files were polled
+	LastReload time.Time    `json:"lastReload"` // last time state was (re)built successfully
+	Reloads    int          `json:"reloads"`    // reloads since start (the initial load is not counted)
+	LastError  string       `json:"lastError,omitempty"`
+	Files      []FileStatus `json:"files"`
+}
+
+// Reloader is implemented by validators that can rebuild their state when the
+// coupon files change on disk.
+type Reloader interface {
+	// Reload rebuilds state from disk, swaps it in and invalidates cached results.
+	Reload() error
+	// Watch polls the files every Config.ReloadInterval and calls Reload when
+	// any file's size or mtime changed. It blocks until ctx is done.
+	Watch(ctx context.Context)
+	// ReloadStatus reports the outcome of the most recent (re)load.
+	ReloadStatus() ReloadStatus
+}
+
+// fileStamp is what polling compares; a missing file has exists=false.
+type fileStamp struct {
+	exists bool
+	size   int64
+	mod    time.Time
+}
+
+// reloader holds the polling/reload bookkeeping shared by the validators.
+// apply rebuilds the validator's state and is only ever called with mu held.
+type reloader struct {
+	cfg   Config
+	apply func() error
+
+	mu     sync.Mutex           // serializes reloads
+	stamps map[string]fileStamp // keyed by file name; patterns are re-expanded on every poll
+
+	statusMu sync.RWMutex
+	status   ReloadStatus
+}
+
+func newReloader(cfg Config, apply func() error) *reloader {
+	return &reloader{cfg: cfg, apply: apply}
+}
+
+// prime records the files backing state that was just built (no apply).
+func (r *reloader) prime() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names, stamps := r.statFiles()
+	files := r.describeFiles(names, stamps)
+
+	r.statusMu.Lock()
+	r.stamps = stamps
+	r.status.LastCheck = time.Now()
+	r.status.LastReload = r.status.LastCheck
+	r.status.Files = files
+	r.statusMu.Unlock()
+}
+
+// reload unconditionally rebuilds state.
+func (r *reloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked(r.statFiles())
+}
+
+// check polls the files and reloads if anything changed since the last load.
+func (r *reloader) check() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names, stamps := r.statFiles()
+
+	r.statusMu.Lock()
+	r.status.LastCheck = time.Now()
+	r.statusMu.Unlock()
+
+	if sameStamps(stamps, r.stamps) {
+		return false, nil
+	}
+	return true, r.reloadLocked(names, stamps)
+}
+
+func (r *reloader) reloadLocked(names []string, stamps map[string]fileStamp) error {
+	// Stamps are recorded even if apply fails, so a bad file is not retried on
+	// every poll; the next change to any file triggers a new attempt.
+	r.stamps = stamps
+	files := r.describeFiles(names, stamps)
+	err := r.apply()
+
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	r.status.Reloads++
+	if err != nil {
+		r.status.LastError = err.Error()
+		return err
+	}
+	r.status.LastError = ""
+	r.status.LastReload = time.Now()
+	r.status.Files = files
+	return nil
+}
+
+func (r *reloader) watch(ctx context.Context) {
+	interval := r.cfg.ReloadInterval
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			_, _ = r.check() // errors are surfaced via ReloadStatus
+		}
+	}
+}
+
+func (r *reloader) snapshot() ReloadStatus {
+	r.statusMu.RLock()
+	defer r.statusMu.RUnlock()
+	st := r.status
+	st.Files = append([]FileStatus(nil), r.status.Files...)
+	return st
+}
+
+// statFiles expands the file set and stats every file (and the schedule);
+// a file appearing or disappearing under a pattern changes the map's keys.
+func (r *reloader) statFiles() ([]string, map[string]fileStamp) {
+	names := expandFiles(r.cfg)
+	if r.cfg.ScheduleFile != "" {
+		names = append(names, r.cfg.ScheduleFile)
+	}
+	out := make(map[string]fileStamp, len(names))
+	for _, name := range names {
+		fi, err := os.Stat(filepath.Join(r.cfg.Dir, name))
+		if err != nil {
+			out[name] = fileStamp{}
+			continue
+		}
+		out[name] = fileStamp{exists: true, size: fi.Size(), mod: fi.ModTime()}
+	}
+	return names, out
+}
+
+// describeFiles computes checksums so ops can confirm which drop is live.
+func (r *reloader) describeFiles(names []string, stamps map[string]fileStamp) []FileStatus {
+	out := make([]FileStatus, 0, len(names))
+	for _, name := range names {
+		st := stamps[name]
+		fs := FileStatus{Name: name, Size: st.size, ModTime: st.mod}
+		sum, err := fileSHA256(filepath.Join(r.cfg.Dir, name))
+		if err != nil {
+			fs.Error = err.Error()
+		} else {
+			fs.SHA256 = sum
+		}
+		out = append(out, fs)
+	}
+	return out
+}
+
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, av := range a {
+		bv, ok := b[k]
+		if !ok || av.exists != bv.exists || av.size != bv.size || !av.mod.Equal(bv.mod) {
+			return false
+		}
+	}
+	return true
+}
+
+func fileSHA256(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
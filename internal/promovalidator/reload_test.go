@@ -0,0 +1,149 @@
+package promovalidator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// touch moves a file's mtime forward so polling notices a rewrite even on
+// filesystems with coarse timestamps.
+func touch(t *testing.T, path string, d time.Duration) {
+	t.Helper()
+	ts := time.Now().Add(d)
+	if err := os.Chtimes(path, ts, ts); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+}
+
+func TestReload_PicksUpChangedFiles(t *testing.T) {
+	for _, mode := range []Mode{ModeStreaming, ModeIndexed} {
+		t.Run(string(mode), func(t *testing.T) {
+			dir := t.TempDir()
+			p1 := filepath.Join(dir, "couponbase1.gz")
+			writeGzipFile(t, p1, []string{"HAPPYHRS"})
+
+			v, err := New(Config{
+				Dir: dir, Files: []string{"couponbase1.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1, Mode: mode,
+			})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if err := v.LoadCouponFiles(); err != nil {
+				t.Fatalf("LoadCouponFiles: %v", err)
+			}
+			if v.ValidatePromoCode("NEWCODE99") {
+				t.Fatalf("NEWCODE99 should not be valid before the drop")
+			}
+			before := v.(Reloader).ReloadStatus()
+
+			// replace the file; a cached/indexed false must not linger
+			writeGzipFile(t, p1, []string{"HAPPYHRS", "NEWCODE99"})
+			touch(t, p1, time.Minute)
+
+			if err := v.(Reloader).Reload(); err != nil {
+				t.Fatalf("Reload: %v", err)
+			}
+			if !v.ValidatePromoCode("NEWCODE99") {
+				t.Fatalf("NEWCODE99 should be valid after reload")
+			}
+
+			after := v.(Reloader).ReloadStatus()
+			if after.Reloads != before.Reloads+1 {
+				t.Fatalf("Reloads = %d, want %d", after.Reloads, before.Reloads+1)
+			}
+			if len(after.Files) != 1 || after.Files[0].SHA256 == "" || after.Files[0].SHA256 == before.Files[0].SHA256 {
+				t.Fatalf("expected a new checksum, before=%+v after=%+v", before.Files, after.Files)
+			}
+		})
+	}
+}
+
+func TestReloader_CheckOnlyReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	p1 := filepath.Join(dir, "a.gz")
+	writeGzipFile(t, p1, []string{"HAPPYHRS"})
+
+	applied := 0
+	r := newReloader(Config{Dir: dir, Files: []string{"a.gz", "b.gz"}}, func() error {
+		applied++
+		return nil
+	})
+	r.prime()
+
+	if changed, _ := r.check(); changed || applied != 0 {
+		t.Fatalf("unchanged files should not reload (changed=%v applied=%d)", changed, applied)
+	}
+
+	// a previously missing file appearing counts as a change
+	writeGzipFile(t, filepath.Join(dir, "b.gz"), []string{"WELCOME10"})
+	if changed, err := r.check(); !changed || err != nil || applied != 1 {
+		t.Fatalf("new file should reload (changed=%v err=%v applied=%d)", changed, err, applied)
+	}
+
+	touch(t, p1, time.Minute)
+	if changed, _ := r.check(); !changed || applied != 2 {
+		t.Fatalf("mtime change should reload (changed=%v applied=%d)", changed, applied)
+	}
+
+	st := r.snapshot()
+	if st.Reloads != 2 || st.LastError != "" || st.LastCheck.IsZero() {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+}
+
+func TestIndexedValidator_FailedReloadKeepsServing(t *testing.T) {
+	dir := t.TempDir()
+	p1 := filepath.Join(dir, "a.gz")
+	writeGzipFile(t, p1, []string{"HAPPYHRS"})
+
+	v := NewIndexedValidatorService(Config{
+		Dir: dir, Files: []string{"a.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1, MaxIndexBytes: 8,
+	})
+	if err := v.LoadCouponFiles(); err != nil {
+		t.Fatalf("LoadCouponFiles: %v", err)
+	}
+
+	// the new drop no longer fits the budget; the old index must stay live
+	writeGzipFile(t, p1, []string{"HAPPYHRS", "WELCOME10"})
+	if err := v.(Reloader).Reload(); err == nil {
+		t.Fatalf("expected reload error")
+	}
+	if !v.ValidatePromoCode("HAPPYHRS") {
+		t.Fatalf("previous index should keep serving after a failed reload")
+	}
+	if st := v.(Reloader).ReloadStatus(); st.LastError == "" {
+		t.Fatalf("expected LastError to be reported")
+	}
+}
+
+func TestWatch_StopsOnCancel(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS"})
+
+	v := NewValidatorService(Config{
+		Dir: dir, Files: []string{"a.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1,
+		ReloadInterval: time.Millisecond,
+	})
+	_ = v.LoadCouponFiles()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		v.(Reloader).Watch(ctx)
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Watch did not return after cancel")
+	}
+	if st := v.(Reloader).ReloadStatus(); st.LastCheck.IsZero() {
+		t.Fatalf("expected at least one poll")
+	}
+}
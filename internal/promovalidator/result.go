@@ -0,0 +1,123 @@
+package promovalidator
+
+import "fmt"
+
+// Reason identifies the rule that decided a promo code check.
+type Reason string
+
+const (
+	ReasonValid           Reason = "valid"
+	ReasonLength          Reason = "invalid_length"           // outside MinLen..MaxLen
+	ReasonCharset         Reason = "not_alphanumeric"         // contains characters other than A-Z, a-z, 0-9
+	ReasonNotEnoughHits   Reason = "insufficient_matches"     // found in fewer than RequiredHits files
+	ReasonAborted         Reason = "aborted"                  // the caller's context ended first
+	ReasonMisconfigured   Reason = "misconfigured"            // the validator could not be loaded
+	ReasonFileError       Reason = "coupon_files_unavailable" // a strict FilePolicy found unreadable files
+	ReasonOutsideSchedule Reason = "outside_schedule"         // valid code used outside its promotion window
+)
+
+// Result is the detailed outcome of a promo code check.
+type Result struct {
+	Code   string `json:"code"`
+	Valid  bool   `json:"valid"`
+	Reason Reason `json:"reason"`
+
+	MinLen int `json:"minLength"`
+	MaxLen int `json:"maxLength"`
+
+	// Hits counts files known to contain the code when the outcome was decided;
+	// scans still running at that point are cancelled and not counted.
+	Hits     int `json:"hits"`
+	Required int `json:"required"`
+	Files    int `json:"files"` // number of coupon files (patterns expanded)
+
+	MatchedFiles    []string `json:"matchedFiles,omitempty"`
+	UnreadableFiles []string `json:"unreadableFiles,omitempty"` // missing, corrupt or failed mid-scan
+
+	Cached bool `json:"cached,omitempty"` // served from the result cache
+
+	Window string `json:"window,omitempty"` // ReasonOutsideSchedule: when the code can be used
+}
+
+// PublicResult is the part of a Result that is safe to show API clients:
+// neither coupon file names nor how close a guess came.
+type PublicResult struct {
+	Code   string `json:"code"`
+	Valid  bool   `json:"valid"`
+	Reason Reason `json:"reason"`
+}
+
+// Public strips r down to what API clients may see. The full Result is for
+// logs and admin views.
+func (r Result) Public() PublicResult {
+	return PublicResult{Code: r.Code, Valid: r.Valid, Reason: r.Reason}
+}
+
+// Message is a short human-readable explanation suitable for API errors. It
+// reveals nothing about the coupon files.
+func (r Result) Message() string {
+	switch r.Reason {
+	case ReasonValid:
+		return "promo code is valid"
+	case ReasonLength:
+		return fmt.Sprintf("promo code must be %d-%d characters long", r.MinLen, r.MaxLen)
+	case ReasonCharset:
+		return "promo code must contain only letters and digits"
+	case ReasonNotEnoughHits:
+		return "promo code is not valid"
+	case ReasonAborted:
+		return "promo code check was aborted"
+	case ReasonMisconfigured:
+		return "promo code validation is unavailable"
+	case ReasonFileError:
+		return "promo codes cannot be checked right now: coupon files unavailable"
+	case ReasonOutsideSchedule:
+		return "promo code is not valid at this time (valid " + r.Window + ")"
+	default:
+		return string(r.Reason)
+	}
+}
+
+// newResult starts a Result for code with the config's bounds filled in.
+// Callers set Files once the file set is known.
+func newResult(cfg Config, code string) Result {
+	return Result{
+		Code:     code,
+		MinLen:   cfg.MinLen,
+		MaxLen:   cfg.MaxLen,
+		Required: cfg.RequiredHits,
+	}
+}
+
+// checkFormat applies the length and charset rules; ok is false when res is final.
+func checkFormat(cfg Config, code string) (res Result, ok bool) {
+	res = newResult(cfg, code)
+	if l := len(code); l < cfg.MinLen || l > cfg.MaxLen {
+		res.Reason = ReasonLength
+		return res, false
+	}
+	if !isAlnum(code) {
+		res.Reason = ReasonCharset
+		return res, false
+	}
+	return res, true
+}
+
+// failFiles rejects r because of unreadable files under a strict FilePolicy.
+func (r *Result) failFiles(files []string) {
+	r.Valid = false
+	r.Reason = ReasonFileError
+	if len(r.UnreadableFiles) == 0 {
+		r.UnreadableFiles = files
+	}
+}
+
+// decide sets Valid/Reason from the hit count.
+func (r *Result) decide() {
+	r.Valid = r.Hits >= r.Required
+	if r.Valid {
+		r.Reason = ReasonValid
+	} else {
+		r.Reason = ReasonNotEnoughHits
+	}
+}
@@ -0,0 +1,130 @@
+package promovalidator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheckPromoCode_Reasons(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "couponbase1.gz"), []string{"HAPPYHRS", "ONLYONE1"})
+	writeGzipFile(t, filepath.Join(dir, "couponbase2.gz"), []string{"HAPPYHRS"})
+	// couponbase3.gz is corrupt (not gzip)
+	if err := os.WriteFile(filepath.Join(dir, "couponbase3.gz"), []byte("not gzip"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cfg := Config{
+		Dir:          dir,
+		Files:        []string{"couponbase1.gz", "couponbase2.gz", "couponbase3.gz"},
+		MinLen:       8,
+		MaxLen:       10,
+		RequiredHits: 2,
+	}
+
+	for _, mode := range []Mode{ModeStreaming, ModeIndexed} {
+		t.Run(string(mode), func(t *testing.T) {
+			c := cfg
+			c.Mode = mode
+			v, err := New(c)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			_ = v.LoadCouponFiles()
+			ctx := context.Background()
+
+			tests := []struct {
+				code       string
+				wantValid  bool
+				wantReason Reason
+				wantHits   int
+			}{
+				{code: "HAPPYHRS", wantValid: true, wantReason: ReasonValid, wantHits: 2},
+				{code: "ONLYONE1", wantReason: ReasonNotEnoughHits, wantHits: 1},
+				{code: "SHORT", wantReason: ReasonLength},
+				{code: "HAPPY-HRS", wantReason: ReasonCharset},
+			}
+			for _, tt := range tests {
+				res := v.CheckPromoCode(ctx, tt.code)
+				if res.Valid != tt.wantValid || res.Reason != tt.wantReason {
+					t.Errorf("CheckPromoCode(%q) = %v/%s, want %v/%s", tt.code, res.Valid, res.Reason, tt.wantValid, tt.wantReason)
+				}
+				// streaming stops scanning once a negative outcome is certain,
+				// so only the index reports exact counts for negatives
+				if (tt.wantValid || mode == ModeIndexed) && res.Hits != tt.wantHits {
+					t.Errorf("CheckPromoCode(%q).Hits = %d, want %d", tt.code, res.Hits, tt.wantHits)
+				}
+				if got := v.ValidatePromoCode(tt.code); got != res.Valid {
+					t.Errorf("ValidatePromoCode(%q) = %v, want %v", tt.code, got, res.Valid)
+				}
+			}
+
+			if res := v.CheckPromoCode(ctx, "HAPPYHRS"); !slices.Equal(res.MatchedFiles, []string{"couponbase1.gz", "couponbase2.gz"}) {
+				t.Errorf("MatchedFiles = %v", res.MatchedFiles)
+			}
+			if mode != ModeIndexed {
+				return
+			}
+
+			// the index consults every file, so the corrupt one is always reported
+			res := v.CheckPromoCode(ctx, "ONLYONE1")
+			if !slices.Equal(res.MatchedFiles, []string{"couponbase1.gz"}) {
+				t.Errorf("MatchedFiles = %v", res.MatchedFiles)
+			}
+			if !slices.Equal(res.UnreadableFiles, []string{"couponbase3.gz"}) {
+				t.Errorf("UnreadableFiles = %v", res.UnreadableFiles)
+			}
+			if msg := res.Message(); strings.Contains(msg, "1 of 3") || strings.Contains(msg, "couponbase") {
+				t.Errorf("Message() = %q, must not reveal coupon files", msg)
+			}
+			if pub := res.Public(); pub != (PublicResult{Code: "ONLYONE1", Reason: ReasonNotEnoughHits}) {
+				t.Errorf("Public() = %+v", pub)
+			}
+		})
+	}
+}
+
+func TestCheckPromoCode_CachedAndAborted(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS"})
+
+	v := NewValidatorService(Config{Dir: dir, Files: []string{"a.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if res := v.CheckPromoCode(ctx, "HAPPYHRS"); res.Valid || res.Reason != ReasonAborted {
+		t.Fatalf("expected aborted result, got %+v", res)
+	}
+
+	first := v.CheckPromoCode(context.Background(), "HAPPYHRS")
+	second := v.CheckPromoCode(context.Background(), "HAPPYHRS")
+	if first.Cached || !second.Cached || !second.Valid {
+		t.Fatalf("expected second lookup from cache, got first=%+v second=%+v", first, second)
+	}
+}
+
+func TestCheckPromoCode_ReportsUnreadableFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "corrupt.gz"), []byte("not gzip"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	v := NewValidatorService(Config{
+		Dir: dir, Files: []string{"corrupt.gz", "missing.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1,
+	})
+	res := v.CheckPromoCode(context.Background(), "HAPPYHRS")
+	if res.Valid || !slices.Equal(res.UnreadableFiles, []string{"corrupt.gz", "missing.gz"}) {
+		t.Fatalf("expected both files reported unreadable, got %+v", res)
+	}
+}
+
+func TestResult_Message(t *testing.T) {
+	res := Result{Reason: ReasonLength, MinLen: 8, MaxLen: 10}
+	if got := res.Message(); got != "promo code must be 8-10 characters long" {
+		t.Fatalf("Message() = %q", got)
+	}
+}
@@ -0,0 +1,318 @@
+package promovalidator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schedule constrains when promo codes are accepted. Codes matched by no
+// Promotion are accepted at any time.
+type Schedule struct {
+	Location   *time.Location
+	Promotions []Promotion
+}
+
+// Promotion is the validity window of the codes it selects: Code exactly, or
+// every code starting with Prefix. All set constraints must hold.
+type Promotion struct {
+	Code   string
+	Prefix string
+
+	Start time.Time      // inclusive; zero = no start
+	End   time.Time      // exclusive; zero = no end
+	Days  []time.Weekday // empty = every day
+	Hours []TimeRange    // empty = all day
+}
+
+// TimeRange is a daily time window in minutes after midnight, [From, To).
+// A range with To <= From wraps past midnight (e.g. 22:00-02:00).
+type TimeRange struct {
+	From, To int
+}
+
+func (tr TimeRange) contains(minute int) bool {
+	if tr.From < tr.To {
+		return minute >= tr.From && minute < tr.To
+	}
+	return minute >= tr.From || minute < tr.To
+}
+
+func (tr TimeRange) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", tr.From/60, tr.From%60, tr.To/60, tr.To%60)
+}
+
+func (p *Promotion) matches(code string) bool {
+	if p.Code != "" {
+		return code == p.Code
+	}
+	return strings.HasPrefix(code, p.Prefix)
+}
+
+// activeAt reports whether t (already in the schedule's location) is inside the window.
+func (p *Promotion) activeAt(t time.Time) bool {
+	if !p.Start.IsZero() && t.Before(p.Start) {
+		return false
+	}
+	if !p.End.IsZero() && !t.Before(p.End) {
+		return false
+	}
+	if len(p.Hours) == 0 {
+		return p.onDay(t.Weekday())
+	}
+	minute := t.Hour()*60 + t.Minute()
+	for _, h := range p.Hours {
+		if !h.contains(minute) {
+			continue
+		}
+		// past midnight, a wrapping range belongs to the day it opened on
+		day := t.Weekday()
+		if h.From >= h.To && minute < h.To {
+			day = (day + 6) % 7
+		}
+		if p.onDay(day) {
+			return true
+		}
+	}
+	return false
+}
+
+// onDay reports whether Days allows d.
+func (p *Promotion) onDay(d time.Weekday) bool {
+	if len(p.Days) == 0 {
+		return true
+	}
+	for _, day := range p.Days {
+		if day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Window describes the promotion's constraints for API errors, e.g.
+// "Mon,Tue 16:00-18:00 (Europe/London)".
+func (p *Promotion) window(loc *time.Location) string {
+	var parts []string
+	if !p.Start.IsZero() {
+		parts = append(parts, "from "+p.Start.Format(time.RFC3339))
+	}
+	if !p.End.IsZero() {
+		parts = append(parts, "until "+p.End.Format(time.RFC3339))
+	}
+	if len(p.Days) > 0 {
+		days := make([]string, len(p.Days))
+		for i, d := range p.Days {
+			days[i] = d.String()[:3]
+		}
+		parts = append(parts, strings.Join(days, ","))
+	}
+	for _, h := range p.Hours {
+		parts = append(parts, h.String())
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "always")
+	}
+	return strings.Join(parts, " ") + " (" + loc.String() + ")"
+}
+
+// Check reports whether code may be used at t. The first Promotion selecting
+// code decides; window describes it when the code is rejected.
+func (s *Schedule) Check(code string, t time.Time) (ok bool, window string) {
+	t = t.In(s.Location)
+	for i := range s.Promotions {
+		p := &s.Promotions[i]
+		if !p.matches(code) {
+			continue
+		}
+		if p.activeAt(t) {
+			return true, ""
+		}
+		return false, p.window(s.Location)
+	}
+	return true, ""
+}
+
+// MarshalJSON reports each promotion's window, for the status endpoint.
+func (s *Schedule) MarshalJSON() ([]byte, error) {
+	type promotion struct {
+		Code   string `json:"code,omitempty"`
+		Prefix string `json:"prefix,omitempty"`
+		Window string `json:"window"`
+	}
+	out := struct {
+		Timezone   string      `json:"timezone"`
+		Promotions []promotion `json:"promotions"`
+	}{Timezone: s.Location.String(), Promotions: make([]promotion, 0, len(s.Promotions))}
+	for i := range s.Promotions {
+		p := &s.Promotions[i]
+		out.Promotions = append(out.Promotions, promotion{Code: p.Code, Prefix: p.Prefix, Window: p.window(s.Location)})
+	}
+	return json.Marshal(out)
+}
+
+// scheduleFile is the on-disk (JSON or YAML) form of a Schedule.
+type scheduleFile struct {
+	Timezone   string `json:"timezone" yaml:"timezone"` // IANA name; default UTC
+	Promotions []struct {
+		Code   string   `json:"code" yaml:"code"`
+		Prefix string   `json:"prefix" yaml:"prefix"`
+		Start  string   `json:"start" yaml:"start"` // RFC 3339 or YYYY-MM-DD (midnight in Timezone)
+		End    string   `json:"end" yaml:"end"`
+		Days   []string `json:"days" yaml:"days"` // "mon".."sun"
+		Hours  []struct {
+			From string `json:"from" yaml:"from"` // HH:MM
+			To   string `json:"to" yaml:"to"`
+		} `json:"hours" yaml:"hours"`
+	} `json:"promotions" yaml:"promotions"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// LoadSchedule reads a schedule definition such as:
+//
+//	{"timezone": "Europe/London", "promotions": [
+//	  {"code": "HAPPYHRS", "days": ["mon", "fri"], "hours": [{"from": "16:00", "to": "18:00"}]},
+//	  {"prefix": "XMAS", "start": "2025-12-01", "end": "2025-12-27"}]}
+//
+// Files named *.yaml or *.yml hold the same fields in YAML; others are JSON.
+func LoadSchedule(filename string) (*Schedule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	unmarshal := json.Unmarshal
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	}
+	var f scheduleFile
+	if err := unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("schedule %s: %w", filepath.Base(filename), err)
+	}
+	s, err := f.parse()
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s: %w", filepath.Base(filename), err)
+	}
+	return s, nil
+}
+
+func (f *scheduleFile) parse() (*Schedule, error) {
+	loc := time.UTC
+	if f.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(f.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	s := &Schedule{Location: loc, Promotions: make([]Promotion, 0, len(f.Promotions))}
+	for i, fp := range f.Promotions {
+		p := Promotion{Code: fp.Code, Prefix: fp.Prefix}
+		if (p.Code == "") == (p.Prefix == "") {
+			return nil, fmt.Errorf("promotion %d: exactly one of code or prefix is required", i+1)
+		}
+		var err error
+		if p.Start, err = parseScheduleTime(fp.Start, loc); err != nil {
+			return nil, fmt.Errorf("promotion %d: start: %w", i+1, err)
+		}
+		if p.End, err = parseScheduleTime(fp.End, loc); err != nil {
+			return nil, fmt.Errorf("promotion %d: end: %w", i+1, err)
+		}
+		if !p.Start.IsZero() && !p.End.IsZero() && !p.End.After(p.Start) {
+			return nil, fmt.Errorf("promotion %d: end must be after start", i+1)
+		}
+		for _, d := range fp.Days {
+			wd, ok := weekdays[strings.ToLower(d)]
+			if !ok {
+				return nil, fmt.Errorf("promotion %d: unknown day %q", i+1, d)
+			}
+			p.Days = append(p.Days, wd)
+		}
+		for _, h := range fp.Hours {
+			from, err1 := parseClock(h.From)
+			to, err2 := parseClock(h.To)
+			if err := errors.Join(err1, err2); err != nil {
+				return nil, fmt.Errorf("promotion %d: hours: %w", i+1, err)
+			}
+			p.Hours = append(p.Hours, TimeRange{From: from, To: to})
+		}
+		s.Promotions = append(s.Promotions, p)
+	}
+	return s, nil
+}
+
+func parseScheduleTime(v string, loc *time.Location) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, loc)
+}
+
+// parseClock parses "HH:MM" (00:00-24:00) into minutes after midnight.
+func parseClock(v string) (int, error) {
+	if v == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// schedules holds the current Schedule for a validator. A missing schedule
+// file means no constraints, so one can be added later and hot reloaded.
+type schedules struct {
+	cfg Config
+	cur atomic.Pointer[Schedule]
+}
+
+func newSchedules(cfg Config) *schedules { return &schedules{cfg: cfg} }
+
+// load (re)reads Config.ScheduleFile; on error the previous schedule stays.
+func (s *schedules) load() error {
+	if s.cfg.ScheduleFile == "" {
+		return nil
+	}
+	sched, err := LoadSchedule(filepath.Join(s.cfg.Dir, s.cfg.ScheduleFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		s.cur.Store(nil)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("validator: %w", err)
+	}
+	s.cur.Store(sched)
+	return nil
+}
+
+// apply rejects a valid res whose code is outside its promotion window.
+func (s *schedules) apply(res *Result) {
+	sched := s.cur.Load()
+	if sched == nil || !res.Valid {
+		return
+	}
+	now := time.Now
+	if s.cfg.Clock != nil {
+		now = s.cfg.Clock
+	}
+	if ok, window := sched.Check(res.Code, now()); !ok {
+		res.Valid = false
+		res.Reason = ReasonOutsideSchedule
+		res.Window = window
+	}
+}
@@ -0,0 +1,198 @@
+package promovalidator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testSchedule = `{
+  "timezone": "Europe/London",
+  "promotions": [
+    {"code": "HAPPYHRS", "days": ["mon", "tue", "wed", "thu", "fri"], "hours": [{"from": "16:00", "to": "18:00"}]},
+    {"code": "LATENITE", "hours": [{"from": "22:00", "to": "02:00"}]},
+    {"code": "FRINIGHT", "days": ["fri"], "hours": [{"from": "22:00", "to": "02:00"}]},
+    {"prefix": "XMAS", "start": "2025-12-01", "end": "2025-12-27"}
+  ]
+}`
+
+func TestSchedule_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	if err := os.WriteFile(path, []byte(testSchedule), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSchedule(path)
+	if err != nil {
+		t.Fatalf("LoadSchedule: %v", err)
+	}
+	london, _ := time.LoadLocation("Europe/London")
+	at := func(v string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", v, london)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		code string
+		at   time.Time
+		want bool
+	}{
+		{"HAPPYHRS", at("2025-06-02 16:30"), true},       // Monday, in hours
+		{"HAPPYHRS", at("2025-06-02 18:00"), false},      // end is exclusive
+		{"HAPPYHRS", at("2025-06-07 17:00"), false},      // Saturday
+		{"HAPPYHRS", at("2025-06-02 16:30").UTC(), true}, // same instant given in UTC
+		{"LATENITE", at("2025-06-02 23:15"), true},       // wraps midnight
+		{"LATENITE", at("2025-06-03 01:59"), true},
+		{"LATENITE", at("2025-06-03 02:00"), false},
+		{"FRINIGHT", at("2025-06-06 23:00"), true},  // Friday
+		{"FRINIGHT", at("2025-06-07 01:00"), true},  // Saturday, but the window opened on Friday
+		{"FRINIGHT", at("2025-06-07 23:00"), false}, // Saturday's own window
+		{"FRINIGHT", at("2025-06-06 01:00"), false}, // Friday, in Thursday's window
+		{"XMAS2025", at("2025-12-26 23:59"), true},
+		{"XMAS2025", at("2025-12-27 00:00"), false},
+		{"XMAS2025", at("2025-11-30 12:00"), false},
+		{"WELCOME10", at("2025-11-30 12:00"), true}, // unscheduled codes are always allowed
+	}
+	for _, tt := range tests {
+		ok, window := s.Check(tt.code, tt.at)
+		if ok != tt.want {
+			t.Errorf("Check(%s, %s) = %v, want %v", tt.code, tt.at, ok, tt.want)
+		}
+		if !ok && window == "" {
+			t.Errorf("Check(%s, %s): expected a window description", tt.code, tt.at)
+		}
+	}
+}
+
+func TestLoadSchedule_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.yaml")
+	data := `timezone: Europe/London
+promotions:
+  - code: HAPPYHRS
+    days: [mon, fri]
+    hours:
+      - {from: "16:00", to: "18:00"}
+  - prefix: XMAS
+    start: "2025-12-01"
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSchedule(path)
+	if err != nil {
+		t.Fatalf("LoadSchedule: %v", err)
+	}
+	if s.Location.String() != "Europe/London" || len(s.Promotions) != 2 ||
+		len(s.Promotions[0].Days) != 2 || s.Promotions[0].Hours[0] != (TimeRange{From: 16 * 60, To: 18 * 60}) ||
+		s.Promotions[1].Prefix != "XMAS" || s.Promotions[1].Start.IsZero() {
+		t.Fatalf("schedule = %+v", s)
+	}
+
+	if err := os.WriteFile(path, []byte("promotions: [{code: A, days: [funday]}]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSchedule(path); err == nil {
+		t.Fatal("expected an error for an unknown day")
+	}
+}
+
+func TestLoadSchedule_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"bad json":       `{`,
+		"bad timezone":   `{"timezone": "Mars/Olympus"}`,
+		"no selector":    `{"promotions": [{"days": ["mon"]}]}`,
+		"both selectors": `{"promotions": [{"code": "A", "prefix": "B"}]}`,
+		"bad day":        `{"promotions": [{"code": "A", "days": ["funday"]}]}`,
+		"bad hours":      `{"promotions": [{"code": "A", "hours": [{"from": "4pm", "to": "18:00"}]}]}`,
+		"end before":     `{"promotions": [{"code": "A", "start": "2025-02-01", "end": "2025-01-01"}]}`,
+	}
+	dir := t.TempDir()
+	for name, data := range tests {
+		path := filepath.Join(dir, "schedule.json")
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSchedule(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCheckPromoCode_Schedule(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS", "WELCOME10"})
+	if err := os.WriteFile(filepath.Join(dir, "schedule.json"), []byte(testSchedule), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	london, _ := time.LoadLocation("Europe/London")
+	var now atomic.Pointer[time.Time]
+	set := func(v string) {
+		tm, _ := time.ParseInLocation("2006-01-02 15:04", v, london)
+		now.Store(&tm)
+	}
+
+	for _, mode := range []Mode{ModeStreaming, ModeIndexed} {
+		t.Run(string(mode), func(t *testing.T) {
+			v, err := New(Config{
+				Dir: dir, Files: []string{"a.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1,
+				Mode: mode, ScheduleFile: "schedule.json",
+				Clock: func() time.Time { return *now.Load() },
+			})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if err := v.LoadCouponFiles(); err != nil {
+				t.Fatalf("LoadCouponFiles: %v", err)
+			}
+
+			set("2025-06-02 16:30")
+			if res := v.CheckPromoCode(context.Background(), "HAPPYHRS"); !res.Valid {
+				t.Fatalf("in window: %+v", res)
+			}
+
+			// The cached positive must not outlive the window.
+			set("2025-06-02 19:00")
+			res := v.CheckPromoCode(context.Background(), "HAPPYHRS")
+			if res.Valid || res.Reason != ReasonOutsideSchedule || !strings.Contains(res.Message(), "16:00-18:00") {
+				t.Fatalf("outside window: %+v (%s)", res, res.Message())
+			}
+			if !v.ValidatePromoCode("WELCOME10") {
+				t.Fatal("unscheduled code rejected")
+			}
+			if res := v.CheckPromoCode(context.Background(), "XMAS2025"); res.Reason != ReasonNotEnoughHits {
+				t.Fatalf("unknown code must fail membership first, got %s", res.Reason)
+			}
+		})
+	}
+}
+
+func TestCheckPromoCode_MissingScheduleFile(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "a.gz"), []string{"HAPPYHRS"})
+
+	v := NewValidatorService(Config{Dir: dir, Files: []string{"a.gz"}, MinLen: 8, MaxLen: 10, RequiredHits: 1, ScheduleFile: "schedule.json"})
+	if err := v.LoadCouponFiles(); err != nil {
+		t.Fatalf("a missing schedule must not fail loading: %v", err)
+	}
+	if !v.ValidatePromoCode("HAPPYHRS") {
+		t.Fatal("code rejected without a schedule")
+	}
+
+	// A schedule added later is picked up on reload.
+	if err := os.WriteFile(filepath.Join(dir, "schedule.json"), []byte(`{"promotions": [{"code": "HAPPYHRS", "end": "2000-01-01"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.(Reloader).Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if res := v.CheckPromoCode(context.Background(), "HAPPYHRS"); res.Reason != ReasonOutsideSchedule {
+		t.Fatalf("after reload: %+v", res)
+	}
+}
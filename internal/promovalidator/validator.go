@@ -3,13 +3,15 @@ package promovalidator
 
 import (
 	"bufio"
-	"compress/gzip"
+	"context"
 	"errors"
+	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 	"unicode"
 )
 
@@ -20,9 +22,55 @@ type Config struct {
 	MinLen                   int      // minimum code length (e.g. 8)
 	MaxLen                   int      // maximum code length (e.g. 10)
 	RequiredHits             int      // how many different files the code must appear in (e.g. 2)
-	MaxConcurrentValidations int      // If <= 0, a small default (2) is used.
+	MaxConcurrentValidations int      // caps concurrent file scans across all validations. If <= 0, a small default (2) is used.
+
+	Mode          Mode  // ModeStreaming (default) or ModeIndexed
+	MaxIndexBytes int64 // ModeIndexed only: fail loading if the index would exceed this (0 = unbounded)
+	// IndexFile (ModeIndexed only) is a compiled index (see WriteIndexFile),
+	// relative to Dir unless absolute. It is used instead of reading the
+	// coupon files while it matches them; otherwise the files are read as usual.
+	IndexFile string
+
+	ReloadInterval time.Duration // how often Watch polls the files; if <= 0, 30s is used
+
+	// FilePolicy decides what unreadable files do; "" means PolicyTolerate.
+	FilePolicy FilePolicy
+
+	// Result cache (streaming mode). Sizes <= 0 use 10000 entries each.
+	// PositiveCacheTTL 0 keeps valid codes until evicted or reloaded;
+	// NegativeCacheTTL 0 uses 10m, a negative value disables expiry.
+	PositiveCacheSize int
+	NegativeCacheSize int
+	PositiveCacheTTL  time.Duration
+	NegativeCacheTTL  time.Duration
+
+	// Prefilter (streaming mode) builds a Bloom filter per file when loading,
+	// so codes a filter rules out skip that file's scan. PrefilterFPRate is
+	// the target false-positive rate (default 0.01); PrefilterMaxBytes caps
+	// the filters' total size (0 = unbounded), raising the rate to fit.
+	// Filters are rebuilt on reload; until then a changed file may be
+	// skipped for codes it gained.
+	Prefilter         bool
+	PrefilterFPRate   float64
+	PrefilterMaxBytes int64
+
+	// ScheduleFile is a JSON promotion schedule in Dir (see LoadSchedule)
+	// restricting when codes are accepted. "" or a missing file: no restriction.
+	ScheduleFile string
+	// Clock returns the time schedules are evaluated at; nil uses time.Now.
+	Clock func() time.Time
 }
 
+// Mode selects the ValidatorService implementation built by New.
+type Mode string
+
+const (
+	// ModeStreaming re-reads the coupon files on demand for every uncached code.
+	ModeStreaming Mode = "streaming"
+	// ModeIndexed reads the coupon files once in LoadCouponFiles and answers from memory.
+	ModeIndexed Mode = "indexed"
+)
+
 // ValidatorService is the public interface for promo validation.
 type ValidatorService interface {
 	// LoadCouponFiles validates the configuration once. (Does NOT open/parse files.)
@@ -31,6 +79,30 @@ type ValidatorService interface {
 	// Case-sensitive, streams files on demand, tolerates missing/unreadable files,
 	// and returns true as soon as RequiredHits is reached.
 	ValidatePromoCode(code string) bool
+	// ValidatePromoCodeContext is ValidatePromoCode, aborting the work (and
+	// returning false) when ctx is cancelled, e.g. on client disconnect.
+	ValidatePromoCodeContext(ctx context.Context, code string) bool
+	// CheckPromoCode is ValidatePromoCodeContext with the reason behind the
+	// outcome; the bool methods are thin wrappers around it.
+	CheckPromoCode(ctx context.Context, code string) Result
+}
+
+// Status is an operational snapshot of a validator, suitable for JSON export.
+type Status struct {
+	Mode   Mode         `json:"mode"`
+	Reload ReloadStatus `json:"reload"`
+	Index  *IndexStats  `json:"index,omitempty"`
+	Cache  *CacheStats  `json:"cache,omitempty"`
+
+	Prefilter *PrefilterStats `json:"prefilter,omitempty"`
+	Health    []FileHealth    `json:"health"`
+
+	Schedule *Schedule `json:"schedule,omitempty"`
+}
+
+// StatusReporter is implemented by validators that can describe their state.
+type StatusReporter interface {
+	Status() Status
 }
 
 // streamingValidator implements ValidatorService with on-demand streaming and caching.
@@ -39,107 +111,355 @@ type streamingValidator struct {
 	once sync.Once
 	init error
 
-	// tiny concurrent cache: promoCode -> bool (result).
-	cache sync.Map
+	// bounded result cache: promoCode -> Result, split into valid/invalid LRUs.
+	cache *resultCache
+	// gen is bumped on every reload so scans that started before it don't
+	// write stale results into the freshly cleared cache.
+	gen    atomic.Uint64
+	rl     *reloader
+	health *fileHealthRegistry
+	// files is Config.Files with patterns expanded, refreshed on (re)load.
+	files  atomic.Pointer[[]string]
+	sched  *schedules
+	filter *prefilters // nil unless Config.Prefilter
 
-	// semaphore to cap concurrent validations (protects memory/disk IO under load)
+	// semaphore to cap concurrent file scans (protects memory/disk IO under load)
 	sem chan struct{}
 }
 
+// New returns the ValidatorService selected by cfg.Mode.
+func New(cfg Config) (ValidatorService, error) {
+	switch cfg.Mode {
+	case "", ModeStreaming:
+		return NewValidatorService(cfg), nil
+	case ModeIndexed:
+		return NewIndexedValidatorService(cfg), nil
+	default:
+		return nil, fmt.Errorf("validator: unknown mode %q", cfg.Mode)
+	}
+}
+
 // NewValidatorService creates a streaming validator with an optional concurrency cap.
 func NewValidatorService(cfg Config) ValidatorService {
 	max := cfg.MaxConcurrentValidations
 	if max <= 0 {
 		max = 2 // small, safe default
 	}
-	return &streamingValidator{
-		cfg: cfg,
-		sem: make(chan struct{}, max),
+	v := &streamingValidator{
+		cfg:    cfg,
+		cache:  newResultCache(cfg),
+		health: newFileHealthRegistry(cfg),
+		sched:  newSchedules(cfg),
+		sem:    make(chan struct{}, max),
+	}
+	if cfg.Prefilter {
+		v.filter = newPrefilters(cfg)
 	}
+	v.rl = newReloader(cfg, v.resetCache)
+	return v
 }
 
-// LoadCouponFiles validates configuration only (no file IO here).
+var (
+	_ Reloader       = (*streamingValidator)(nil)
+	_ StatusReporter = (*streamingValidator)(nil)
+	_ HealthReporter = (*streamingValidator)(nil)
+)
+
+// LoadCouponFiles validates configuration, probes each file's header for
+// Health, and records the files' checksums for ReloadStatus. Coupon contents
+// are not parsed here. Under PolicyRequireAll any unreadable file is an error.
 func (v *streamingValidator) LoadCouponFiles() error {
 	v.once.Do(func() {
-		if len(v.cfg.Files) == 0 {
-			v.init = errors.New("validator: no files configured")
+		if v.init = validateConfig(v.cfg); v.init != nil {
 			return
 		}
-		if v.cfg.MinLen <= 0 || v.cfg.MaxLen < v.cfg.MinLen {
-			v.init = errors.New("validator: invalid length bounds")
+		if v.init = v.sched.load(); v.init != nil {
 			return
 		}
-		if v.cfg.RequiredHits <= 0 {
-			v.init = errors.New("validator: RequiredHits must be >= 1")
+		names, err := v.health.probe()
+		if err != nil && v.cfg.FilePolicy == PolicyRequireAll {
+			v.files.Store(&names)
+			v.init = err
 			return
 		}
+		if v.filter != nil {
+			v.filter.cur.Store(v.filter.build(names))
+		}
+		v.files.Store(&names)
+		v.rl.prime()
 	})
 	return v.init
 }
 
-// ValidatePromoCode checks code (case-sensitive), scanning files on demand.
-// Missing/unreadable files are skipped. Results are cached per code.
+// Reload drops all cached results; files are re-read on the next lookups.
+func (v *streamingValidator) Reload() error { return v.rl.reload() }
+
+// Watch polls the coupon files and reloads when they change, until ctx is done.
+func (v *streamingValidator) Watch(ctx context.Context) { v.rl.watch(ctx) }
+
+// ReloadStatus reports the outcome of the most recent (re)load.
+func (v *streamingValidator) ReloadStatus() ReloadStatus { return v.rl.snapshot() }
+
+// Health lists the last known state of every configured file.
+func (v *streamingValidator) Health() []FileHealth { return v.health.snapshot() }
+
+// Status reports the validator's operational state.
+func (v *streamingValidator) Status() Status {
+	cache := v.cache.stats()
+	st := Status{Mode: ModeStreaming, Reload: v.ReloadStatus(), Cache: &cache, Health: v.Health(), Schedule: v.sched.cur.Load()}
+	if v.filter != nil {
+		st.Prefilter = v.filter.stats()
+	}
+	return st
+}
+
+// resetCache reloads the schedule, drops cached results and re-expands and
+// re-probes the files after a change.
+func (v *streamingValidator) resetCache() error {
+	if err := v.sched.load(); err != nil {
+		return err
+	}
+	v.gen.Add(1)
+	v.cache.purge()
+	names, err := v.health.probe()
+	if err != nil && v.cfg.FilePolicy == PolicyRequireAll {
+		v.files.Store(&names)
+		return err
+	}
+	if v.filter != nil {
+		v.filter.cur.Store(v.filter.build(names))
+	}
+	v.files.Store(&names)
+	return nil
+}
+
+// fileList returns the current file set, expanding patterns if no load has
+// recorded one yet.
+func (v *streamingValidator) fileList() []string {
+	if names := v.files.Load(); names != nil {
+		return *names
+	}
+	return expandFiles(v.cfg)
+}
+
+// validateConfig checks the rules shared by every validator implementation.
+func validateConfig(cfg Config) error {
+	if len(cfg.Files) == 0 {
+		return errors.New("validator: no files configured")
+	}
+	for _, entry := range cfg.Files {
+		if _, err := filepath.Match(entry, ""); err != nil {
+			return fmt.Errorf("validator: bad file pattern %q: %w", entry, err)
+		}
+	}
+	if cfg.MinLen <= 0 || cfg.MaxLen < cfg.MinLen {
+		return errors.New("validator: invalid length bounds")
+	}
+	if cfg.RequiredHits <= 0 {
+		return errors.New("validator: RequiredHits must be >= 1")
+	}
+	if !cfg.FilePolicy.valid() {
+		return fmt.Errorf("validator: unknown file policy %q", cfg.FilePolicy)
+	}
+	return nil
+}
+
+// ValidatePromoCode is ValidatePromoCodeContext without cancellation.
 func (v *streamingValidator) ValidatePromoCode(code string) bool {
-	// Concurrency cap for validations
-	v.sem <- struct{}{}
-	defer func() { <-v.sem }()
+	return v.ValidatePromoCodeContext(context.Background(), code)
+}
 
-	// Best-effort config validation (Once); ignore error at call site.
-	_ = v.LoadCouponFiles()
+// ValidatePromoCodeContext reports CheckPromoCode(ctx, code).Valid.
+func (v *streamingValidator) ValidatePromoCodeContext(ctx context.Context, code string) bool {
+	return v.CheckPromoCode(ctx, code).Valid
+}
+
+// CheckPromoCode checks code (case-sensitive), scanning files on demand.
+// Missing/unreadable files are skipped (and listed in the result) unless
+// FilePolicy is strict, in which case the code is rejected. Results are
+// cached per code; aborted or file-error results are not cached. The
+// schedule is applied after the cache, so cached codes still expire on time.
+func (v *streamingValidator) CheckPromoCode(ctx context.Context, code string) Result {
+	res := v.lookup(ctx, code)
+	v.sched.apply(&res)
+	return res
+}
 
+// lookup decides file membership of code.
+func (v *streamingValidator) lookup(ctx context.Context, code string) Result {
 	code = strings.TrimSpace(code)
-	if l := len(code); l < v.cfg.MinLen || l > v.cfg.MaxLen || !isAlnum(code) {
-		return false
+
+	// Best-effort config validation (Once); only strict policies act on the error.
+	if err := v.LoadCouponFiles(); err != nil && v.cfg.FilePolicy.strict() {
+		res := newResult(v.cfg, code)
+		res.Reason = ReasonMisconfigured
+		return res
+	}
+
+	res, ok := checkFormat(v.cfg, code)
+	if !ok {
+		return res
+	}
+
+	// Fail closed while any file is known to be broken (checked before the
+	// cache so earlier positives are not served either).
+	if v.cfg.FilePolicy.strict() {
+		if failed := v.health.failed(); len(failed) > 0 {
+			res.failFiles(failed)
+			return res
+		}
 	}
 
 	// Cache fast path
-	if cached, ok := v.cache.Load(code); ok {
-		return cached.(bool)
+	if cached, ok := v.cache.get(code); ok {
+		cached.Cached = true
+		return cached
+	}
+
+	gen := v.gen.Load()
+	if err := v.scanFiles(ctx, &res); err != nil {
+		res.Valid, res.Reason = false, ReasonAborted
+		return res
 	}
+	if v.cfg.FilePolicy.strict() && len(res.UnreadableFiles) > 0 {
+		res.failFiles(nil)
+		return res
+	}
+	v.storeResult(gen, res)
+	return res
+}
 
-	// Stream files sequentially with early exit on RequiredHits
-	found := 0
-	for _, name := range v.cfg.Files {
-		full := filepath.Join(v.cfg.Dir, name)
-		ok, err := foundInFile(full, code, v.cfg.MinLen, v.cfg.MaxLen)
-		if err != nil {
-			// tolerate unreadable/missing file: skip
+// fileScan is the outcome of scanning one file for one code.
+type fileScan struct {
+	i   int // index into the file set being scanned
+	ok  bool
+	err error
+}
+
+// scanFiles scans all files concurrently (each scan holds a v.sem slot) and
+// cancels the remaining scans as soon as the outcome is decided: RequiredHits
+// reached, or too few files left to reach it. It fills res in config order and
+// returns ctx's error if the caller gave up before a decision.
+func (v *streamingValidator) scanFiles(parent context.Context, res *Result) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	files := v.fileList()
+	res.Files = len(files)
+	n := len(files)
+	results := make(chan fileScan, n) // buffered: late scans never block
+	h := hashToken(res.Code)
+	for i, name := range files {
+		if v.filter != nil && v.filter.absent(name, h) {
+			results <- fileScan{i: i} // definite miss, no I/O
 			continue
 		}
-		if ok {
-			found++
-			if found >= v.cfg.RequiredHits {
-				v.cache.Store(code, true)
-				return true
+		go func(i int, name string) {
+			select {
+			case v.sem <- struct{}{}:
+			case <-ctx.Done():
+				results <- fileScan{i: i, err: ctx.Err()}
+				return
+			}
+			defer func() { <-v.sem }()
+
+			ok, err := foundInFile(ctx, filepath.Join(v.cfg.Dir, name), res.Code, v.cfg.MinLen, v.cfg.MaxLen)
+			if ok || ctx.Err() == nil {
+				v.health.record(name, nilIfFound(ok, err), true)
 			}
+			results <- fileScan{i: i, ok: ok, err: err}
+		}(i, name)
+	}
+
+	scans := make([]*fileScan, n)
+	found, pending := 0, n
+	for pending > 0 {
+		fs := <-results
+		scans[fs.i] = &fs
+		if fs.ok {
+			found++
 		}
+		pending--
+		if found >= v.cfg.RequiredHits || found+pending < v.cfg.RequiredHits {
+			break
+		}
+	}
+	if err := parent.Err(); err != nil {
+		// scans may have been cut short; the outcome is unknown
+		return err
 	}
 
-	v.cache.Store(code, false)
-	return false
+	for i, fs := range scans {
+		switch {
+		case fs == nil: // cancelled after the decision
+		case fs.ok:
+			res.MatchedFiles = append(res.MatchedFiles, files[i])
+		case fs.err != nil && !errors.Is(fs.err, context.Canceled):
+			res.UnreadableFiles = append(res.UnreadableFiles, files[i])
+		}
+	}
+	res.Hits = found
+	res.decide()
+	return nil
+}
+
+// nilIfFound treats a match as a healthy read even if the file failed later.
+func nilIfFound(found bool, err error) error {
+	if found {
+		return nil
+	}
+	return err
+}
+
+// storeResult caches a result unless a reload happened since the scan began.
+func (v *streamingValidator) storeResult(gen uint64, res Result) {
+	if v.gen.Load() == gen {
+		v.cache.put(res)
+	}
 }
 
 // foundInFile opens and scans the file for the exact token (case-sensitive).
-// If file can't be opened or scan fails, it returns (false, nil) to indicate "not found, but not fatal".
-func foundInFile(filename, code string, minLen, maxLen int) (bool, error) {
-	f, err := os.Open(filename)
+// A match found before a read error still counts; otherwise the open,
+// decompression, scan or ctx error is returned for the caller to judge.
+func foundInFile(ctx context.Context, filename, code string, minLen, maxLen int) (bool, error) {
+	r, err := openCouponFile(filename)
 	if err != nil {
-		// treat missing/unreadable file as non-fatal
-		return false, nil
+		return false, err
 	}
-	defer f.Close()
+	defer r.Close()
 
-	var r io.Reader = f
-	if strings.HasSuffix(strings.ToLower(filename), ".gz") {
-		gzr, err := gzip.NewReader(f)
-		if err != nil {
-			// unreadable gzip -> skip
-			return false, nil
+	found := false
+	err = scanTokens(&ctxReader{ctx: ctx, r: r}, minLen, maxLen, func(w string) bool {
+		if w == code {
+			found = true
+			return false
 		}
-		defer gzr.Close()
-		r = gzr
+		return true
+	})
+	if found {
+		return true, nil
+	}
+	return false, err
+}
+
+// ctxReader fails reads once ctx is done, so long scans stop within one buffer fill.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
 	}
+	return c.r.Read(p)
+}
 
+// scanTokens splits r into tokens on non-alphanumeric runes and calls fn for
+// every ASCII-alphanumeric token whose length is within [minLen, maxLen].
+// Scanning stops early when fn returns false. Both the streaming scan and the
+// index builder go through here so they share the exact same token rules.
+func scanTokens(r io.Reader, minLen, maxLen int, fn func(tok string) bool) error {
 	sc := bufio.NewScanner(r)
 	// allow reasonably long lines (1MiB max token buffer)
 	const maxLine = 1024 * 1024
@@ -152,13 +472,14 @@ func foundInFile(filename, code string, minLen, maxLen int) (bool, error) {
 			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
 		})
 		for _, w := range words {
-			if len(w) == len(code) && len(w) >= minLen && len(w) <= maxLen && isAlnum(w) && w == code {
-				return true, nil
+			if len(w) >= minLen && len(w) <= maxLen && isAlnum(w) {
+				if !fn(w) {
+					return nil
+				}
 			}
 		}
 	}
-	// treat scanner errors as "not found" but non-fatal
-	return false, nil
+	return sc.Err()
 }
 
 func isAlnum(s string) bool {
@@ -2,8 +2,11 @@ package promovalidator
 
 import (
 	"compress/gzip"
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 )
 
@@ -137,3 +140,63 @@ func TestValidatePromoCode_CaseSensitive(t *testing.T) {
 		t.Fatalf("expected lowercase happyhrs to be invalid (case-sensitive check)")
 	}
 }
+
+func TestValidatePromoCodeContext_CancelledIsNotCached(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "couponbase1.gz"), []string{"HAPPYHRS"})
+	writeGzipFile(t, filepath.Join(dir, "couponbase2.gz"), []string{"HAPPYHRS"})
+
+	v := NewValidatorService(Config{
+		Dir:          dir,
+		Files:        []string{"couponbase1.gz", "couponbase2.gz"},
+		MinLen:       8,
+		MaxLen:       10,
+		RequiredHits: 2,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if v.ValidatePromoCodeContext(ctx, "HAPPYHRS") {
+		t.Fatalf("expected false for a cancelled lookup")
+	}
+	if !v.ValidatePromoCode("HAPPYHRS") {
+		t.Fatalf("cancelled lookup must not poison the cache")
+	}
+}
+
+func TestValidatePromoCodeContext_ParallelUnderSemaphore(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{"a.gz", "b.gz", "c.gz", "d.gz"}
+	for i, f := range files {
+		lines := []string{"FILLER001", "FILLER002"}
+		if i%2 == 0 {
+			lines = append(lines, "HAPPYHRS")
+		}
+		writeGzipFile(t, filepath.Join(dir, f), lines)
+	}
+
+	v := NewValidatorService(Config{
+		Dir:                      dir,
+		Files:                    files,
+		MinLen:                   8,
+		MaxLen:                   10,
+		RequiredHits:             2,
+		MaxConcurrentValidations: 1,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code, want := "HAPPYHRS", true
+			if i%2 == 1 {
+				code, want = fmt.Sprintf("MISSING%02d", i), false
+			}
+			if got := v.ValidatePromoCodeContext(context.Background(), code); got != want {
+				t.Errorf("ValidatePromoCodeContext(%q) = %v, want %v", code, got, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+)
+
+// ErrInsufficientStock is matched (errors.Is) by every *StockShortageError.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// StockShortage is a product an order wants more of than is available.
+type StockShortage struct {
+	Item      int    `json:"item"` // 1-based position of the product's first line
+	ProductID string `json:"productId"`
+	Requested int    `json:"requested"` // over all of the order's lines
+	Available int    `json:"available"` // 0 when sold out
+	SoldOut   bool   `json:"soldOut,omitempty"`
+}
+
+// StockShortageError lists every short product of a failed reservation.
+type StockShortageError struct {
+	Shortages []StockShortage `json:"shortages"`
+}
+
+func (e *StockShortageError) Error() string {
+	parts := make([]string, 0, len(e.Shortages))
+	for _, s := range e.Shortages {
+		if s.SoldOut {
+			parts = append(parts, fmt.Sprintf("item %d: product %s is sold out", s.Item, s.ProductID))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("item %d: product %s has %d left, %d requested",
+			s.Item, s.ProductID, s.Available, s.Requested))
+	}
+	return "insufficient stock: " + strings.Join(parts, "; ")
+}
+
+func (e *StockShortageError) Is(target error) bool { return target == ErrInsufficientStock }
+
+// InventoryRepository holds the stock that orders have reserved.
+type InventoryRepository interface {
+	// Reserve sets orderID's reservation to the quantities of items,
+	// replacing any earlier reservation of the order. All lines are checked
+	// and taken under one lock: either the whole reservation succeeds or
+	// nothing changes and a *StockShortageError lists the short products.
+	// Products without a stock count are only checked for being sold out.
+	Reserve(orderID string, items []models.OrderItem) error
+
+	// Release returns the stock reserved for orderID. Releasing an unknown
+	// reservation is a no-op.
+	Release(orderID string) error
+}
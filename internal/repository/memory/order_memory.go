@@ -2,6 +2,8 @@ package memory
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
@@ -62,3 +64,73 @@ func (r *orderMemoryRepository) FindByID(id string) (*models.Order, error) {
 	cp := order
 	return &cp, nil
 }
+
+// UpdateOrder runs update on a deep copy under the write lock and stores it.
+func (r *orderMemoryRepository) UpdateOrder(id string, update func(*models.Order) error) (*models.Order, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, repository.ErrInvalidOrderID
+	}
+	order, exists := r.orders[id]
+	if !exists {
+		return nil, repository.ErrOrderNotFound
+	}
+
+	cp := cloneOrder(order)
+	if err := update(&cp); err != nil {
+		return nil, err
+	}
+	cp.ID = id // the key is not updatable
+	r.orders[id] = cp
+
+	out := cloneOrder(cp)
+	return &out, nil
+}
+
+// ListOrders filters and sorts all orders, then cuts the page after q.After.
+func (r *orderMemoryRepository) ListOrders(q repository.OrderQuery) (repository.OrderPage, error) {
+	r.mutex.RLock()
+	var matched []*models.Order
+	for id := range r.orders {
+		o := r.orders[id]
+		if q.Matches(&o) && (q.After == nil || q.After.After(&o)) {
+			matched = append(matched, &o)
+		}
+	}
+	r.mutex.RUnlock()
+
+	slices.SortFunc(matched, func(a, b *models.Order) int {
+		if c := q.Sort.Key(a).Compare(q.Sort.Key(b)); c != 0 {
+			if q.Sort.Desc() {
+				return -c
+			}
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	var page repository.OrderPage
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+		last := matched[q.Limit-1]
+		page.Next = &repository.OrderCursor{Sort: q.Sort, At: q.Sort.Key(last), ID: last.ID}
+	}
+	page.Orders = make([]models.Order, 0, len(matched))
+	for _, o := range matched {
+		page.Orders = append(page.Orders, cloneOrder(*o))
+	}
+	return page, nil
+}
+
+// cloneOrder copies o's slices so callers can't modify the stored order.
+func cloneOrder(o models.Order) models.Order {
+	o.Items = slices.Clone(o.Items)
+	o.Products = slices.Clone(o.Products)
+	o.Discounts = slices.Clone(o.Discounts)
+	o.Pricing.Lines = slices.Clone(o.Pricing.Lines)
+	o.StatusHistory = slices.Clone(o.StatusHistory)
+	o.Revisions = slices.Clone(o.Revisions)
+	return o
+}
@@ -2,7 +2,9 @@ package memory
 
 import (
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -172,6 +174,188 @@ func TestFindByID_Behavior(t *testing.T) {
 	}
 }
 
+func TestUpdateOrder_Behavior(t *testing.T) {
+	t.Parallel()
+
+	repo := newRepo(t)
+
+	validID := uuid.New().String()
+	if _, err := repo.CreateOrder(&models.Order{ID: validID, Status: models.StatusPlaced}); err != nil {
+		t.Fatalf("seed create failed: %v", err)
+	}
+	errVeto := errors.New("veto")
+
+	tests := []struct {
+		name       string
+		id         string
+		update     func(*models.Order) error
+		wantIs     error
+		wantStatus models.OrderStatus // stored status afterwards
+	}{
+		{
+			name:       "success",
+			id:         validID,
+			update:     func(o *models.Order) error { o.Status = models.StatusAccepted; return nil },
+			wantStatus: models.StatusAccepted,
+		},
+		{
+			name: "update error aborts",
+			id:   validID,
+			update: func(o *models.Order) error {
+				o.Status = models.StatusRejected
+				return errVeto
+			},
+			wantIs:     errVeto,
+			wantStatus: models.StatusAccepted,
+		},
+		{
+			name:       "id is not updatable",
+			id:         validID,
+			update:     func(o *models.Order) error { o.ID = uuid.New().String(); return nil },
+			wantStatus: models.StatusAccepted,
+		},
+		{
+			name:   "invalid uuid",
+			id:     "bad-id",
+			update: func(*models.Order) error { return nil },
+			wantIs: repository.ErrInvalidOrderID,
+		},
+		{
+			name:   "not found",
+			id:     uuid.New().String(),
+			update: func(*models.Order) error { return nil },
+			wantIs: repository.ErrOrderNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := repo.UpdateOrder(tc.id, tc.update)
+			if !errors.Is(err, tc.wantIs) {
+				t.Fatalf("want errors.Is(err, %v)=true; got err=%v", tc.wantIs, err)
+			}
+			if tc.wantStatus == "" {
+				return
+			}
+			got, err := repo.FindByID(validID)
+			if err != nil || got.Status != tc.wantStatus {
+				t.Fatalf("stored order = %+v, %v; want status %s", got, err, tc.wantStatus)
+			}
+		})
+	}
+}
+
+func TestListOrders_FiltersSortAndPages(t *testing.T) {
+	t.Parallel()
+
+	repo := newRepo(t)
+	base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// o[i] created i hours after base; o[2] and o[3] share a timestamp
+	var o []models.Order
+	for i, spec := range []struct {
+		hour   int
+		status models.OrderStatus
+		key    string
+	}{
+		{0, models.StatusPlaced, "a"},
+		{1, models.StatusDelivered, "b"},
+		{2, models.StatusPlaced, "a"},
+		{2, models.StatusCancelled, "a"},
+		{4, models.StatusPlaced, "b"},
+	} {
+		order := models.Order{
+			ID:        uuid.New().String(),
+			Status:    spec.status,
+			APIKey:    spec.key,
+			CreatedAt: base.Add(time.Duration(spec.hour) * time.Hour),
+			UpdatedAt: base.Add(time.Duration(10-i) * time.Hour),
+		}
+		if _, err := repo.CreateOrder(&order); err != nil {
+			t.Fatalf("seed create failed: %v", err)
+		}
+		o = append(o, order)
+	}
+	if o[3].ID < o[2].ID {
+		o[2], o[3] = o[3], o[2] // ties sort by ID
+	}
+
+	tests := []struct {
+		name  string
+		query repository.OrderQuery
+		want  []models.Order
+	}{
+		{
+			name:  "newest first",
+			query: repository.OrderQuery{Sort: repository.SortCreatedDesc},
+			want:  []models.Order{o[4], o[2], o[3], o[1], o[0]},
+		},
+		{
+			name:  "oldest first",
+			query: repository.OrderQuery{Sort: repository.SortCreatedAsc},
+			want:  []models.Order{o[0], o[1], o[2], o[3], o[4]},
+		},
+		{
+			name:  "by status",
+			query: repository.OrderQuery{Sort: repository.SortCreatedAsc, Statuses: []models.OrderStatus{models.StatusDelivered, models.StatusCancelled}},
+			want:  filter(o, func(x models.Order) bool { return x.Status != models.StatusPlaced }),
+		},
+		{
+			name:  "by api key and date range",
+			query: repository.OrderQuery{Sort: repository.SortCreatedAsc, APIKey: "a", From: base.Add(time.Hour), To: base.Add(3 * time.Hour)},
+			want:  []models.Order{o[2], o[3]},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// walk every page of size 2 and check the concatenation
+			var got []models.Order
+			q := tc.query
+			q.Limit = 2
+			for pages := 0; ; pages++ {
+				if pages > len(o) {
+					t.Fatal("pagination does not terminate")
+				}
+				page, err := repo.ListOrders(q)
+				if err != nil {
+					t.Fatalf("ListOrders: %v", err)
+				}
+				got = append(got, page.Orders...)
+				if page.Next == nil {
+					break
+				}
+				// the cursor survives an encode/decode round trip
+				if q.After, err = repository.ParseOrderCursor(page.Next.Encode()); err != nil {
+					t.Fatalf("ParseOrderCursor: %v", err)
+				}
+			}
+			if ids(got) != ids(tc.want) {
+				t.Fatalf("got %v, want %v", ids(got), ids(tc.want))
+			}
+		})
+	}
+}
+
+func filter(orders []models.Order, keep func(models.Order) bool) []models.Order {
+	var out []models.Order
+	for _, o := range orders {
+		if keep(o) {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+func ids(orders []models.Order) string {
+	var s []string
+	for _, o := range orders {
+		s = append(s, o.ID[:8])
+	}
+	return strings.Join(s, ",")
+}
+
 // containsFold: simple case-insensitive substring check
 func containsFold(s, sub string) bool {
 	if len(sub) == 0 {
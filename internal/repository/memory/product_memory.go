@@ -15,28 +15,39 @@ var (
 )
 
 type ProductRepo struct {
-	mu       sync.RWMutex
-	products map[string]models.Product
+	mu           sync.RWMutex
+	products     map[string]models.Product
+	reservations map[string]map[string]int // orderID → productID → reserved units
 }
 
 // NewProductRepo seeds the repo with initial products.
 func NewProductRepo(seed []models.Product) *ProductRepo {
 	m := make(map[string]models.Product, len(seed))
 	for _, p := range seed {
-		m[p.ID] = p
+		m[p.ID] = cloneProduct(p)
 	}
-	return &ProductRepo{products: m}
+	return &ProductRepo{products: m, reservations: make(map[string]map[string]int)}
 }
 
 var _ repository.ProductRepository = (*ProductRepo)(nil)
 var _ repository.ProductWriter = (*ProductRepo)(nil) // Optional
+var _ repository.InventoryRepository = (*ProductRepo)(nil)
+
+// cloneProduct copies p so callers can't change the stored stock count.
+func cloneProduct(p models.Product) models.Product {
+	if p.Stock != nil {
+		n := *p.Stock
+		p.Stock = &n
+	}
+	return p
+}
 
 func (r *ProductRepo) GetAll() ([]models.Product, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	out := make([]models.Product, 0, len(r.products))
 	for _, p := range r.products {
-		out = append(out, p)
+		out = append(out, cloneProduct(p))
 	}
 	return out, nil
 }
@@ -48,7 +59,7 @@ func (r *ProductRepo) GetByID(id string) (*models.Product, error) {
 	if !ok {
 		return nil, ErrProductNotFound
 	}
-	cp := p
+	cp := cloneProduct(p)
 	return &cp, nil
 }
 
@@ -63,7 +74,7 @@ func (r *ProductRepo) Create(p models.Product) error {
 	if _, exists := r.products[p.ID]; exists {
 		return ErrProductExists
 	}
-	r.products[p.ID] = p
+	r.products[p.ID] = cloneProduct(p)
 	return nil
 }
 
@@ -76,7 +87,7 @@ func (r *ProductRepo) Update(p models.Product) error {
 	if _, ok := r.products[p.ID]; !ok {
 		return ErrProductNotFound
 	}
-	r.products[p.ID] = p
+	r.products[p.ID] = cloneProduct(p)
 	return nil
 }
 
@@ -92,3 +103,75 @@ func (r *ProductRepo) Delete(id string) error {
 	delete(r.products, id)
 	return nil
 }
+
+// --- Inventory ---
+
+// Reserve checks every product of items and adjusts stock under one lock.
+// Only the difference to the order's earlier reservation is taken or given
+// back, so modifying an order never competes with its own stock.
+func (r *ProductRepo) Reserve(orderID string, items []models.OrderItem) error {
+	want := make(map[string]int, len(items))
+	first := make(map[string]int, len(items))
+	var ids []string
+	for i, it := range items {
+		if _, seen := want[it.ProductID]; !seen {
+			first[it.ProductID] = i + 1
+			ids = append(ids, it.ProductID)
+		}
+		want[it.ProductID] += it.Quantity
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	held := r.reservations[orderID]
+	var short []repository.StockShortage
+	for _, id := range ids {
+		p, ok := r.products[id]
+		more := want[id] - held[id]
+		switch {
+		case more <= 0:
+		case !ok || p.SoldOut:
+			short = append(short, repository.StockShortage{Item: first[id], ProductID: id, Requested: want[id], SoldOut: true})
+		case p.Stock != nil && *p.Stock < more:
+			short = append(short, repository.StockShortage{Item: first[id], ProductID: id, Requested: want[id], Available: *p.Stock + held[id]})
+		}
+	}
+	if len(short) > 0 {
+		return &repository.StockShortageError{Shortages: short}
+	}
+
+	r.restock(held)
+	for id, n := range want {
+		r.adjust(id, -n)
+	}
+	r.reservations[orderID] = want
+	return nil
+}
+
+// Release gives the order's reserved units back to stock.
+func (r *ProductRepo) Release(orderID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.restock(r.reservations[orderID])
+	delete(r.reservations, orderID)
+	return nil
+}
+
+func (r *ProductRepo) restock(units map[string]int) {
+	for id, n := range units {
+		r.adjust(id, n)
+	}
+}
+
+// adjust changes a counted product's stock by delta. Callers hold r.mu.
+func (r *ProductRepo) adjust(id string, delta int) {
+	p, ok := r.products[id]
+	if !ok || p.Stock == nil {
+		return
+	}
+	n := *p.Stock + delta
+	p.Stock = &n
+	r.products[id] = p
+}
@@ -1,15 +1,19 @@
 package memory
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository"
 )
 
 func TestProductRepo_Behavior(t *testing.T) {
 	seed := []models.Product{
-		{ID: "1", Name: "Chicken Waffle", Price: 12.99, Category: "Waffle"},
-		{ID: "2", Name: "Caesar Salad", Price: 8.99, Category: "Salad"},
+		{ID: "1", Name: "Chicken Waffle", Price: money.MustParse("12.99", ""), Category: "Waffle"},
+		{ID: "2", Name: "Caesar Salad", Price: money.MustParse("8.99", ""), Category: "Salad"},
 	}
 	repo := NewProductRepo(seed)
 
@@ -45,7 +49,7 @@ func TestProductRepo_Behavior(t *testing.T) {
 		{
 			name: "Create new product works",
 			action: func() (any, error) {
-				p := models.Product{ID: "3", Name: "Burger", Price: 10, Category: "FastFood"}
+				p := models.Product{ID: "3", Name: "Burger", Price: money.MustParse("10", ""), Category: "FastFood"}
 				return nil, repo.Create(p)
 			},
 			wantErr:    nil,
@@ -54,7 +58,7 @@ func TestProductRepo_Behavior(t *testing.T) {
 		{
 			name: "Create duplicate fails",
 			action: func() (any, error) {
-				p := models.Product{ID: "1", Name: "Duplicate", Price: 1, Category: "X"}
+				p := models.Product{ID: "1", Name: "Duplicate", Price: money.MustParse("1", ""), Category: "X"}
 				return nil, repo.Create(p)
 			},
 			wantErr: ErrProductExists,
@@ -62,7 +66,7 @@ func TestProductRepo_Behavior(t *testing.T) {
 		{
 			name: "Update existing works",
 			action: func() (any, error) {
-				p := models.Product{ID: "1", Name: "Updated Chicken Waffle", Price: 12.99, Category: "Waffle"}
+				p := models.Product{ID: "1", Name: "Updated Chicken Waffle", Price: money.MustParse("12.99", ""), Category: "Waffle"}
 				return nil, repo.Update(p)
 			},
 			wantErr: nil,
@@ -70,7 +74,7 @@ func TestProductRepo_Behavior(t *testing.T) {
 		{
 			name: "Update missing fails",
 			action: func() (any, error) {
-				p := models.Product{ID: "99", Name: "Ghost", Price: 1}
+				p := models.Product{ID: "99", Name: "Ghost", Price: money.MustParse("1", "")}
 				return nil, repo.Update(p)
 			},
 			wantErr: ErrProductNotFound,
@@ -128,3 +132,70 @@ func TestProductRepo_Behavior(t *testing.T) {
 		})
 	}
 }
+
+func TestProductRepo_Reserve(t *testing.T) {
+	stock := func(n int) *int { return &n }
+	repo := NewProductRepo([]models.Product{
+		{ID: "1", Name: "Chicken Waffle", Stock: stock(3)},
+		{ID: "2", Name: "Caesar Salad", Stock: stock(1)},
+		{ID: "3", Name: "Coffee"},
+		{ID: "4", Name: "Soup", SoldOut: true},
+	})
+	left := func(id string) int {
+		p, _ := repo.GetByID(id)
+		return *p.Stock
+	}
+	items := func(pairs ...any) []models.OrderItem {
+		var out []models.OrderItem
+		for i := 0; i < len(pairs); i += 2 {
+			out = append(out, models.OrderItem{ProductID: pairs[i].(string), Quantity: pairs[i+1].(int)})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		order     string
+		items     []models.OrderItem // nil: release the order
+		wantShort []repository.StockShortage
+		wantLeft  [2]int // products 1 and 2 afterwards
+	}{
+		{name: "uncounted products are unlimited", order: "a", items: items("1", 1, "3", 100), wantLeft: [2]int{2, 1}},
+		{
+			name: "all or nothing", order: "b", items: items("3", 1, "1", 3, "4", 1, "2", 2),
+			wantShort: []repository.StockShortage{
+				{Item: 2, ProductID: "1", Requested: 3, Available: 2},
+				{Item: 3, ProductID: "4", Requested: 1, SoldOut: true},
+				{Item: 4, ProductID: "2", Requested: 2, Available: 1},
+			},
+			wantLeft: [2]int{2, 1},
+		},
+		{name: "changing a reservation takes the difference", order: "a", items: items("1", 3), wantLeft: [2]int{0, 1}},
+		{
+			name: "own units count as available", order: "a", items: items("1", 4),
+			wantShort: []repository.StockShortage{{Item: 1, ProductID: "1", Requested: 4, Available: 3}},
+			wantLeft:  [2]int{0, 1},
+		},
+		{name: "lines of one product add up", order: "a", items: items("1", 2, "2", 1, "1", 1), wantLeft: [2]int{0, 0}},
+		{name: "release", order: "a", wantLeft: [2]int{3, 1}},
+		{name: "release twice", order: "a", wantLeft: [2]int{3, 1}},
+	}
+	for _, tc := range tests {
+		var err error
+		if tc.items == nil {
+			err = repo.Release(tc.order)
+		} else {
+			err = repo.Reserve(tc.order, tc.items)
+		}
+		var se *repository.StockShortageError
+		switch {
+		case tc.wantShort == nil && err != nil:
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		case tc.wantShort != nil && (!errors.As(err, &se) || !reflect.DeepEqual(se.Shortages, tc.wantShort)):
+			t.Fatalf("%s: err = %v, want shortages %+v", tc.name, err, tc.wantShort)
+		}
+		if got := [2]int{left("1"), left("2")}; got != tc.wantLeft {
+			t.Fatalf("%s: stock left = %v, want %v", tc.name, got, tc.wantLeft)
+		}
+	}
+}
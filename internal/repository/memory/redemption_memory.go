@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository"
+)
+
+// redemptionMemoryRepository is a thread-safe in-memory redemption ledger.
+type redemptionMemoryRepository struct {
+	mutex  sync.Mutex
+	byCode map[string][]models.Redemption
+}
+
+// NewRedemptionRepo creates an empty in-memory redemption ledger.
+func NewRedemptionRepo() repository.RedemptionRepository {
+	return &redemptionMemoryRepository{
+		byCode: make(map[string][]models.Redemption),
+	}
+}
+
+var _ repository.RedemptionRepository = (*redemptionMemoryRepository)(nil)
+
+// Reserve checks limits and records the redemption under one lock.
+func (r *redemptionMemoryRepository) Reserve(red models.Redemption, limits repository.RedemptionLimits) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	uses := r.byCode[red.Code]
+	if limits.MaxUses > 0 && len(uses) >= limits.MaxUses {
+		return &repository.RedemptionLimitError{Code: red.Code, Scope: "global", Limit: limits.MaxUses}
+	}
+	if limits.MaxUsesPerKey > 0 {
+		n := 0
+		for _, u := range uses {
+			if u.APIKey == red.APIKey {
+				n++
+			}
+		}
+		if n >= limits.MaxUsesPerKey {
+			return &repository.RedemptionLimitError{Code: red.Code, Scope: "api_key", Limit: limits.MaxUsesPerKey}
+		}
+	}
+
+	if red.RedeemedAt.IsZero() {
+		red.RedeemedAt = time.Now()
+	}
+	r.byCode[red.Code] = append(uses, red)
+	return nil
+}
+
+// Release drops the redemption recorded for orderID, if any.
+func (r *redemptionMemoryRepository) Release(code, orderID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	uses := r.byCode[code]
+	for i, u := range uses {
+		if u.OrderID == orderID {
+			r.byCode[code] = append(uses[:i:i], uses[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// ListByCode returns a copy of the redemptions of code.
+func (r *redemptionMemoryRepository) ListByCode(code string) ([]models.Redemption, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]models.Redemption(nil), r.byCode[code]...), nil
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository"
+)
+
+func TestRedemptionRepo_Limits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		limits  repository.RedemptionLimits
+		keys    []string // one redemption attempt per entry
+		wantOK  int
+		wantErr string // scope of the first limit error
+	}{
+		{"unlimited", repository.RedemptionLimits{}, []string{"a", "a", "b"}, 3, ""},
+		{"single use", repository.RedemptionLimits{MaxUses: 1}, []string{"a", "b"}, 1, "global"},
+		{"global cap", repository.RedemptionLimits{MaxUses: 2}, []string{"a", "b", "c"}, 2, "global"},
+		{"per key cap", repository.RedemptionLimits{MaxUsesPerKey: 1}, []string{"a", "b", "a"}, 2, "api_key"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := NewRedemptionRepo()
+			ok, scope := 0, ""
+			for i, key := range tc.keys {
+				err := repo.Reserve(models.Redemption{Code: "PROMO123", OrderID: fmt.Sprint(i), APIKey: key}, tc.limits)
+				var le *repository.RedemptionLimitError
+				switch {
+				case err == nil:
+					ok++
+				case errors.As(err, &le) && errors.Is(err, repository.ErrRedemptionLimit):
+					if scope == "" {
+						scope = le.Scope
+					}
+				default:
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if ok != tc.wantOK || scope != tc.wantErr {
+				t.Fatalf("ok=%d scope=%q, want ok=%d scope=%q", ok, scope, tc.wantOK, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedemptionRepo_ReleaseFreesUse(t *testing.T) {
+	t.Parallel()
+
+	repo := NewRedemptionRepo()
+	limits := repository.RedemptionLimits{MaxUses: 1}
+	if err := repo.Reserve(models.Redemption{Code: "ONCE1234", OrderID: "o1"}, limits); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	if err := repo.Release("ONCE1234", "o1"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if err := repo.Reserve(models.Redemption{Code: "ONCE1234", OrderID: "o2"}, limits); err != nil {
+		t.Fatalf("reserve after release: %v", err)
+	}
+	got, _ := repo.ListByCode("ONCE1234")
+	if len(got) != 1 || got[0].OrderID != "o2" || got[0].RedeemedAt.IsZero() {
+		t.Fatalf("unexpected ledger %+v", got)
+	}
+}
+
+func TestRedemptionRepo_ConcurrentSingleUse(t *testing.T) {
+	t.Parallel()
+
+	repo := NewRedemptionRepo()
+	var ok atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			red := models.Redemption{Code: "ONCE1234", OrderID: fmt.Sprint(i)}
+			if repo.Reserve(red, repository.RedemptionLimits{MaxUses: 1}) == nil {
+				ok.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if ok.Load() != 1 {
+		t.Fatalf("%d concurrent reservations succeeded, want 1", ok.Load())
+	}
+}
@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
 )
@@ -11,8 +14,114 @@ var (
 	ErrInvalidOrderID = errors.New("invalid order id (must be UUID)")
 	ErrOrderExists    = errors.New("order already exists")
 	ErrOrderNotFound  = errors.New("order not found")
+	ErrInvalidCursor  = errors.New("invalid cursor")
 )
 
+// OrderSort orders a listing by a timestamp; ties are broken by order ID.
+type OrderSort string
+
+const (
+	SortCreatedDesc OrderSort = "-createdAt" // newest first (default)
+	SortCreatedAsc  OrderSort = "createdAt"
+	SortUpdatedDesc OrderSort = "-updatedAt"
+	SortUpdatedAsc  OrderSort = "updatedAt"
+)
+
+// Valid reports whether s is a known sort.
+func (s OrderSort) Valid() bool {
+	switch s {
+	case SortCreatedDesc, SortCreatedAsc, SortUpdatedDesc, SortUpdatedAsc:
+		return true
+	}
+	return false
+}
+
+// Key returns the timestamp o is sorted by.
+func (s OrderSort) Key(o *models.Order) time.Time {
+	if s == SortUpdatedAsc || s == SortUpdatedDesc {
+		return o.UpdatedAt
+	}
+	return o.CreatedAt
+}
+
+// Desc reports whether s sorts newest first.
+func (s OrderSort) Desc() bool { return s == SortCreatedDesc || s == SortUpdatedDesc }
+
+// OrderQuery selects a page of orders. Zero fields don't filter.
+type OrderQuery struct {
+	Statuses []models.OrderStatus // any of these
+	From     time.Time            // CreatedAt >= From
+	To       time.Time            // CreatedAt < To
+	APIKey   string               // placed with this api_key
+
+	Sort  OrderSort
+	Limit int          // page size; must be > 0
+	After *OrderCursor // continue after this position (from a previous page)
+}
+
+// Matches reports whether o passes the query's filters.
+func (q *OrderQuery) Matches(o *models.Order) bool {
+	if len(q.Statuses) > 0 {
+		found := false
+		for _, s := range q.Statuses {
+			found = found || o.Status == s
+		}
+		if !found {
+			return false
+		}
+	}
+	if !q.From.IsZero() && o.CreatedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !o.CreatedAt.Before(q.To) {
+		return false
+	}
+	return q.APIKey == "" || o.APIKey == q.APIKey
+}
+
+// OrderPage is one page of a listing.
+type OrderPage struct {
+	Orders []models.Order
+	Next   *OrderCursor // nil on the last page
+}
+
+// OrderCursor is the position of the last order on a page. Pages continue
+// from it (keyset pagination), so orders placed meanwhile don't shift them.
+type OrderCursor struct {
+	Sort OrderSort `json:"s"`
+	At   time.Time `json:"t"`
+	ID   string    `json:"id"`
+}
+
+// After reports whether o comes after the cursor in its sort order.
+func (c *OrderCursor) After(o *models.Order) bool {
+	at := c.Sort.Key(o)
+	if at.Equal(c.At) {
+		return o.ID > c.ID
+	}
+	return at.After(c.At) != c.Sort.Desc()
+}
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c *OrderCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseOrderCursor decodes a token from Encode. It returns ErrInvalidCursor
+// for anything else.
+func ParseOrderCursor(token string) (*OrderCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c OrderCursor
+	if err := json.Unmarshal(b, &c); err != nil || !c.Sort.Valid() || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
 // OrderRepository defines persistence for orders.
 type OrderRepository interface {
 	// CreateOrder persists a new order and returns the stored copy.
@@ -20,4 +129,12 @@ type OrderRepository interface {
 
 	// FindByID retrieves an order by its ID.
 	FindByID(id string) (*models.Order, error)
+
+	// UpdateOrder applies update to a copy of the stored order and saves the
+	// result, atomically with respect to other updates of the same order. An
+	// error from update aborts the change and is returned as is.
+	UpdateOrder(id string, update func(*models.Order) error) (*models.Order, error)
+
+	// ListOrders returns the page of orders matching q, sorted by q.Sort.
+	ListOrders(q OrderQuery) (OrderPage, error)
 }
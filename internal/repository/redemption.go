@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+)
+
+// ErrRedemptionLimit is matched (errors.Is) by every *RedemptionLimitError.
+var ErrRedemptionLimit = errors.New("redemption limit reached")
+
+// RedemptionLimits caps how often a code may be redeemed. Zero means unlimited.
+type RedemptionLimits struct {
+	MaxUses       int // across all customers
+	MaxUsesPerKey int // per API key
+}
+
+// Limited reports whether any cap is set.
+func (l RedemptionLimits) Limited() bool { return l.MaxUses > 0 || l.MaxUsesPerKey > 0 }
+
+// RedemptionLimitError reports which cap a redemption would exceed.
+type RedemptionLimitError struct {
+	Code  string `json:"code"`
+	Scope string `json:"scope"` // "global" or "api_key"
+	Limit int    `json:"limit"`
+}
+
+func (e *RedemptionLimitError) Error() string {
+	if e.Limit == 1 && e.Scope == "global" {
+		return fmt.Sprintf("promo code %s has already been used", e.Code)
+	}
+	if e.Scope == "api_key" {
+		return fmt.Sprintf("promo code %s can be used at most %d times per customer", e.Code, e.Limit)
+	}
+	return fmt.Sprintf("promo code %s has reached its limit of %d uses", e.Code, e.Limit)
+}
+
+func (e *RedemptionLimitError) Is(target error) bool { return target == ErrRedemptionLimit }
+
+// RedemptionRepository is the ledger of promo code uses.
+type RedemptionRepository interface {
+	// Reserve records red if it stays within limits, checking and recording
+	// atomically so concurrent callers cannot both take the last use. It
+	// returns a *RedemptionLimitError otherwise.
+	Reserve(red models.Redemption, limits RedemptionLimits) error
+
+	// Release removes the redemption for (code, orderID), e.g. when the order
+	// could not be saved. Releasing an unknown redemption is a no-op.
+	Release(code, orderID string) error
+
+	// ListByCode returns the redemptions of code, oldest first.
+	ListByCode(code string) ([]models.Redemption, error)
+}
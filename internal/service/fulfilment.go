@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+)
+
+// Length limits (in characters) of the free-text order fields.
+const (
+	maxNotesLength        = 500
+	maxNameLength         = 100
+	maxLineLength         = 200
+	maxCityLength         = 100
+	maxPostalLength       = 20
+	maxInstructionsLength = 200
+	maxTableLength        = 20
+	maxPhoneLength        = 25
+	minPhoneDigits        = 7
+	maxPhoneDigits        = 15 // E.164
+)
+
+// orderDetails is the validated, trimmed fulfilment, contact and notes of an
+// order request.
+type orderDetails struct {
+	fulfilment models.Fulfilment
+	customer   models.Customer
+	notes      string
+}
+
+// validateDetails checks the request's fulfilment against the rules of its
+// type (delivery needs an address and phone, dine-in a table, and neither
+// accepts the other's fields) and the length limits of all free text.
+func validateDetails(req models.OrderRequest) (*orderDetails, error) {
+	d := &orderDetails{fulfilment: models.Fulfilment{Type: req.Fulfilment.Type}}
+	if d.fulfilment.Type == "" {
+		d.fulfilment.Type = models.FulfilmentPickup
+	}
+
+	var err error
+	text := func(field, value string, max int, required bool) string {
+		value = strings.TrimSpace(value)
+		switch {
+		case err != nil:
+		case required && value == "":
+			err = NewValidationError(field + " is required")
+		case utf8.RuneCountInString(value) > max:
+			err = NewValidationError(fmt.Sprintf("%s must be at most %d characters", field, max))
+		case strings.IndexFunc(value, isControl) >= 0:
+			err = NewValidationError(field + " must not contain control characters")
+		}
+		return value
+	}
+
+	f := req.Fulfilment
+	switch d.fulfilment.Type {
+	case models.FulfilmentDelivery:
+		if f.Address == nil {
+			return nil, NewValidationError("fulfilment.address is required for delivery")
+		}
+		d.fulfilment.Address = &models.Address{
+			Line1:        text("fulfilment.address.line1", f.Address.Line1, maxLineLength, true),
+			Line2:        text("fulfilment.address.line2", f.Address.Line2, maxLineLength, false),
+			City:         text("fulfilment.address.city", f.Address.City, maxCityLength, true),
+			PostalCode:   text("fulfilment.address.postalCode", f.Address.PostalCode, maxPostalLength, true),
+			Instructions: text("fulfilment.address.instructions", f.Address.Instructions, maxInstructionsLength, false),
+		}
+		if err == nil && strings.TrimSpace(req.Customer.Phone) == "" {
+			err = NewValidationError("customer.phone is required for delivery")
+		}
+	case models.FulfilmentDineIn:
+		d.fulfilment.Table = text("fulfilment.table", f.Table, maxTableLength, true)
+	case models.FulfilmentPickup:
+	default:
+		return nil, NewValidationError(fmt.Sprintf("unknown fulfilment type %q (want %s, %s or %s)",
+			f.Type, models.FulfilmentPickup, models.FulfilmentDelivery, models.FulfilmentDineIn))
+	}
+	if f.Address != nil && d.fulfilment.Type != models.FulfilmentDelivery {
+		return nil, NewValidationError(fmt.Sprintf("fulfilment.address is not used for %s orders", d.fulfilment.Type))
+	}
+	if f.Table != "" && d.fulfilment.Type != models.FulfilmentDineIn {
+		return nil, NewValidationError(fmt.Sprintf("fulfilment.table is not used for %s orders", d.fulfilment.Type))
+	}
+
+	d.customer.Name = text("customer.name", req.Customer.Name, maxNameLength, false)
+	d.customer.Phone = text("customer.phone", req.Customer.Phone, maxPhoneLength, false)
+	d.notes = text("notes", req.Notes, maxNotesLength, false)
+	if err != nil {
+		return nil, err
+	}
+	if d.customer.Phone != "" && !validPhone(d.customer.Phone) {
+		return nil, NewValidationError(fmt.Sprintf("customer.phone must have %d to %d digits and only +, spaces, dashes, dots or parentheses besides",
+			minPhoneDigits, maxPhoneDigits))
+	}
+	return d, nil
+}
+
+// validPhone accepts international and local formats such as
+// "+44 20 7946 0958" or "(555) 123-4567".
+func validPhone(s string) bool {
+	digits := 0
+	for i, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r == '+' && i == 0:
+		case strings.ContainsRune(" -.()", r):
+		default:
+			return false
+		}
+	}
+	return digits >= minPhoneDigits && digits <= maxPhoneDigits
+}
+
+// isControl reports control characters other than line breaks and tabs.
+func isControl(r rune) bool { return unicode.IsControl(r) && r != '\n' && r != '\t' }
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/testutil"
+)
+
+func TestOrderService_PlaceOrder_Fulfilment(t *testing.T) {
+	t.Parallel()
+
+	address := &models.Address{Line1: " 1 Main St ", City: "Springfield", PostalCode: "12345"}
+	phone := models.Customer{Name: "Sam", Phone: "+1 (555) 123-4567"}
+
+	tests := []struct {
+		name           string
+		req            models.OrderRequest
+		wantFulfilment models.Fulfilment
+		wantErr        string
+	}{
+		{
+			name:           "pickup by default",
+			req:            models.OrderRequest{Notes: "  extra napkins\nplease "},
+			wantFulfilment: models.Fulfilment{Type: models.FulfilmentPickup},
+		},
+		{
+			name:           "delivery",
+			req:            models.OrderRequest{Fulfilment: models.Fulfilment{Type: models.FulfilmentDelivery, Address: address}, Customer: phone},
+			wantFulfilment: models.Fulfilment{Type: models.FulfilmentDelivery, Address: &models.Address{Line1: "1 Main St", City: "Springfield", PostalCode: "12345"}},
+		},
+		{
+			name:           "dine in",
+			req:            models.OrderRequest{Fulfilment: models.Fulfilment{Type: models.FulfilmentDineIn, Table: "12"}},
+			wantFulfilment: models.Fulfilment{Type: models.FulfilmentDineIn, Table: "12"},
+		},
+		{
+			name:    "delivery without address",
+			req:     models.OrderRequest{Fulfilment: models.Fulfilment{Type: models.FulfilmentDelivery}, Customer: phone},
+			wantErr: "fulfilment.address is required for delivery",
+		},
+		{
+			name:    "delivery without city",
+			req:     models.OrderRequest{Fulfilment: models.Fulfilment{Type: models.FulfilmentDelivery, Address: &models.Address{Line1: "1 Main St", PostalCode: "1"}}, Customer: phone},
+			wantErr: "fulfilment.address.city is required",
+		},
+		{
+			name:    "delivery without phone",
+			req:     models.OrderRequest{Fulfilment: models.Fulfilment{Type: models.FulfilmentDelivery, Address: address}},
+			wantErr: "customer.phone is required for delivery",
+		},
+		{
+			name:    "dine in without table",
+			req:     models.OrderRequest{Fulfilment: models.Fulfilment{Type: models.FulfilmentDineIn, Table: "  "}},
+			wantErr: "fulfilment.table is required",
+		},
+		{
+			name:    "address on pickup",
+			req:     models.OrderRequest{Fulfilment: models.Fulfilment{Address: address}},
+			wantErr: "fulfilment.address is not used for pickup orders",
+		},
+		{
+			name:    "table on delivery",
+			req:     models.OrderRequest{Fulfilment: models.Fulfilment{Type: models.FulfilmentDelivery, Address: address, Table: "4"}, Customer: phone},
+			wantErr: "fulfilment.table is not used for delivery orders",
+		},
+		{
+			name:    "unknown type",
+			req:     models.OrderRequest{Fulfilment: models.Fulfilment{Type: "drone"}},
+			wantErr: `unknown fulfilment type "drone" (want pickup, delivery or dine_in)`,
+		},
+		{
+			name:    "notes too long",
+			req:     models.OrderRequest{Notes: strings.Repeat("é", 501)},
+			wantErr: "notes must be at most 500 characters",
+		},
+		{
+			name:    "control characters",
+			req:     models.OrderRequest{Customer: models.Customer{Name: "Sam\x00"}},
+			wantErr: "customer.name must not contain control characters",
+		},
+		{
+			name:    "bad phone",
+			req:     models.OrderRequest{Customer: models.Customer{Phone: "call me"}},
+			wantErr: "customer.phone must have 7 to 15 digits and only +, spaces, dashes, dots or parentheses besides",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+			svc := NewOrderService(ps, testutil.NewOrderRepoStub(), &testutil.ValidatorStub{Valid: true})
+
+			tc.req.Items = []models.OrderItem{{ProductID: "1", Quantity: 1}}
+			got, err := svc.PlaceOrder(tc.req)
+			if tc.wantErr != "" {
+				if !IsValidationError(err) || err.Error() != tc.wantErr {
+					t.Fatalf("want validation error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got.Fulfilment, tc.wantFulfilment) {
+				t.Fatalf("fulfilment = %+v, want %+v", got.Fulfilment, tc.wantFulfilment)
+			}
+			if got.Customer.Name != strings.TrimSpace(tc.req.Customer.Name) || got.Notes != strings.TrimSpace(tc.req.Notes) {
+				t.Fatalf("details not stored: %+v %q", got.Customer, got.Notes)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
+)
+
+// resolveOptions checks the options selected for the item at position pos
+// against p's option groups (existence, no repeats, each group's min/max)
+// and returns them priced, in item order, with the resulting unit price.
+func resolveOptions(pos int, it models.OrderItem, p models.Product) ([]models.PricedOption, money.Money, error) {
+	fail := func(format string, args ...any) ([]models.PricedOption, money.Money, error) {
+		msg := fmt.Sprintf("item %d: ", pos) + fmt.Sprintf(format, args...)
+		return nil, money.Money{}, NewValidationError(msg)
+	}
+
+	groups := make(map[string]*models.OptionGroup, len(p.Options))
+	for i := range p.Options {
+		groups[p.Options[i].ID] = &p.Options[i]
+	}
+
+	unit := p.Price
+	counts := make(map[string]int, len(p.Options))
+	seen := make(map[models.SelectedOption]bool, len(it.Options))
+	var priced []models.PricedOption
+	for _, sel := range it.Options {
+		g, ok := groups[sel.Group]
+		if !ok {
+			return fail("product %s has no option group %q", p.ID, sel.Group)
+		}
+		opt, ok := findOption(g, sel.Option)
+		if !ok {
+			return fail("%s has no option %q", g.Name, sel.Option)
+		}
+		if seen[sel] {
+			return fail("option %q of %s is selected more than once", sel.Option, g.Name)
+		}
+		if !opt.Price.SameCurrency(p.Price) {
+			return fail("option %q is priced in %s, product %s in %s", sel.Option, opt.Price.Currency, p.ID, p.Price.Currency)
+		}
+		seen[sel] = true
+		counts[g.ID]++
+		unit = unit.Add(opt.Price)
+		priced = append(priced, models.PricedOption{Group: g.ID, Option: opt.ID, Name: opt.Name, Price: opt.Price})
+	}
+
+	for _, g := range p.Options {
+		switch n := counts[g.ID]; {
+		case n < g.Min:
+			return fail("choose at least %d of %s", g.Min, g.Name)
+		case g.Max > 0 && n > g.Max:
+			return fail("choose at most %d of %s", g.Max, g.Name)
+		}
+	}
+	if unit.IsNegative() {
+		return fail("options make the price of product %s negative", p.ID)
+	}
+	return priced, unit, nil
+}
+
+func findOption(g *models.OptionGroup, id string) (models.Option, bool) {
+	for _, o := range g.Options {
+		if o.ID == id {
+			return o, true
+		}
+	}
+	return models.Option{}, false
+}
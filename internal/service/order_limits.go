@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
+)
+
+// OrderLimits normalizes and bounds the lines of an order. Zero values mean
+// no limit.
+type OrderLimits struct {
+	MergeDuplicates bool        // combine lines of the same product into its first line
+	MaxQuantity     int         // per line, after merging
+	MaxItems        int         // distinct products per order
+	MaxTotal        money.Money // order total after discounts and tax
+}
+
+// WithOrderLimits normalizes every placed or modified order with limits.
+func WithOrderLimits(limits OrderLimits) OrderOption {
+	return func(s *OrderService) { s.limits = limits }
+}
+
+// normalize merges duplicate lines (if enabled: same product and options) and checks the quantity and
+// item limits. Items must have positive quantities of at most MaxLineQuantity;
+// merged lines are held to MaxLineQuantity as well. Errors name the request's item positions, including every
+// line that was merged into the offending one.
+func (l OrderLimits) normalize(items []models.OrderItem) ([]models.OrderItem, error) {
+	lines := make([]models.OrderItem, 0, len(items))
+	positions := make([][]int, 0, len(items)) // 1-based request positions of each line
+	first := make(map[string]int, len(items)) // lineKey → index in lines
+	products := make(map[string]bool, len(items))
+	for i, it := range items {
+		key := lineKey(it)
+		if j, seen := first[key]; seen && l.MergeDuplicates {
+			positions[j] = append(positions[j], i+1)
+			// compared before adding so the sum can't wrap around
+			if it.Quantity > MaxLineQuantity-lines[j].Quantity {
+				return nil, NewValidationError(fmt.Sprintf("%s: merged quantity exceeds the maximum of %d per line",
+					itemLabel(positions[j]), MaxLineQuantity))
+			}
+			lines[j].Quantity += it.Quantity
+			continue
+		}
+		if !products[it.ProductID] {
+			if l.MaxItems > 0 && len(products) == l.MaxItems {
+				return nil, NewValidationError(fmt.Sprintf("item %d: an order may contain at most %d different products", i+1, l.MaxItems))
+			}
+			products[it.ProductID] = true
+		}
+		if _, seen := first[key]; !seen {
+			first[key] = len(lines)
+		}
+		lines = append(lines, it)
+		positions = append(positions, []int{i + 1})
+	}
+
+	if l.MaxQuantity > 0 {
+		for j, it := range lines {
+			if it.Quantity > l.MaxQuantity {
+				return nil, NewValidationError(fmt.Sprintf("%s: quantity %d exceeds the maximum of %d per line",
+					itemLabel(positions[j]), it.Quantity, l.MaxQuantity))
+			}
+		}
+	}
+	return lines, nil
+}
+
+// checkTotal rejects a priced order above MaxTotal. A cap in another
+// currency than the order's does not apply.
+func (l OrderLimits) checkTotal(p models.Pricing) error {
+	if !l.MaxTotal.IsPositive() || !l.MaxTotal.SameCurrency(p.Total) {
+		return nil
+	}
+	if p.Total.Cmp(l.MaxTotal) > 0 {
+		return NewValidationError(fmt.Sprintf("order total %s exceeds the maximum of %s", p.Total, l.MaxTotal))
+	}
+	return nil
+}
+
+// lineKey identifies the lines that can be merged: same product, same
+// options in any order.
+func lineKey(it models.OrderItem) string {
+	opts := make([]string, len(it.Options))
+	for i, o := range it.Options {
+		opts[i] = strconv.Quote(o.Group) + "/" + strconv.Quote(o.Option)
+	}
+	slices.Sort(opts)
+	return strconv.Quote(it.ProductID) + " " + strings.Join(opts, ",")
+}
+
+// itemLabel is "item 2", or "items 1 and 3" for merged lines.
+func itemLabel(positions []int) string {
+	if len(positions) == 1 {
+		return "item " + strconv.Itoa(positions[0])
+	}
+	s := make([]string, len(positions))
+	for i, p := range positions {
+		s[i] = strconv.Itoa(p)
+	}
+	return "items " + strings.Join(s[:len(s)-1], ", ") + " and " + s[len(s)-1]
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+)
+
+// EditConflictError reports a modification that cannot be applied to the
+// order as it is now: it has left the editable status, or it is no longer
+// at the revision the client expected.
+type EditConflictError struct {
+	OrderID  string             `json:"orderId"`
+	Status   models.OrderStatus `json:"status"`
+	Revision int                `json:"revision"`
+	stale    bool
+}
+
+func (e *EditConflictError) Error() string {
+	if e.stale {
+		return fmt.Sprintf("order was modified meanwhile and is now at revision %d", e.Revision)
+	}
+	return fmt.Sprintf("order is %s and can no longer be modified", e.Status)
+}
+
+// Editable reports whether an order in status st may still be modified:
+// only until the restaurant accepts it.
+func Editable(st models.OrderStatus) bool { return st == models.StatusPlaced }
+
+// ModifyOrder applies mod to the items of order id, placed with apiKey, and
+// re-runs the checks of PlaceOrderContext (products, promo code, discount
+// rule) on the result, repricing it and appending a revision. The new version is computed outside
+// the repository's lock and only saved if the order has not changed status
+// or revision in the meantime; its stock reservation is adjusted along with it.
+func (s *OrderService) ModifyOrder(ctx context.Context, id, apiKey string, mod models.OrderModification) (*models.Order, error) {
+	if len(mod.Add)+len(mod.Remove)+len(mod.Update) == 0 {
+		return nil, NewValidationError("modification must add, remove or update at least one item")
+	}
+
+	current, err := s.GetOwnOrder(id, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEditable(current, mod.Revision); err != nil {
+		return nil, err
+	}
+
+	items, err := modifyItems(current.Items, mod)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.buildLines(ctx, current.CouponCode, items)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.orderRepo.UpdateOrder(id, func(o *models.Order) error {
+		if err := checkEditable(o, current.Revision); err != nil {
+			return err
+		}
+		if err := s.reserveStock(o.ID, lines.items); err != nil {
+			return err
+		}
+		now := s.now()
+		lines.apply(o)
+		changes := mod
+		changes.Revision = 0
+		o.Revision++
+		o.Revisions = append(o.Revisions, models.OrderRevision{
+			Revision: o.Revision, At: now, Items: o.Items, Total: o.Pricing.Total, Changes: &changes,
+		})
+		o.UpdatedAt = now
+		return nil
+	})
+}
+
+// checkEditable returns an *EditConflictError unless o is editable and, when
+// revision is set, still at it.
+func checkEditable(o *models.Order, revision int) error {
+	if !Editable(o.Status) {
+		return &EditConflictError{OrderID: o.ID, Status: o.Status, Revision: o.Revision}
+	}
+	if revision != 0 && o.Revision != revision {
+		return &EditConflictError{OrderID: o.ID, Status: o.Status, Revision: o.Revision, stale: true}
+	}
+	return nil
+}
+
+// modifyItems returns items with mod applied. Removed products and updated
+// lines must be in the order; new lines are validated with the rest by
+// buildLines. An update addresses the lines with its product and options,
+// the key PlaceOrder merges by, so other option variants of the product are
+// left alone; without options it addresses the product's lines only if they
+// all share the same ones.
+func modifyItems(items []models.OrderItem, mod models.OrderModification) ([]models.OrderItem, error) {
+	out := append([]models.OrderItem(nil), items...)
+	index := func(productID string) int {
+		for i, it := range out {
+			if it.ProductID == productID {
+				return i
+			}
+		}
+		return -1
+	}
+	drop := func(match func(models.OrderItem) bool) {
+		kept := out[:0]
+		for _, it := range out {
+			if !match(it) {
+				kept = append(kept, it)
+			}
+		}
+		out = kept
+	}
+
+	for i, pid := range mod.Remove {
+		if index(pid) < 0 {
+			return nil, NewValidationError(fmt.Sprintf("remove %d: product %s is not in the order", i+1, pid))
+		}
+		drop(func(it models.OrderItem) bool { return it.ProductID == pid })
+	}
+	for i, up := range mod.Update {
+		if up.Quantity < 0 {
+			return nil, NewValidationError(fmt.Sprintf("update %d: quantity must be >= 0", i+1))
+		}
+		at := index(up.ProductID)
+		if at < 0 {
+			return nil, NewValidationError(fmt.Sprintf("update %d: product %s is not in the order", i+1, up.ProductID))
+		}
+		key := lineKey(up)
+		if up.Options == nil {
+			key = lineKey(out[at])
+			for _, it := range out[at:] {
+				if it.ProductID == up.ProductID && lineKey(it) != key {
+					return nil, NewValidationError(fmt.Sprintf(
+						"update %d: product %s is in the order with different options; give the line's options", i+1, up.ProductID))
+				}
+			}
+		}
+		at = slices.IndexFunc(out, func(it models.OrderItem) bool { return lineKey(it) == key })
+		if at < 0 {
+			return nil, NewValidationError(fmt.Sprintf("update %d: product %s with these options is not in the order", i+1, up.ProductID))
+		}
+		// the update sets the line's total quantity: keep one line where
+		// the first match was, with the options it already has
+		line := out[at]
+		line.Quantity = up.Quantity
+		drop(func(it models.OrderItem) bool { return lineKey(it) == key })
+		if up.Quantity > 0 {
+			out = slices.Insert(out, at, line)
+		}
+	}
+	return append(out, mod.Add...), nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/discount"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository/memory"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/testutil"
+)
+
+func TestOrderService_ModifyOrder(t *testing.T) {
+	t.Parallel()
+
+	engine, err := discount.NewEngine([]discount.Rule{
+		{ID: "waffles", Code: "WAFFLE20", Type: discount.TypePercentage, Percent: 20, Category: "Waffle"},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	clock := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+	svc := NewOrderService(ps, memory.NewOrderRepo(), &testutil.ValidatorStub{Valid: true},
+		WithDiscounts(engine), WithClock(func() time.Time { return clock }))
+
+	placed, err := svc.PlaceOrder(models.OrderRequest{
+		CouponCode: "WAFFLE20",
+		Items:      []models.OrderItem{{ProductID: "1", Quantity: 2}, {ProductID: "3", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if placed.Revision != 1 || len(placed.Revisions) != 1 {
+		t.Fatalf("new order revisions = %d %+v", placed.Revision, placed.Revisions)
+	}
+
+	tests := []struct {
+		name         string
+		mod          models.OrderModification
+		wantItems    []models.OrderItem // nil: expect an error
+		wantDiscount string
+		conflict     bool // expect *EditConflictError rather than a validation error
+	}{
+		{
+			name:         "change quantity",
+			mod:          models.OrderModification{Update: []models.OrderItem{{ProductID: "1", Quantity: 1}}, Revision: 1},
+			wantItems:    []models.OrderItem{{ProductID: "1", Quantity: 1}, {ProductID: "3", Quantity: 1}},
+			wantDiscount: "2.60",
+		},
+		{
+			name:         "swap an item",
+			mod:          models.OrderModification{Remove: []string{"3"}, Add: []models.OrderItem{{ProductID: "2", Quantity: 1}}},
+			wantItems:    []models.OrderItem{{ProductID: "1", Quantity: 1}, {ProductID: "2", Quantity: 1}},
+			wantDiscount: "4.60",
+		},
+		{name: "stale revision", mod: models.OrderModification{Remove: []string{"2"}, Revision: 1}, conflict: true},
+		{name: "nothing to change", mod: models.OrderModification{Revision: 3}},
+		{name: "remove missing product", mod: models.OrderModification{Remove: []string{"3"}}},
+		{name: "unknown product", mod: models.OrderModification{Add: []models.OrderItem{{ProductID: "99", Quantity: 1}}}},
+		{name: "negative quantity", mod: models.OrderModification{Update: []models.OrderItem{{ProductID: "1", Quantity: -1}}}},
+		{name: "promo no longer applies", mod: models.OrderModification{Update: []models.OrderItem{{ProductID: "1"}, {ProductID: "2"}}, Add: []models.OrderItem{{ProductID: "3", Quantity: 1}}}},
+		{name: "empty order", mod: models.OrderModification{Remove: []string{"1", "2"}}},
+	}
+	for _, tc := range tests {
+		clock = clock.Add(time.Minute)
+		before, _ := svc.GetOrder(placed.ID)
+		got, err := svc.ModifyOrder(context.Background(), placed.ID, "", tc.mod)
+		if tc.wantItems == nil {
+			var ee *EditConflictError
+			if errors.As(err, &ee) != tc.conflict || (!tc.conflict && !IsValidationError(err)) {
+				t.Fatalf("%s: err = %v, want conflict %v", tc.name, err, tc.conflict)
+			}
+			if after, _ := svc.GetOrder(placed.ID); after.Revision != before.Revision {
+				t.Fatalf("%s: failed modification was saved", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(got.Items, tc.wantItems) {
+			t.Fatalf("%s: items = %+v, want %+v", tc.name, got.Items, tc.wantItems)
+		}
+		if got.DiscountTotal == nil || got.DiscountTotal.Decimal() != tc.wantDiscount {
+			t.Fatalf("%s: discountTotal = %v, want %s", tc.name, got.DiscountTotal, tc.wantDiscount)
+		}
+		last := got.Revisions[len(got.Revisions)-1]
+		if got.Revision != before.Revision+1 || last.Revision != got.Revision || last.Changes == nil ||
+			last.Total != got.Pricing.Total || !last.At.Equal(clock) || !got.UpdatedAt.Equal(clock) {
+			t.Fatalf("%s: revision not recorded: %+v", tc.name, got.Revisions)
+		}
+	}
+
+	if _, err := svc.AdvanceOrder(placed.ID, "", models.StatusAccepted, ""); err != nil {
+		t.Fatalf("AdvanceOrder: %v", err)
+	}
+	_, err = svc.ModifyOrder(context.Background(), placed.ID, "", models.OrderModification{Add: []models.OrderItem{{ProductID: "2", Quantity: 1}}})
+	var ee *EditConflictError
+	if !errors.As(err, &ee) || ee.Status != models.StatusAccepted {
+		t.Fatalf("accepted order modified: %v", err)
+	}
+}
+
+func TestModifyItems_Options(t *testing.T) {
+	t.Parallel()
+
+	opt := func(group, option string) models.SelectedOption {
+		return models.SelectedOption{Group: group, Option: option}
+	}
+	waffle := func(qty int, opts ...models.SelectedOption) models.OrderItem {
+		return models.OrderItem{ProductID: "1", Quantity: qty, Options: opts}
+	}
+	items := []models.OrderItem{
+		waffle(1, opt("size", "large"), opt("extras", "cream")),
+		{ProductID: "3", Quantity: 1},
+		waffle(2, opt("size", "regular")),
+	}
+
+	tests := []struct {
+		name    string
+		mod     models.OrderModification
+		want    []models.OrderItem
+		wantErr string
+	}{
+		{
+			name: "update one variant",
+			mod:  models.OrderModification{Update: []models.OrderItem{waffle(3, opt("extras", "cream"), opt("size", "large"))}},
+			want: []models.OrderItem{waffle(3, opt("size", "large"), opt("extras", "cream")), items[1], items[2]},
+		},
+		{
+			name: "remove one variant",
+			mod:  models.OrderModification{Update: []models.OrderItem{waffle(0, opt("size", "regular"))}},
+			want: []models.OrderItem{items[0], items[1]},
+		},
+		{
+			name: "no options once the product has one variant",
+			mod:  models.OrderModification{Update: []models.OrderItem{waffle(0, opt("size", "large"), opt("extras", "cream")), waffle(4)}},
+			want: []models.OrderItem{items[1], waffle(4, opt("size", "regular"))},
+		},
+		{
+			name: "product without options",
+			mod:  models.OrderModification{Update: []models.OrderItem{{ProductID: "3", Quantity: 2, Options: []models.SelectedOption{}}}},
+			want: []models.OrderItem{items[0], {ProductID: "3", Quantity: 2}, items[2]},
+		},
+		{
+			name:    "no options with several variants",
+			mod:     models.OrderModification{Update: []models.OrderItem{waffle(1)}},
+			wantErr: "update 1: product 1 is in the order with different options; give the line's options",
+		},
+		{
+			name:    "variant not in the order",
+			mod:     models.OrderModification{Update: []models.OrderItem{waffle(1, opt("size", "large"))}},
+			wantErr: "update 1: product 1 with these options is not in the order",
+		},
+		{
+			name: "remove drops every variant",
+			mod:  models.OrderModification{Remove: []string{"1"}},
+			want: []models.OrderItem{items[1]},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := modifyItems(items, tc.mod)
+			if tc.wantErr != "" {
+				if !IsValidationError(err) || err.Error() != tc.wantErr {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("modifyItems: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("items = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
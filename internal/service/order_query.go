@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository"
+)
+
+// Page sizes for ListOrders.
+const (
+	DefaultOrderPageSize = 20
+	MaxOrderPageSize     = 100
+)
+
+// OrderListOptions filters, sorts and pages ListOrders. Zero values mean
+// "any" / defaults.
+type OrderListOptions struct {
+	Statuses []models.OrderStatus
+	From, To time.Time // creation time range [From, To)
+	APIKey   string    // only orders placed with this api_key ("" = all; API callers always set it)
+	Sort     repository.OrderSort
+	Limit    int
+	Cursor   string // nextCursor of the previous page
+}
+
+// GetOrder returns order id. Malformed and unknown IDs come back as
+// repository.ErrInvalidOrderID and repository.ErrOrderNotFound.
+func (s *OrderService) GetOrder(id string) (*models.Order, error) {
+	return s.orderRepo.FindByID(id)
+}
+
+// GetOwnOrder is GetOrder for the caller holding apiKey: an order placed
+// with another key is reported as repository.ErrOrderNotFound, so its
+// customer details stay private.
+func (s *OrderService) GetOwnOrder(id, apiKey string) (*models.Order, error) {
+	o, err := s.orderRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOwner(o, apiKey); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// checkOwner returns repository.ErrOrderNotFound unless o was placed with
+// apiKey. Every operation on an order by ID goes through it, so other
+// callers can neither see nor change the order.
+func checkOwner(o *models.Order, apiKey string) error {
+	if o.APIKey != apiKey {
+		return repository.ErrOrderNotFound
+	}
+	return nil
+}
+
+// ListOrders returns one page of orders. The cursor must come from a
+// listing with the same sort.
+func (s *OrderService) ListOrders(opts OrderListOptions) (*models.OrderList, error) {
+	q := repository.OrderQuery{
+		Statuses: opts.Statuses,
+		From:     opts.From,
+		To:       opts.To,
+		APIKey:   opts.APIKey,
+		Sort:     opts.Sort,
+		Limit:    opts.Limit,
+	}
+	for _, st := range q.Statuses {
+		if !ValidStatus(st) {
+			return nil, NewValidationError(fmt.Sprintf("unknown order status %q", st))
+		}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return nil, NewValidationError("from must be before to")
+	}
+	if q.Sort == "" {
+		q.Sort = repository.SortCreatedDesc
+	}
+	if !q.Sort.Valid() {
+		return nil, NewValidationError(fmt.Sprintf("unknown sort %q (want createdAt, -createdAt, updatedAt or -updatedAt)", q.Sort))
+	}
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultOrderPageSize
+	case q.Limit < 0 || q.Limit > MaxOrderPageSize:
+		return nil, NewValidationError(fmt.Sprintf("limit must be between 1 and %d", MaxOrderPageSize))
+	}
+	if opts.Cursor != "" {
+		c, err := repository.ParseOrderCursor(opts.Cursor)
+		if err != nil || c.Sort != q.Sort {
+			return nil, NewValidationError("invalid cursor")
+		}
+		q.After = c
+	}
+
+	page, err := s.orderRepo.ListOrders(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+	list := &models.OrderList{Orders: page.Orders}
+	if page.Next != nil {
+		list.NextCursor = page.Next.Encode()
+	}
+	return list, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository/memory"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/testutil"
+)
+
+func TestOrderService_ListOrders(t *testing.T) {
+	t.Parallel()
+
+	clock := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+	svc := NewOrderService(ps, memory.NewOrderRepo(), &testutil.ValidatorStub{Valid: true},
+		WithClock(func() time.Time { return clock }))
+	for i := 0; i < 3; i++ {
+		clock = clock.Add(time.Minute)
+		key := "alice"
+		if i == 2 {
+			key = "bob"
+		}
+		if _, err := svc.PlaceOrder(models.OrderRequest{APIKey: key, Items: []models.OrderItem{{ProductID: "1", Quantity: 1}}}); err != nil {
+			t.Fatalf("PlaceOrder: %v", err)
+		}
+	}
+	first, err := svc.ListOrders(OrderListOptions{Limit: 1})
+	if err != nil || len(first.Orders) != 1 || first.NextCursor == "" {
+		t.Fatalf("first page = %+v, %v", first, err)
+	}
+
+	tests := []struct {
+		name      string
+		opts      OrderListOptions
+		wantCount int
+		wantErr   bool // ValidationError
+	}{
+		{name: "defaults", opts: OrderListOptions{}, wantCount: 3},
+		{name: "by api key", opts: OrderListOptions{APIKey: "alice"}, wantCount: 2},
+		{name: "next page", opts: OrderListOptions{Limit: 5, Cursor: first.NextCursor}, wantCount: 2},
+		{name: "unknown status", opts: OrderListOptions{Statuses: []models.OrderStatus{"eaten"}}, wantErr: true},
+		{name: "unknown sort", opts: OrderListOptions{Sort: "price"}, wantErr: true},
+		{name: "limit too large", opts: OrderListOptions{Limit: MaxOrderPageSize + 1}, wantErr: true},
+		{name: "empty range", opts: OrderListOptions{From: clock, To: clock}, wantErr: true},
+		{name: "garbage cursor", opts: OrderListOptions{Cursor: "!!"}, wantErr: true},
+		{name: "cursor from another sort", opts: OrderListOptions{Sort: repository.SortUpdatedAsc, Cursor: first.NextCursor}, wantErr: true},
+	}
+	for _, tc := range tests {
+		list, err := svc.ListOrders(tc.opts)
+		if tc.wantErr {
+			if !IsValidationError(err) {
+				t.Errorf("%s: expected validation error, got %v", tc.name, err)
+			}
+			continue
+		}
+		if err != nil || len(list.Orders) != tc.wantCount {
+			t.Errorf("%s: got %d orders (%v), want %d", tc.name, len(list.Orders), err, tc.wantCount)
+		}
+	}
+}
+
+func TestOrderService_GetOwnOrder(t *testing.T) {
+	t.Parallel()
+
+	products := testutil.SeedProducts()
+	stock := 5
+	products[0].Stock = &stock
+	ps := NewProductService(testutil.NewProductRepoStub(products))
+	svc := NewOrderService(ps, memory.NewOrderRepo(), &testutil.ValidatorStub{Valid: true})
+	placed, err := svc.PlaceOrder(models.OrderRequest{APIKey: "alice", Items: []models.OrderItem{{ProductID: "1", Quantity: 1}}})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if placed.Products[0].Stock != nil {
+		t.Fatalf("order snapshot exposes stock: %+v", placed.Products[0])
+	}
+
+	if got, err := svc.GetOwnOrder(placed.ID, "alice"); err != nil || got.ID != placed.ID {
+		t.Fatalf("GetOwnOrder(owner) = %+v, %v", got, err)
+	}
+	if _, err := svc.GetOwnOrder(placed.ID, "bob"); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Fatalf("GetOwnOrder(other key) err = %v, want ErrOrderNotFound", err)
+	}
+
+	// changes by another key fail the same way and leave the order alone
+	mod := models.OrderModification{Add: []models.OrderItem{{ProductID: "2", Quantity: 1}}}
+	if _, err := svc.ModifyOrder(context.Background(), placed.ID, "bob", mod); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Fatalf("ModifyOrder(other key) err = %v, want ErrOrderNotFound", err)
+	}
+	if _, err := svc.AdvanceOrder(placed.ID, "bob", "", ""); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Fatalf("AdvanceOrder(other key) err = %v, want ErrOrderNotFound", err)
+	}
+	if _, err := svc.CancelOrder(placed.ID, "bob", ""); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Fatalf("CancelOrder(other key) err = %v, want ErrOrderNotFound", err)
+	}
+	got, err := svc.GetOwnOrder(placed.ID, "alice")
+	if err != nil || got.Status != models.StatusPlaced || got.Revision != placed.Revision {
+		t.Fatalf("order after other key's attempts = %+v, %v; want it unchanged", got, err)
+	}
+	if _, err := svc.CancelOrder(placed.ID, "alice", ""); err != nil {
+		t.Fatalf("CancelOrder(owner): %v", err)
+	}
+}
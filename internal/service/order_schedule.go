@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/slots"
+)
+
+// ErrSchedulingDisabled is returned by ListSlots without WithSlots.
+var ErrSchedulingDisabled = errors.New("scheduled orders are not accepted")
+
+// WithSlots accepts orders scheduled for a slot of catalogue, booking them
+// against its capacity at the service's clock.
+func WithSlots(catalogue *slots.Catalogue) OrderOption {
+	return func(s *OrderService) { s.slots = catalogue }
+}
+
+// ListSlots returns the bookable slots that are not full, on date
+// (YYYY-MM-DD in the opening hours' time zone) or, if date is empty,
+// up to the booking horizon.
+func (s *OrderService) ListSlots(date string) ([]slots.Slot, error) {
+	if s.slots == nil {
+		return nil, ErrSchedulingDisabled
+	}
+	now := s.now()
+	from, to := now, now.AddDate(1, 0, 0) // Slots caps at the horizon
+	if date != "" {
+		day, err := time.ParseInLocation(time.DateOnly, date, s.slots.Location())
+		if err != nil {
+			return nil, NewValidationError(fmt.Sprintf("invalid date %q: want YYYY-MM-DD", date))
+		}
+		from, to = day, day.AddDate(0, 0, 1)
+	}
+
+	open := []slots.Slot{}
+	for _, sl := range s.slots.Slots(now, from, to) {
+		if !sl.Full() {
+			open = append(open, sl)
+		}
+	}
+	return open, nil
+}
+
+// reserveSlot books order into the slot starting at start, if any, and
+// records it on the order. An unavailable slot is returned as a
+// ValidationError with the *slots.SlotError as details.
+func (s *OrderService) reserveSlot(order *models.Order, start *time.Time, now time.Time) error {
+	if start == nil {
+		return nil
+	}
+	if s.slots == nil {
+		return NewValidationError(ErrSchedulingDisabled.Error())
+	}
+	slot, err := s.slots.Reserve(order.ID, *start, now)
+	if err != nil {
+		var se *slots.SlotError
+		if errors.As(err, &se) {
+			return NewValidationErrorWithDetails(se.Error(), se)
+		}
+		return fmt.Errorf("failed to reserve slot: %w", err)
+	}
+	order.Slot = &models.TimeSlot{Start: slot.Start, End: slot.End}
+	return nil
+}
+
+// releaseHolds gives back the stock and slot held by orderID.
+func (s *OrderService) releaseHolds(orderID string) {
+	s.releaseStock(orderID)
+	if s.slots != nil {
+		s.slots.Release(orderID)
+	}
+}
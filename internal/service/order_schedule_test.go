@@ -0,0 +1,116 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository/memory"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/slots"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/testutil"
+)
+
+func TestOrderService_ScheduledOrders(t *testing.T) {
+	t.Parallel()
+
+	hours, err := slots.ParseHours("mon-fri 11:00-12:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalogue, err := slots.New(slots.Config{Hours: hours, Length: 15 * time.Minute, Capacity: 1, LeadTime: 30 * time.Minute, Horizon: 72 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, min int) *time.Time {
+		t := time.Date(2025, 6, day, hour, min, 0, 0, time.UTC) // June 2 is a Monday
+		return &t
+	}
+	clock := *at(2, 11, 0)
+	ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+	svc := NewOrderService(ps, memory.NewOrderRepo(), &testutil.ValidatorStub{Valid: true},
+		WithSlots(catalogue), WithClock(func() time.Time { return clock }))
+	place := func(start *time.Time) (*models.Order, error) {
+		return svc.PlaceOrder(models.OrderRequest{Items: []models.OrderItem{{ProductID: "1", Quantity: 1}}, ScheduledFor: start})
+	}
+	starts := func(date string) []int {
+		list, err := svc.ListSlots(date)
+		if err != nil {
+			t.Fatalf("ListSlots(%q): %v", date, err)
+		}
+		var out []int
+		for _, s := range list {
+			out = append(out, s.Start.Day()*10000+s.Start.Hour()*100+s.Start.Minute())
+		}
+		return out
+	}
+
+	// 11:30 is the first slot after the lead time
+	if got := starts("2025-06-02"); len(got) != 2 || got[0] != 21130 {
+		t.Fatalf("monday slots = %v", got)
+	}
+
+	asap, err := place(nil)
+	if err != nil || asap.Slot != nil {
+		t.Fatalf("as-soon-as-possible order: %v %+v", err, asap)
+	}
+	first, err := place(at(2, 11, 45))
+	if err != nil || !first.Slot.End.Equal(*at(2, 12, 0)) {
+		t.Fatalf("scheduled order: %v %+v", err, first)
+	}
+	if got := starts("2025-06-02"); len(got) != 1 || got[0] != 21130 {
+		t.Fatalf("full slot still listed: %v", got)
+	}
+
+	tests := []struct {
+		name   string
+		start  *time.Time
+		reason slots.Reason
+	}{
+		{"full", at(2, 11, 45), slots.ReasonFull},
+		{"closed", at(2, 13, 0), slots.ReasonClosed},
+		{"too soon", at(2, 11, 15), slots.ReasonTooSoon},
+		{"weekend", at(7, 11, 0), slots.ReasonClosed},
+	}
+	for _, tt := range tests {
+		_, err := place(tt.start)
+		var ve *ValidationError
+		se, _ := detailsOf[*slots.SlotError](err)
+		if !errors.As(err, &ve) || se == nil || se.Reason != tt.reason {
+			t.Fatalf("%s: err = %v, want slot reason %s", tt.name, err, tt.reason)
+		}
+	}
+
+	if _, err := svc.CancelOrder(first.ID, "", ""); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	clock = clock.Add(time.Minute) // time moves on: 11:30 is now within the lead time
+	if got := starts("2025-06-02"); len(got) != 1 || got[0] != 21145 {
+		t.Fatalf("cancelled slot not freed: %v", got)
+	}
+	if got := starts(""); len(got) != 10 { // 11:45 today, tuesday, wednesday, thursday 11:00
+		t.Fatalf("slots up to the horizon = %v", got)
+	}
+	if _, err := svc.ListSlots("monday"); !IsValidationError(err) {
+		t.Fatalf("invalid date: %v", err)
+	}
+
+	plain := NewOrderService(ps, memory.NewOrderRepo(), &testutil.ValidatorStub{Valid: true})
+	if _, err := plain.PlaceOrder(models.OrderRequest{Items: []models.OrderItem{{ProductID: "1", Quantity: 1}}, ScheduledFor: at(2, 11, 45)}); !IsValidationError(err) {
+		t.Fatalf("scheduled order without slots: %v", err)
+	}
+	if _, err := plain.ListSlots(""); !errors.Is(err, ErrSchedulingDisabled) {
+		t.Fatalf("ListSlots without slots: %v", err)
+	}
+}
+
+// detailsOf returns err's ValidationError details as a T.
+func detailsOf[T any](err error) (T, bool) {
+	var ve *ValidationError
+	var zero T
+	if !errors.As(err, &ve) {
+		return zero, false
+	}
+	d, ok := ve.Details.(T)
+	return d, ok
+}
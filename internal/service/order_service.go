@@ -1,72 +1,241 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/Niraj-Shaw/orderfoodonline/internal/discount"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/promovalidator"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/repository"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/slots"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/tax"
 )
 
-type ValidationError struct{ Message string }
+type ValidationError struct {
+	Message string
+	Details any // optional machine-readable context, returned to API clients
+}
 
 func (e *ValidationError) Error() string { return e.Message }
 
 func NewValidationError(msg string) *ValidationError { return &ValidationError{Message: msg} }
 
+// NewValidationErrorWithDetails is NewValidationError carrying structured details.
+func NewValidationErrorWithDetails(msg string, details any) *ValidationError {
+	return &ValidationError{Message: msg, Details: details}
+}
+
 func IsValidationError(err error) bool {
 	var v *ValidationError
 	return errors.As(err, &v)
 }
 
+// ErrMisconfigured wraps errors caused by the service's configuration, such
+// as a product whose tax class the tax rules lack. They are not the caller's
+// fault and are reported as internal errors.
+var ErrMisconfigured = errors.New("service misconfigured")
+
 // OrderService handles business logic for order operations.
 type OrderService struct {
 	productService *ProductService
 	orderRepo      repository.OrderRepository
 	validator      promovalidator.ValidatorService
+	discounts      *discount.Engine                // nil: valid codes carry no discount
+	redemptions    repository.RedemptionRepository // nil: redemption limits are not enforced
+	inventory      repository.InventoryRepository  // nil: stock is not enforced
+	limits         OrderLimits                     // line normalization and caps
+	slots          *slots.Catalogue                // nil: orders cannot be scheduled
+	tax            tax.Rules                       // rates per product and price mode
+	now            func() time.Time                // clock for order timestamps
+}
+
+// OrderOption configures optional OrderService behaviour.
+type OrderOption func(*OrderService)
+
+// WithDiscounts applies engine's rules to orders placed with a valid promo code.
+func WithDiscounts(engine *discount.Engine) OrderOption {
+	return func(s *OrderService) { s.discounts = engine }
+}
+
+// WithRedemptions enforces the discount rules' redemption limits using repo.
+// It has no effect without WithDiscounts.
+func WithRedemptions(repo repository.RedemptionRepository) OrderOption {
+	return func(s *OrderService) { s.redemptions = repo }
+}
+
+// WithInventory reserves stock for every order in repo, failing orders for
+// products that are sold out or short, and gives it back when an order is
+// cancelled or rejected.
+func WithInventory(repo repository.InventoryRepository) OrderOption {
+	return func(s *OrderService) { s.inventory = repo }
+}
+
+// WithTaxPercent charges tax of percent on products without a tax class
+// (see WithTaxRules), after discounts.
+func WithTaxPercent(percent float64) OrderOption {
+	return func(s *OrderService) { s.tax.DefaultRate = percent }
+}
+
+// WithTaxRounding sets how each line's tax is rounded to minor units
+// (default money.HalfUp).
+func WithTaxRounding(mode money.RoundingMode) OrderOption {
+	return func(s *OrderService) { s.tax.Rounding = mode }
+}
+
+// WithTaxRules taxes each product at the rate of its tax class or category
+// and sets whether prices include tax. It replaces WithTaxPercent and
+// WithTaxRounding given before it.
+func WithTaxRules(rules tax.Rules) OrderOption {
+	return func(s *OrderService) { s.tax = rules }
+}
+
+// WithClock sets the clock used for order timestamps (default time.Now).
+func WithClock(now func() time.Time) OrderOption {
+	return func(s *OrderService) { s.now = now }
 }
 
 func NewOrderService(
 	productService *ProductService,
 	orderRepo repository.OrderRepository,
 	validator promovalidator.ValidatorService,
+	opts ...OrderOption,
 ) *OrderService {
-	return &OrderService{
+	s := &OrderService{
 		productService: productService,
 		orderRepo:      orderRepo,
 		validator:      validator,
+		tax:            tax.Rules{Mode: tax.Exclusive, Rounding: money.HalfUp},
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-// PlaceOrder validates input, resolves products (preserving item order),
-// validates promo, assigns a UUID, persists, and returns the saved order.
+// PlaceOrder is PlaceOrderContext without cancellation.
 func (s *OrderService) PlaceOrder(req models.OrderRequest) (*models.Order, error) {
+	return s.PlaceOrderContext(context.Background(), req)
+}
+
+// PlaceOrderContext validates input (fulfilment and contact details, items),
+// resolves products (preserving item order), validates promo, applies its
+// discount rule (if any), prices the order,
+// assigns a UUID, persists, and returns the saved order. Stock, the requested slot
+// and a limited code's redemption are reserved before the order is saved and
+// released if saving fails.
+// Cancelling ctx (e.g. client disconnect) aborts an in-flight promo lookup.
+func (s *OrderService) PlaceOrderContext(ctx context.Context, req models.OrderRequest) (*models.Order, error) {
+	details, err := validateDetails(req)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.buildLines(ctx, req.CouponCode, req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build order with UUID
+	now := s.now()
+	order := &models.Order{
+		ID:            uuid.New().String(),
+		CouponCode:    req.CouponCode,
+		Fulfilment:    details.fulfilment,
+		Customer:      details.customer,
+		Notes:         details.notes,
+		Status:        models.StatusPlaced,
+		StatusHistory: []models.StatusChange{{Status: models.StatusPlaced, At: now}},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		APIKey:        req.APIKey,
+	}
+	lines.apply(order)
+	order.Revisions = []models.OrderRevision{{Revision: 1, At: now, Items: order.Items, Total: order.Pricing.Total}}
+	order.Revision = 1
+
+	// Reserve stock, the slot and the redemption first so concurrent orders
+	// can't both take the last unit, place or use
+	if err := s.reserveStock(order.ID, order.Items); err != nil {
+		return nil, err
+	}
+	if err := s.reserveSlot(order, req.ScheduledFor, now); err != nil {
+		s.releaseHolds(order.ID)
+		return nil, err
+	}
+	release, err := s.reserveRedemption(req, order.ID)
+	if err != nil {
+		s.releaseHolds(order.ID)
+		return nil, err
+	}
+
+	// Persist
+	saved, err := s.orderRepo.CreateOrder(order)
+	if err != nil {
+		release()
+		s.releaseHolds(order.ID)
+		return nil, fmt.Errorf("failed to save order: %w", err)
+	}
+	return saved, nil
+}
+
+// MaxLineQuantity caps every line's quantity, whatever OrderLimits allow, so
+// line totals stay far from overflowing.
+const MaxLineQuantity = 10_000
+
+// orderLines is the validated and priced content of an order.
+type orderLines struct {
+	items     []models.OrderItem
+	products  []models.Product
+	discounts []models.DiscountLine
+	pricing   models.Pricing
+}
+
+// buildLines validates and normalizes items, checks couponCode, resolves
+// products (preserving item order), applies the code's discount rule and
+// prices the result. Placing and modifying an order run the same checks
+// through it.
+func (s *OrderService) buildLines(ctx context.Context, couponCode string, items []models.OrderItem) (*orderLines, error) {
 	// Basic request validation
-	if len(req.Items) == 0 {
+	if len(items) == 0 {
 		return nil, NewValidationError("order must contain at least one item")
 	}
-	for i, it := range req.Items {
+	for i, it := range items {
 		if it.ProductID == "" {
 			return nil, NewValidationError(fmt.Sprintf("item %d: productId is required", i+1))
 		}
 		if it.Quantity <= 0 {
 			return nil, NewValidationError(fmt.Sprintf("item %d: quantity must be > 0", i+1))
 		}
+		if it.Quantity > MaxLineQuantity {
+			return nil, NewValidationError(fmt.Sprintf("item %d: quantity %d exceeds the maximum of %d per line", i+1, it.Quantity, MaxLineQuantity))
+		}
+	}
+	items, err := s.limits.normalize(items)
+	if err != nil {
+		return nil, err
 	}
 
 	// Promo validation (case-sensitive) if provided
-	if req.CouponCode != "" {
-		if !s.validator.ValidatePromoCode(req.CouponCode) {
-			return nil, NewValidationError("invalid promo code")
+	if couponCode != "" {
+		res := s.validator.CheckPromoCode(ctx, couponCode)
+		if !res.Valid {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("promo validation aborted: %w", err)
+			}
+			return nil, NewValidationErrorWithDetails("invalid promo code: "+res.Message(), res)
 		}
 	}
 
 	// Bulk validate existence → map[id]Product (lets us preserve item order)
-	ids := make([]string, 0, len(req.Items))
-	for _, it := range req.Items {
+	ids := make([]string, 0, len(items))
+	for _, it := range items {
 		ids = append(ids, it.ProductID)
 	}
 	prodMap, err := s.productService.ValidateProductsExist(ids)
@@ -75,27 +244,124 @@ func (s *OrderService) PlaceOrder(req models.OrderRequest) (*models.Order, error
 	}
 
 	// Resolve items/products in the same order as request
-	resolvedItems := make([]models.OrderItem, 0, len(req.Items))
-	resolvedProducts := make([]models.Product, 0, len(req.Items))
-	for _, it := range req.Items {
+	resolvedItems := make([]models.OrderItem, 0, len(items))
+	resolvedProducts := make([]models.Product, 0, len(items))
+	for _, it := range items {
 		resolvedItems = append(resolvedItems, models.OrderItem{
 			ProductID: it.ProductID,
 			Quantity:  it.Quantity,
+			Options:   slices.Clone(it.Options),
 		})
-		resolvedProducts = append(resolvedProducts, prodMap[it.ProductID])
+		p := prodMap[it.ProductID]
+		p.Stock, p.SoldOut = nil, false // the order's snapshot doesn't reveal inventory
+		if err := s.tax.Check(p); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMisconfigured, err)
+		}
+		resolvedProducts = append(resolvedProducts, p)
 	}
 
-	// Build order with UUID
-	order := &models.Order{
-		ID:       uuid.New().String(),
-		Items:    resolvedItems,
-		Products: resolvedProducts,
+	if i, ok := sameCurrency(resolvedProducts); !ok {
+		return nil, NewValidationError(fmt.Sprintf("item %d: product %s is priced in %s, the rest of the order in %s",
+			i+1, resolvedProducts[i].ID, resolvedProducts[i].Price.Currency, resolvedProducts[0].Price.Currency))
 	}
 
-	// Persist
-	saved, err := s.orderRepo.CreateOrder(order)
-	if err != nil {
-		return nil, fmt.Errorf("failed to save order: %w", err)
+	// Selected options; discounts and pricing see each product at its price with options
+	options := make([][]models.PricedOption, len(resolvedItems))
+	priced := slices.Clone(resolvedProducts)
+	for i, it := range resolvedItems {
+		if options[i], priced[i].Price, err = resolveOptions(i+1, it, resolvedProducts[i]); err != nil {
+			return nil, err
+		}
 	}
-	return saved, nil
+
+	// Everything below adds up line totals; make sure they fit
+	if _, err := checkedSubtotal(resolvedItems, priced); err != nil {
+		return nil, NewValidationError("order total is too large")
+	}
+
+	// Discount lines for the (already validated) promo code
+	var discounts []models.DiscountLine
+	if couponCode != "" && s.discounts != nil {
+		discounts, err = s.discounts.Apply(couponCode, resolvedItems, priced)
+		var na *discount.NotApplicableError
+		if errors.As(err, &na) {
+			return nil, NewValidationErrorWithDetails("promo code not applicable: "+na.Message, na)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply discount: %w", err)
+		}
+	}
+
+	pricing := priceOrder(resolvedItems, priced, options, discounts, s.tax)
+	if err := s.limits.checkTotal(pricing); err != nil {
+		return nil, err
+	}
+
+	return &orderLines{
+		items:     resolvedItems,
+		products:  resolvedProducts,
+		discounts: discounts,
+		pricing:   pricing,
+	}, nil
+}
+
+// apply sets the lines on o, replacing its previous content.
+func (l *orderLines) apply(o *models.Order) {
+	o.Items, o.Products, o.Pricing = l.items, l.products, l.pricing
+	o.Discounts, o.DiscountTotal = nil, nil
+	if len(l.discounts) > 0 {
+		total := l.pricing.DiscountTotal // the lines' amounts, as clipped by pricing
+		o.Discounts, o.DiscountTotal = l.discounts, &total
+	}
+}
+
+// reserveStock sets orderID's stock reservation to items. A shortage is
+// returned as a ValidationError listing the short products.
+func (s *OrderService) reserveStock(orderID string, items []models.OrderItem) error {
+	if s.inventory == nil {
+		return nil
+	}
+	if err := s.inventory.Reserve(orderID, items); err != nil {
+		var se *repository.StockShortageError
+		if errors.As(err, &se) {
+			return NewValidationErrorWithDetails(se.Error(), se)
+		}
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+	return nil
+}
+
+// releaseStock gives orderID's reserved stock back.
+func (s *OrderService) releaseStock(orderID string) {
+	if s.inventory != nil {
+		_ = s.inventory.Release(orderID)
+	}
+}
+
+// reserveRedemption records the use of req.CouponCode for orderID when its
+// rule limits redemptions. The returned func undoes the reservation.
+func (s *OrderService) reserveRedemption(req models.OrderRequest, orderID string) (release func(), err error) {
+	noop := func() {}
+	if req.CouponCode == "" || s.discounts == nil || s.redemptions == nil {
+		return noop, nil
+	}
+	rule, ok := s.discounts.Match(req.CouponCode)
+	if !ok {
+		return noop, nil
+	}
+	maxUses, perKey := rule.UseLimits()
+	limits := repository.RedemptionLimits{MaxUses: maxUses, MaxUsesPerKey: perKey}
+	if !limits.Limited() {
+		return noop, nil
+	}
+
+	red := models.Redemption{Code: req.CouponCode, OrderID: orderID, APIKey: req.APIKey}
+	if err := s.redemptions.Reserve(red, limits); err != nil {
+		var le *repository.RedemptionLimitError
+		if errors.As(err, &le) {
+			return nil, NewValidationErrorWithDetails(le.Error(), le)
+		}
+		return nil, fmt.Errorf("failed to reserve promo code: %w", err)
+	}
+	return func() { _ = s.redemptions.Release(red.Code, orderID) }, nil
 }
@@ -2,13 +2,26 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"github.com/Niraj-Shaw/orderfoodonline/internal/discount"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/promovalidator"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository/memory"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/tax"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/testutil"
 )
 
+func usd(s string) money.Money { return money.MustParse(s, "USD") }
+
 func TestOrderService_PlaceOrder_TableDriven(t *testing.T) {
 	type fields struct {
 		productRepo *testutil.ProductRepoStub
@@ -109,6 +122,28 @@ func TestOrderService_PlaceOrder_TableDriven(t *testing.T) {
 			args: args{req: models.OrderRequest{Items: []models.OrderItem{{ProductID: "1", Quantity: 0}}}},
 			want: want{orderNil: true, errIsValidation: true, errContains: "quantity must be > 0"},
 		},
+		{
+			name: "quantity above the hard ceiling",
+			fields: fields{
+				productRepo: testutil.NewProductRepoStub(testutil.SeedProducts()),
+				orderRepo:   testutil.NewOrderRepoStub(),
+				validator:   &testutil.ValidatorStub{Valid: true},
+			},
+			args: args{req: models.OrderRequest{Items: []models.OrderItem{{ProductID: "1", Quantity: math.MaxInt64 / 1000}}}},
+			want: want{orderNil: true, errIsValidation: true, errContains: "exceeds the maximum of 10000 per line"},
+		},
+		{
+			name: "line totals overflowing",
+			fields: fields{
+				productRepo: testutil.NewProductRepoStub([]models.Product{
+					{ID: "1", Name: "Gold Waffle", Price: money.New(math.MaxInt64/4, "USD"), Category: "Waffle"},
+				}),
+				orderRepo: testutil.NewOrderRepoStub(),
+				validator: &testutil.ValidatorStub{Valid: true},
+			},
+			args: args{req: models.OrderRequest{Items: []models.OrderItem{{ProductID: "1", Quantity: 5}}}},
+			want: want{orderNil: true, errIsValidation: true, errContains: "order total is too large"},
+		},
 		{
 			name: "product not found",
 			fields: fields{
@@ -174,3 +209,746 @@ func TestOrderService_PlaceOrder_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestOrderService_PlaceOrderContext_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+	repo := testutil.NewOrderRepoStub()
+	svc := NewOrderService(ps, repo, &testutil.ValidatorStub{Valid: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	got, err := svc.PlaceOrderContext(ctx, models.OrderRequest{
+		CouponCode: "HAPPYHRS",
+		Items:      []models.OrderItem{{ProductID: "1", Quantity: 1}},
+	})
+	if got != nil || err == nil {
+		t.Fatalf("expected error for cancelled context, got order=%+v err=%v", got, err)
+	}
+	if IsValidationError(err) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected non-validation context.Canceled error, got %T: %v", err, err)
+	}
+	if repo.Stored != nil {
+		t.Fatalf("order must not be persisted after cancellation")
+	}
+}
+
+func TestOrderService_PlaceOrder_PromoRejectionDetails(t *testing.T) {
+	t.Parallel()
+
+	ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+	svc := NewOrderService(ps, testutil.NewOrderRepoStub(), &testutil.ValidatorStub{Reason: promovalidator.ReasonLength})
+
+	_, err := svc.PlaceOrder(models.OrderRequest{
+		CouponCode: "BAD",
+		Items:      []models.OrderItem{{ProductID: "1", Quantity: 1}},
+	})
+
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected ValidationError, got %T: %v", err, err)
+	}
+	if !testutil.ContainsFold(ve.Message, "8-10 characters") {
+		t.Fatalf("expected length explanation in message, got %q", ve.Message)
+	}
+	res, ok := ve.Details.(promovalidator.Result)
+	if !ok || res.Reason != promovalidator.ReasonLength {
+		t.Fatalf("expected promo Result details, got %#v", ve.Details)
+	}
+}
+
+func TestOrderService_PlaceOrder_Discounts(t *testing.T) {
+	t.Parallel()
+
+	engine, err := discount.NewEngine([]discount.Rule{
+		{ID: "waffles", Code: "WAFFLE20", Type: discount.TypePercentage, Percent: 20, Category: "Waffle"},
+		{ID: "big", Code: "BIGBASKET", Type: discount.TypeFixed, Amount: usd("5"), MinBasket: usd("50")},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		code        string
+		wantTotal   string // "" = no discount
+		errContains string
+	}{
+		{name: "category discount", code: "WAFFLE20", wantTotal: "5.20"}, // 20% of 2*12.99
+		{name: "valid code without rule", code: "HAPPYHRS"},
+		{name: "minimum basket not met", code: "BIGBASKET", errContains: "minimum order of 50.00"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+			repo := testutil.NewOrderRepoStub()
+			svc := NewOrderService(ps, repo, &testutil.ValidatorStub{Valid: true}, WithDiscounts(engine))
+
+			got, err := svc.PlaceOrder(models.OrderRequest{
+				CouponCode: tc.code,
+				Items: []models.OrderItem{
+					{ProductID: "1", Quantity: 2},
+					{ProductID: "3", Quantity: 1},
+				},
+			})
+			if tc.errContains != "" {
+				if !IsValidationError(err) || !testutil.ContainsFold(err.Error(), tc.errContains) {
+					t.Fatalf("expected validation error containing %q, got %v", tc.errContains, err)
+				}
+				if repo.Stored != nil {
+					t.Fatalf("order must not be persisted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			gotTotal := ""
+			if got.DiscountTotal != nil {
+				gotTotal = got.DiscountTotal.Decimal()
+			}
+			if gotTotal != tc.wantTotal {
+				t.Fatalf("discountTotal = %q, want %q (lines %+v)", gotTotal, tc.wantTotal, got.Discounts)
+			}
+			if tc.wantTotal != "" && (len(got.Discounts) != 1 || got.Discounts[0].RuleID != "waffles") {
+				t.Fatalf("unexpected discount lines %+v", got.Discounts)
+			}
+		})
+	}
+}
+
+func TestOrderService_PlaceOrder_Pricing(t *testing.T) {
+	t.Parallel()
+
+	engine, err := discount.NewEngine([]discount.Rule{
+		{ID: "waffles", Code: "WAFFLE20", Type: discount.TypePercentage, Percent: 20, Category: "Waffle"},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	line := func(id string, qty int, unit, total, disc string, rate float64, tax string) models.PricingLine {
+		return models.PricingLine{ProductID: id, Quantity: qty, UnitPrice: usd(unit), LineTotal: usd(total),
+			Discount: usd(disc), TaxRate: rate, Tax: usd(tax)}
+	}
+	tests := []struct {
+		name       string
+		code       string
+		taxPercent float64
+		wantLines  []models.PricingLine
+		want       models.Pricing // without Lines
+	}{
+		{
+			name: "no discount, no tax",
+			wantLines: []models.PricingLine{
+				line("1", 2, "12.99", "25.98", "0", 0, "0"),
+				line("3", 1, "8.99", "8.99", "0", 0, "0"),
+			},
+			want: models.Pricing{Subtotal: usd("34.97"), DiscountTotal: usd("0"), TaxMode: "exclusive",
+				Taxes: []models.TaxLine{{Taxable: usd("34.97"), Tax: usd("0")}}, Tax: usd("0"), Total: usd("34.97")},
+		},
+		{
+			name:       "tax rounds half up to the cent per line",
+			taxPercent: 10,
+			wantLines: []models.PricingLine{
+				line("1", 2, "12.99", "25.98", "0", 10, "2.60"),
+				line("3", 1, "8.99", "8.99", "0", 10, "0.90"),
+			},
+			want: models.Pricing{Subtotal: usd("34.97"), DiscountTotal: usd("0"), TaxMode: "exclusive",
+				Taxes: []models.TaxLine{{Rate: 10, Taxable: usd("34.97"), Tax: usd("3.50")}}, Tax: usd("3.50"), Total: usd("38.47")},
+		},
+		{
+			name:       "tax on discounted lines",
+			code:       "WAFFLE20",
+			taxPercent: 8.25,
+			wantLines: []models.PricingLine{
+				line("1", 2, "12.99", "25.98", "5.20", 8.25, "1.71"),
+				line("3", 1, "8.99", "8.99", "0", 8.25, "0.74"),
+			},
+			want: models.Pricing{Subtotal: usd("34.97"), DiscountTotal: usd("5.20"), TaxMode: "exclusive",
+				Taxes: []models.TaxLine{{Rate: 8.25, Taxable: usd("29.77"), Tax: usd("2.45")}}, Tax: usd("2.45"), Total: usd("32.22")},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+			repo := testutil.NewOrderRepoStub()
+			svc := NewOrderService(ps, repo, &testutil.ValidatorStub{Valid: true},
+				WithDiscounts(engine), WithTaxPercent(tc.taxPercent))
+
+			got, err := svc.PlaceOrder(models.OrderRequest{
+				CouponCode: tc.code,
+				Items: []models.OrderItem{
+					{ProductID: "1", Quantity: 2},
+					{ProductID: "3", Quantity: 1},
+				},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			p := got.Pricing
+			if !reflect.DeepEqual(p.Lines, tc.wantLines) {
+				t.Fatalf("lines = %+v, want %+v", p.Lines, tc.wantLines)
+			}
+			p.Lines = nil
+			if !reflect.DeepEqual(p, tc.want) {
+				t.Fatalf("pricing = %+v, want %+v", p, tc.want)
+			}
+			if repo.Stored == nil || !reflect.DeepEqual(repo.Stored.Pricing, got.Pricing) {
+				t.Fatalf("stored order must carry the same pricing")
+			}
+		})
+	}
+}
+
+func TestOrderService_PlaceOrder_MixedCurrencies(t *testing.T) {
+	products := append(testutil.SeedProducts(), models.Product{ID: "4", Name: "Croissant", Price: money.MustParse("2.50", "EUR")})
+	ps := NewProductService(testutil.NewProductRepoStub(products))
+	repo := testutil.NewOrderRepoStub()
+	svc := NewOrderService(ps, repo, &testutil.ValidatorStub{Valid: true})
+
+	_, err := svc.PlaceOrder(models.OrderRequest{Items: []models.OrderItem{
+		{ProductID: "1", Quantity: 1},
+		{ProductID: "4", Quantity: 1},
+	}})
+	if !IsValidationError(err) || !testutil.ContainsFold(err.Error(), "item 2: product 4 is priced in EUR") {
+		t.Fatalf("expected currency validation error, got %v", err)
+	}
+	if repo.Stored != nil {
+		t.Fatalf("order must not be persisted")
+	}
+}
+
+func TestOrderService_PlaceOrder_RedemptionLimits(t *testing.T) {
+	t.Parallel()
+
+	engine, err := discount.NewEngine([]discount.Rule{
+		{ID: "once", Code: "ONETIME01", SingleUse: true},
+		{ID: "perkey", Code: "PERKEY001", Type: discount.TypeFixed, Amount: usd("1"), MaxUsesPerKey: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	order := func(code, key string) models.OrderRequest {
+		return models.OrderRequest{CouponCode: code, APIKey: key, Items: []models.OrderItem{{ProductID: "1", Quantity: 1}}}
+	}
+
+	t.Run("single use", func(t *testing.T) {
+		t.Parallel()
+		ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+		ledger := memory.NewRedemptionRepo()
+		svc := NewOrderService(ps, testutil.NewOrderRepoStub(), &testutil.ValidatorStub{Valid: true},
+			WithDiscounts(engine), WithRedemptions(ledger))
+
+		first, err := svc.PlaceOrder(order("ONETIME01", "k1"))
+		if err != nil {
+			t.Fatalf("first order: %v", err)
+		}
+		_, err = svc.PlaceOrder(order("ONETIME01", "k2"))
+		if !IsValidationError(err) || !errors.Is(err.(*ValidationError).Details.(error), repository.ErrRedemptionLimit) {
+			t.Fatalf("expected redemption limit validation error, got %v", err)
+		}
+		reds, _ := ledger.ListByCode("ONETIME01")
+		if len(reds) != 1 || reds[0].OrderID != first.ID || reds[0].APIKey != "k1" {
+			t.Fatalf("unexpected ledger %+v", reds)
+		}
+	})
+
+	t.Run("per api key", func(t *testing.T) {
+		t.Parallel()
+		ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+		svc := NewOrderService(ps, testutil.NewOrderRepoStub(), &testutil.ValidatorStub{Valid: true},
+			WithDiscounts(engine), WithRedemptions(memory.NewRedemptionRepo()))
+
+		for _, tc := range []struct {
+			key    string
+			wantOK bool
+		}{{"k1", true}, {"k2", true}, {"k1", false}} {
+			_, err := svc.PlaceOrder(order("PERKEY001", tc.key))
+			if (err == nil) != tc.wantOK {
+				t.Fatalf("key %s: err = %v, want ok=%v", tc.key, err, tc.wantOK)
+			}
+		}
+	})
+
+	t.Run("released when the order is not saved", func(t *testing.T) {
+		t.Parallel()
+		ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+		ledger := memory.NewRedemptionRepo()
+		failing := NewOrderService(ps, &testutil.OrderRepoStub{Err: testutil.ErrRepoDown}, &testutil.ValidatorStub{Valid: true},
+			WithDiscounts(engine), WithRedemptions(ledger))
+
+		if _, err := failing.PlaceOrder(order("ONETIME01", "k1")); err == nil {
+			t.Fatal("expected save error")
+		}
+		if reds, _ := ledger.ListByCode("ONETIME01"); len(reds) != 0 {
+			t.Fatalf("redemption not released: %+v", reds)
+		}
+	})
+
+	t.Run("concurrent single use", func(t *testing.T) {
+		t.Parallel()
+		ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+		svc := NewOrderService(ps, memory.NewOrderRepo(), &testutil.ValidatorStub{Valid: true},
+			WithDiscounts(engine), WithRedemptions(memory.NewRedemptionRepo()))
+
+		var ok atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := svc.PlaceOrder(order("ONETIME01", "k1")); err == nil {
+					ok.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if ok.Load() != 1 {
+			t.Fatalf("%d orders redeemed a single-use code, want 1", ok.Load())
+		}
+	})
+}
+
+func TestOrderService_Stock(t *testing.T) {
+	t.Parallel()
+
+	products := testutil.SeedProducts()
+	stock := 5
+	products[0].Stock = &stock // Chicken Waffle
+	products[2].SoldOut = true // Caesar Salad
+	inventory := memory.NewProductRepo(products)
+	svc := NewOrderService(NewProductService(inventory), memory.NewOrderRepo(), &testutil.ValidatorStub{Valid: true},
+		WithInventory(inventory))
+	left := func() int {
+		p, _ := inventory.GetByID("1")
+		return *p.Stock
+	}
+	place := func(items ...models.OrderItem) (*models.Order, error) {
+		return svc.PlaceOrder(models.OrderRequest{Items: items})
+	}
+
+	// concurrent orders never oversell
+	var wg sync.WaitGroup
+	var placed atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := place(models.OrderItem{ProductID: "1", Quantity: 2}); err == nil {
+				placed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if placed.Load() != 2 || left() != 1 {
+		t.Fatalf("placed %d orders, %d left; want 2 and 1", placed.Load(), left())
+	}
+
+	// shortages are listed together and nothing is reserved
+	_, err := place(models.OrderItem{ProductID: "2", Quantity: 1}, models.OrderItem{ProductID: "1", Quantity: 2},
+		models.OrderItem{ProductID: "3", Quantity: 1})
+	var ve *ValidationError
+	if !errors.As(err, &ve) || !errors.Is(ve.Details.(error), repository.ErrInsufficientStock) ||
+		err.Error() != "insufficient stock: item 2: product 1 has 1 left, 2 requested; item 3: product 3 is sold out" {
+		t.Fatalf("want stock validation error, got %v", err)
+	}
+	if left() != 1 {
+		t.Fatalf("failed order reserved stock: %d left", left())
+	}
+
+	// modifying adjusts the reservation, cancelling gives it back
+	order, err := place(models.OrderItem{ProductID: "1", Quantity: 1})
+	if err != nil || left() != 0 {
+		t.Fatalf("place: %v (%d left)", err, left())
+	}
+	if _, err := svc.ModifyOrder(context.Background(), order.ID, "", models.OrderModification{
+		Update: []models.OrderItem{{ProductID: "1", Quantity: 2}},
+	}); !IsValidationError(err) {
+		t.Fatalf("modification beyond stock: %v", err)
+	}
+	if _, err := svc.ModifyOrder(context.Background(), order.ID, "", models.OrderModification{
+		Remove: []string{"1"}, Add: []models.OrderItem{{ProductID: "2", Quantity: 1}},
+	}); err != nil || left() != 1 {
+		t.Fatalf("modify: %v (%d left)", err, left())
+	}
+	if _, err := svc.ModifyOrder(context.Background(), order.ID, "", models.OrderModification{
+		Add: []models.OrderItem{{ProductID: "1", Quantity: 1}},
+	}); err != nil || left() != 0 {
+		t.Fatalf("modify: %v (%d left)", err, left())
+	}
+	if _, err := svc.CancelOrder(order.ID, "", ""); err != nil || left() != 1 {
+		t.Fatalf("cancel: %v (%d left)", err, left())
+	}
+}
+
+func TestOrderService_PlaceOrder_Limits(t *testing.T) {
+	t.Parallel()
+
+	item := func(id string, qty int) models.OrderItem { return models.OrderItem{ProductID: id, Quantity: qty} }
+	merge := OrderLimits{MergeDuplicates: true}
+
+	tests := []struct {
+		name      string
+		limits    OrderLimits
+		items     []models.OrderItem
+		wantItems []models.OrderItem
+		wantErr   string
+	}{
+		{
+			name:      "duplicates merged into first line",
+			limits:    merge,
+			items:     []models.OrderItem{item("1", 2), item("3", 1), item("1", 1)},
+			wantItems: []models.OrderItem{item("1", 3), item("3", 1)},
+		},
+		{
+			name:      "merging disabled",
+			items:     []models.OrderItem{item("1", 2), item("3", 1), item("1", 1)},
+			wantItems: []models.OrderItem{item("1", 2), item("3", 1), item("1", 1)},
+		},
+		{
+			name:    "quantity per line",
+			limits:  OrderLimits{MaxQuantity: 4},
+			items:   []models.OrderItem{item("1", 4), item("2", 5)},
+			wantErr: "item 2: quantity 5 exceeds the maximum of 4 per line",
+		},
+		{
+			name:    "quantity of merged lines",
+			limits:  OrderLimits{MergeDuplicates: true, MaxQuantity: 4},
+			items:   []models.OrderItem{item("1", 2), item("2", 1), item("1", 2), item("1", 1)},
+			wantErr: "items 1, 3 and 4: quantity 5 exceeds the maximum of 4 per line",
+		},
+		{
+			name:    "merged lines above the hard ceiling",
+			limits:  merge,
+			items:   []models.OrderItem{item("1", 6000), item("3", 1), item("1", 5000)},
+			wantErr: "items 1 and 3: merged quantity exceeds the maximum of 10000 per line",
+		},
+		{
+			name:    "distinct products",
+			limits:  OrderLimits{MaxItems: 2},
+			items:   []models.OrderItem{item("1", 1), item("2", 1), item("1", 1), item("3", 1)},
+			wantErr: "item 4: an order may contain at most 2 different products",
+		},
+		{
+			name:    "order total",
+			limits:  OrderLimits{MaxTotal: usd("30")},
+			items:   []models.OrderItem{item("1", 3)},
+			wantErr: "order total 38.97 USD exceeds the maximum of 30.00 USD",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+			svc := NewOrderService(ps, testutil.NewOrderRepoStub(), &testutil.ValidatorStub{Valid: true},
+				WithOrderLimits(tc.limits))
+
+			got, err := svc.PlaceOrder(models.OrderRequest{Items: tc.items})
+			if tc.wantErr != "" {
+				if !IsValidationError(err) || err.Error() != tc.wantErr {
+					t.Fatalf("want validation error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got.Items, tc.wantItems) || len(got.Products) != len(tc.wantItems) {
+				t.Fatalf("items = %+v, want %+v", got.Items, tc.wantItems)
+			}
+		})
+	}
+}
+
+func TestOrderLimits_NormalizeDoesNotWrap(t *testing.T) {
+	t.Parallel()
+
+	items := []models.OrderItem{{ProductID: "1", Quantity: math.MaxInt64}, {ProductID: "1", Quantity: 2}}
+	lines, err := OrderLimits{MergeDuplicates: true}.normalize(items)
+	if !IsValidationError(err) {
+		t.Fatalf("normalize = %+v, %v; want a validation error", lines, err)
+	}
+}
+
+func TestOrderService_PlaceOrder_Options(t *testing.T) {
+	t.Parallel()
+
+	products := testutil.SeedProducts()
+	products[0].Options = []models.OptionGroup{ // Chicken Waffle, 12.99
+		{ID: "size", Name: "Size", Min: 1, Max: 1, Options: []models.Option{
+			{ID: "regular", Name: "Regular"}, {ID: "large", Name: "Large", Price: usd("2.00")},
+		}},
+		{ID: "extras", Name: "Extras", Max: 2, Options: []models.Option{
+			{ID: "cream", Name: "Cream", Price: usd("0.50")}, {ID: "berries", Name: "Berries", Price: usd("1.25")},
+			{ID: "syrup", Name: "Syrup", Price: usd("0.75")},
+		}},
+		{ID: "remove", Name: "Remove", Options: []models.Option{{ID: "nuts", Name: "No nuts"}}},
+	}
+	engine, err := discount.NewEngine([]discount.Rule{
+		{ID: "half", Code: "HALFWAFFL", Type: discount.TypePercentage, Percent: 50, Category: "Waffle"},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	opt := func(group, option string) models.SelectedOption {
+		return models.SelectedOption{Group: group, Option: option}
+	}
+	waffle := func(qty int, opts ...models.SelectedOption) models.OrderItem {
+		return models.OrderItem{ProductID: "1", Quantity: qty, Options: opts}
+	}
+
+	tests := []struct {
+		name      string
+		code      string
+		items     []models.OrderItem
+		wantUnits []string // unit price per line
+		wantLines int
+		wantErr   string
+	}{
+		{
+			name:      "options add to the unit price",
+			items:     []models.OrderItem{waffle(2, opt("size", "large"), opt("extras", "cream"), opt("extras", "berries"))},
+			wantUnits: []string{"16.74"},
+		},
+		{
+			name: "same options merge in any order",
+			items: []models.OrderItem{
+				waffle(1, opt("size", "regular"), opt("remove", "nuts")),
+				waffle(1, opt("size", "large")),
+				waffle(1, opt("remove", "nuts"), opt("size", "regular")),
+			},
+			wantUnits: []string{"12.99", "14.99"},
+		},
+		{
+			name:      "discount sees the price with options",
+			code:      "HALFWAFFL",
+			items:     []models.OrderItem{waffle(1, opt("size", "large"))},
+			wantUnits: []string{"14.99"},
+		},
+		{name: "required group", items: []models.OrderItem{waffle(1)}, wantErr: "item 1: choose at least 1 of Size"},
+		{
+			name:    "too many in group",
+			items:   []models.OrderItem{waffle(1, opt("size", "regular"), opt("extras", "cream"), opt("extras", "berries"), opt("extras", "syrup"))},
+			wantErr: "item 1: choose at most 2 of Extras",
+		},
+		{name: "unknown group", items: []models.OrderItem{waffle(1, opt("sauce", "bbq"))}, wantErr: `item 1: product 1 has no option group "sauce"`},
+		{name: "unknown option", items: []models.OrderItem{waffle(1, opt("size", "huge"))}, wantErr: `item 1: Size has no option "huge"`},
+		{
+			name:    "repeated option",
+			items:   []models.OrderItem{waffle(1, opt("size", "large"), opt("extras", "cream"), opt("extras", "cream"))},
+			wantErr: `item 1: option "cream" of Extras is selected more than once`,
+		},
+		{
+			name:    "options on a product without any",
+			items:   []models.OrderItem{{ProductID: "3", Quantity: 1, Options: []models.SelectedOption{opt("size", "large")}}},
+			wantErr: `item 1: product 3 has no option group "size"`,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ps := NewProductService(testutil.NewProductRepoStub(products))
+			svc := NewOrderService(ps, testutil.NewOrderRepoStub(), &testutil.ValidatorStub{Valid: true},
+				WithDiscounts(engine), WithOrderLimits(OrderLimits{MergeDuplicates: true}))
+
+			got, err := svc.PlaceOrder(models.OrderRequest{CouponCode: tc.code, Items: tc.items})
+			if tc.wantErr != "" {
+				if !IsValidationError(err) || err.Error() != tc.wantErr {
+					t.Fatalf("want validation error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got.Pricing.Lines) != len(tc.wantUnits) {
+				t.Fatalf("lines = %+v, want %d", got.Pricing.Lines, len(tc.wantUnits))
+			}
+			for i, line := range got.Pricing.Lines {
+				if line.UnitPrice.Decimal() != tc.wantUnits[i] || len(line.Options) != len(got.Items[i].Options) {
+					t.Fatalf("line %d = %+v, want unit price %s", i+1, line, tc.wantUnits[i])
+				}
+			}
+			if got.Products[0].Price != usd("12.99") {
+				t.Fatalf("stored product price changed: %s", got.Products[0].Price)
+			}
+			if tc.code != "" && (got.DiscountTotal == nil || got.DiscountTotal.Decimal() != "7.50") {
+				t.Fatalf("discountTotal = %v, want 7.50", got.DiscountTotal)
+			}
+		})
+	}
+}
+
+func TestOrderService_PlaceOrder_TaxClasses(t *testing.T) {
+	t.Parallel()
+
+	products := testutil.SeedProducts()
+	products[1].TaxClass = "zero" // Belgian Waffle, 9.99: class overrides its category
+	engine, err := discount.NewEngine([]discount.Rule{
+		{ID: "ten", Code: "TENOFF", Type: discount.TypeFixed, Amount: usd("10.00")},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	rules := tax.Rules{
+		DefaultRate: 20,
+		Classes:     map[string]float64{"food": 5, "zero": 0},
+		Categories:  map[string]string{"Waffle": "food"},
+	}
+	items := []models.OrderItem{
+		{ProductID: "1", Quantity: 2}, // 25.98, Waffle → food
+		{ProductID: "3", Quantity: 1}, // 8.99, Salad → default rate
+		{ProductID: "2", Quantity: 1}, // 9.99, zero
+	}
+
+	tests := []struct {
+		name      string
+		mode      tax.Mode
+		code      string
+		wantDisc  []string // per line
+		wantTax   []string // per line
+		wantTaxes []models.TaxLine
+		wantTotal string
+	}{
+		{
+			name:     "exclusive",
+			mode:     tax.Exclusive,
+			wantDisc: []string{"0.00", "0.00", "0.00"},
+			wantTax:  []string{"1.30", "1.80", "0.00"},
+			wantTaxes: []models.TaxLine{
+				{Class: "food", Rate: 5, Taxable: usd("25.98"), Tax: usd("1.30")},
+				{Rate: 20, Taxable: usd("8.99"), Tax: usd("1.80")},
+				{Class: "zero", Taxable: usd("9.99"), Tax: usd("0")},
+			},
+			wantTotal: "48.06",
+		},
+		{
+			name:     "inclusive prices contain the tax",
+			mode:     tax.Inclusive,
+			wantDisc: []string{"0.00", "0.00", "0.00"},
+			wantTax:  []string{"1.24", "1.50", "0.00"}, // 25.98 × 5/105, 8.99 × 20/120
+			wantTaxes: []models.TaxLine{
+				{Class: "food", Rate: 5, Taxable: usd("24.74"), Tax: usd("1.24")},
+				{Rate: 20, Taxable: usd("7.49"), Tax: usd("1.50")},
+				{Class: "zero", Taxable: usd("9.99"), Tax: usd("0")},
+			},
+			wantTotal: "44.96",
+		},
+		{
+			name:     "order discount is shared out before tax",
+			mode:     tax.Exclusive,
+			code:     "TENOFF",
+			wantDisc: []string{"5.78", "2.00", "2.22"}, // pro rata, remainders to the largest fractions
+			wantTax:  []string{"1.01", "1.40", "0.00"},
+			wantTaxes: []models.TaxLine{
+				{Class: "food", Rate: 5, Taxable: usd("20.20"), Tax: usd("1.01")},
+				{Rate: 20, Taxable: usd("6.99"), Tax: usd("1.40")},
+				{Class: "zero", Taxable: usd("7.77"), Tax: usd("0")},
+			},
+			wantTotal: "37.37",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := rules
+			r.Mode = tc.mode
+			ps := NewProductService(testutil.NewProductRepoStub(products))
+			svc := NewOrderService(ps, testutil.NewOrderRepoStub(), &testutil.ValidatorStub{Valid: true},
+				WithDiscounts(engine), WithTaxRules(r))
+
+			got, err := svc.PlaceOrder(models.OrderRequest{CouponCode: tc.code, Items: items})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			p := got.Pricing
+			for i, l := range p.Lines {
+				if l.Discount.Decimal() != tc.wantDisc[i] || l.Tax.Decimal() != tc.wantTax[i] {
+					t.Errorf("line %d: discount %s, tax %s; want %s, %s", i+1, l.Discount.Decimal(), l.Tax.Decimal(), tc.wantDisc[i], tc.wantTax[i])
+				}
+			}
+			if !reflect.DeepEqual(p.Taxes, tc.wantTaxes) {
+				t.Errorf("taxes = %+v, want %+v", p.Taxes, tc.wantTaxes)
+			}
+			if p.TaxMode != string(tc.mode) || p.Total.Decimal() != tc.wantTotal {
+				t.Errorf("mode %s, total %s; want %s, %s", p.TaxMode, p.Total.Decimal(), tc.mode, tc.wantTotal)
+			}
+		})
+	}
+}
+
+func TestOrderService_PlaceOrder_UnknownTaxClass(t *testing.T) {
+	t.Parallel()
+
+	products := testutil.SeedProducts()
+	products[0].TaxClass = "luxury" // not in the rules
+	ps := NewProductService(testutil.NewProductRepoStub(products))
+	svc := NewOrderService(ps, testutil.NewOrderRepoStub(), &testutil.ValidatorStub{Valid: true},
+		WithTaxRules(tax.Rules{Classes: map[string]float64{"food": 5}, Categories: map[string]string{"Waffle": "food"}}))
+
+	_, err := svc.PlaceOrder(models.OrderRequest{Items: []models.OrderItem{{ProductID: "1", Quantity: 1}}})
+	if !errors.Is(err, ErrMisconfigured) || IsValidationError(err) {
+		t.Fatalf("err = %v, want ErrMisconfigured instead of taxing at another rate", err)
+	}
+}
+
+func TestPriceOrder_AllocatesLargeDiscountsExactly(t *testing.T) {
+	t.Parallel()
+
+	// line × discount is around 1e24 minor units, far beyond int64
+	products := []models.Product{
+		{ID: "1", Price: money.New(1_000_000_000_000, "USD")},
+		{ID: "2", Price: money.New(3_000_000_000_000, "USD")},
+	}
+	items := []models.OrderItem{{ProductID: "1", Quantity: 1}, {ProductID: "2", Quantity: 1}}
+	discounts := []models.DiscountLine{{RuleID: "big", Amount: money.New(2_000_000_000_000, "USD")}}
+
+	p := priceOrder(items, products, nil, discounts, tax.Rules{})
+	if got := p.Lines[0].Discount.Amount; got != 500_000_000_000 {
+		t.Errorf("line 1 discount = %d, want 500000000000", got)
+	}
+	if got := p.Lines[1].Discount.Amount; got != 1_500_000_000_000 {
+		t.Errorf("line 2 discount = %d, want 1500000000000", got)
+	}
+	if p.DiscountTotal.Amount != 2_000_000_000_000 {
+		t.Errorf("discountTotal = %d, want 2000000000000", p.DiscountTotal.Amount)
+	}
+}
+
+func TestPriceOrder_ClipsDiscountLines(t *testing.T) {
+	t.Parallel()
+
+	products := testutil.SeedProducts()[:1] // Chicken Waffle, 12.99
+	items := []models.OrderItem{{ProductID: "1", Quantity: 2}}
+	discounts := []models.DiscountLine{
+		{RuleID: "half", Amount: usd("20.00")},
+		{RuleID: "more", Amount: usd("10.00")}, // only 5.98 left to take off
+	}
+
+	p := priceOrder(items, products, nil, discounts, tax.Rules{})
+	if p.DiscountTotal.Decimal() != "25.98" || p.Total.Decimal() != "0.00" {
+		t.Fatalf("discountTotal %s, total %s; want 25.98, 0.00", p.DiscountTotal.Decimal(), p.Total.Decimal())
+	}
+	if discounts[0].Amount.Decimal() != "20.00" || discounts[1].Amount.Decimal() != "5.98" {
+		t.Fatalf("discount lines = %+v, want them clipped to what was taken off", discounts)
+	}
+	if got := discount.Total(discounts); got != p.DiscountTotal {
+		t.Fatalf("lines add up to %s, pricing says %s", got, p.DiscountTotal)
+	}
+}
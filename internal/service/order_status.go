@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+)
+
+// transitions lists the statuses each status may move to. The first entry is
+// the normal next step, used when AdvanceOrder is not told where to go.
+// Statuses without entries are final.
+var transitions = map[models.OrderStatus][]models.OrderStatus{
+	models.StatusPlaced:         {models.StatusAccepted, models.StatusRejected, models.StatusCancelled},
+	models.StatusAccepted:       {models.StatusPreparing, models.StatusCancelled},
+	models.StatusPreparing:      {models.StatusReady, models.StatusCancelled},
+	models.StatusReady:          {models.StatusOutForDelivery, models.StatusDelivered},
+	models.StatusOutForDelivery: {models.StatusDelivered},
+	models.StatusDelivered:      nil,
+	models.StatusCancelled:      nil,
+	models.StatusRejected:       nil,
+}
+
+// ValidStatus reports whether s is a known order status.
+func ValidStatus(s models.OrderStatus) bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// CanTransition reports whether an order in from may move to to.
+func CanTransition(from, to models.OrderStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError reports a status change the transition table forbids.
+type TransitionError struct {
+	OrderID string               `json:"orderId"`
+	From    models.OrderStatus   `json:"from"`
+	To      models.OrderStatus   `json:"to"`
+	Allowed []models.OrderStatus `json:"allowed"` // empty once the order is final
+}
+
+func (e *TransitionError) Error() string {
+	if len(e.Allowed) == 0 {
+		return fmt.Sprintf("order is %s and can no longer change status", e.From)
+	}
+	allowed := make([]string, len(e.Allowed))
+	for i, s := range e.Allowed {
+		allowed[i] = string(s)
+	}
+	return fmt.Sprintf("order cannot move from %s to %s (allowed: %s)", e.From, e.To, strings.Join(allowed, ", "))
+}
+
+// IsTransitionError reports whether err is (or wraps) a *TransitionError.
+func IsTransitionError(err error) bool {
+	var te *TransitionError
+	return errors.As(err, &te)
+}
+
+// AdvanceOrder moves order id, placed with apiKey, to status to, or to its
+// normal next status when to is empty. Moving to cancelled or rejected
+// releases the order's stock, slot and promo code redemption.
+func (s *OrderService) AdvanceOrder(id, apiKey string, to models.OrderStatus, reason string) (*models.Order, error) {
+	if to != "" && !ValidStatus(to) {
+		return nil, NewValidationError(fmt.Sprintf("unknown order status %q", to))
+	}
+	return s.transition(id, apiKey, to, reason)
+}
+
+// CancelOrder moves order id, placed with apiKey, to cancelled.
+func (s *OrderService) CancelOrder(id, apiKey, reason string) (*models.Order, error) {
+	return s.transition(id, apiKey, models.StatusCancelled, reason)
+}
+
+func (s *OrderService) transition(id, apiKey string, to models.OrderStatus, reason string) (*models.Order, error) {
+	order, err := s.orderRepo.UpdateOrder(id, func(o *models.Order) error {
+		if err := checkOwner(o, apiKey); err != nil {
+			return err
+		}
+		target := to
+		if target == "" && len(transitions[o.Status]) > 0 {
+			target = transitions[o.Status][0]
+		}
+		if !CanTransition(o.Status, target) {
+			return &TransitionError{OrderID: o.ID, From: o.Status, To: target, Allowed: transitions[o.Status]}
+		}
+		now := s.now()
+		o.Status, o.UpdatedAt = target, now
+		o.StatusHistory = append(o.StatusHistory, models.StatusChange{Status: target, At: now, Reason: reason})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status == models.StatusCancelled || order.Status == models.StatusRejected {
+		s.releaseHolds(order.ID)
+		if order.CouponCode != "" && s.redemptions != nil {
+			_ = s.redemptions.Release(order.CouponCode, order.ID) // no-op for unlimited codes
+		}
+	}
+	return order, nil
+}
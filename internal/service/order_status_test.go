@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/discount"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository/memory"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/testutil"
+)
+
+func TestOrderService_StatusTransitions(t *testing.T) {
+	t.Parallel()
+
+	type step struct {
+		cancel   bool
+		to       models.OrderStatus // advance target; "" = next
+		want     models.OrderStatus // status afterwards
+		conflict bool               // expect *TransitionError
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "delivery happy path",
+			steps: []step{
+				{want: models.StatusAccepted},
+				{want: models.StatusPreparing},
+				{want: models.StatusReady},
+				{want: models.StatusOutForDelivery},
+				{want: models.StatusDelivered},
+				{want: models.StatusDelivered, conflict: true}, // final
+			},
+		},
+		{
+			name: "pickup skips delivery",
+			steps: []step{
+				{to: models.StatusAccepted, want: models.StatusAccepted},
+				{to: models.StatusPreparing, want: models.StatusPreparing},
+				{to: models.StatusReady, want: models.StatusReady},
+				{to: models.StatusDelivered, want: models.StatusDelivered},
+			},
+		},
+		{
+			name: "no skipping ahead",
+			steps: []step{
+				{to: models.StatusReady, want: models.StatusPlaced, conflict: true},
+			},
+		},
+		{
+			name: "rejected by restaurant",
+			steps: []step{
+				{to: models.StatusRejected, want: models.StatusRejected},
+				{cancel: true, want: models.StatusRejected, conflict: true},
+			},
+		},
+		{
+			name: "cancelled while preparing",
+			steps: []step{
+				{want: models.StatusAccepted},
+				{want: models.StatusPreparing},
+				{cancel: true, want: models.StatusCancelled},
+			},
+		},
+		{
+			name: "too late to cancel",
+			steps: []step{
+				{want: models.StatusAccepted},
+				{want: models.StatusPreparing},
+				{want: models.StatusReady},
+				{cancel: true, want: models.StatusReady, conflict: true},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			clock := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+			ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+			svc := NewOrderService(ps, memory.NewOrderRepo(), &testutil.ValidatorStub{Valid: true},
+				WithClock(func() time.Time { return clock }))
+
+			placed, err := svc.PlaceOrder(models.OrderRequest{Items: []models.OrderItem{{ProductID: "1", Quantity: 1}}})
+			if err != nil {
+				t.Fatalf("PlaceOrder: %v", err)
+			}
+			if placed.Status != models.StatusPlaced || len(placed.StatusHistory) != 1 || !placed.CreatedAt.Equal(clock) {
+				t.Fatalf("new order = %+v", placed)
+			}
+
+			changes := 1
+			for i, st := range tc.steps {
+				clock = clock.Add(time.Minute)
+				var got *models.Order
+				if st.cancel {
+					got, err = svc.CancelOrder(placed.ID, "", "changed my mind")
+				} else {
+					got, err = svc.AdvanceOrder(placed.ID, "", st.to, "")
+				}
+				if st.conflict != IsTransitionError(err) {
+					t.Fatalf("step %d: err = %v, want conflict %v", i+1, err, st.conflict)
+				}
+				if err == nil {
+					changes++
+					last := got.StatusHistory[len(got.StatusHistory)-1]
+					if len(got.StatusHistory) != changes || last.Status != got.Status || !last.At.Equal(clock) || !got.UpdatedAt.Equal(clock) {
+						t.Fatalf("step %d: history not recorded: %+v", i+1, got)
+					}
+				}
+				stored, _ := svc.orderRepo.FindByID(placed.ID)
+				if stored.Status != st.want {
+					t.Fatalf("step %d: status = %s, want %s", i+1, stored.Status, st.want)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderService_StatusErrors(t *testing.T) {
+	t.Parallel()
+
+	ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+	svc := NewOrderService(ps, memory.NewOrderRepo(), &testutil.ValidatorStub{Valid: true})
+
+	if _, err := svc.AdvanceOrder("0b8f3a36-4c1e-4a5e-9a53-0f1f1f1f1f1f", "", "", ""); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("unknown order: got %v", err)
+	}
+	if _, err := svc.AdvanceOrder("not-a-uuid", "", "", ""); !errors.Is(err, repository.ErrInvalidOrderID) {
+		t.Errorf("invalid id: got %v", err)
+	}
+	if _, err := svc.AdvanceOrder("0b8f3a36-4c1e-4a5e-9a53-0f1f1f1f1f1f", "", "eaten", ""); !IsValidationError(err) {
+		t.Errorf("unknown status: got %v", err)
+	}
+}
+
+func TestOrderService_CancelReleasesRedemption(t *testing.T) {
+	t.Parallel()
+
+	engine, err := discount.NewEngine([]discount.Rule{{ID: "once", Code: "ONETIME01", SingleUse: true}})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	ps := NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+	svc := NewOrderService(ps, memory.NewOrderRepo(), &testutil.ValidatorStub{Valid: true},
+		WithDiscounts(engine), WithRedemptions(memory.NewRedemptionRepo()))
+	req := models.OrderRequest{CouponCode: "ONETIME01", Items: []models.OrderItem{{ProductID: "1", Quantity: 1}}}
+
+	first, err := svc.PlaceOrder(req)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if _, err := svc.PlaceOrder(req); !IsValidationError(err) {
+		t.Fatalf("single-use code reused: %v", err)
+	}
+	if _, err := svc.CancelOrder(first.ID, "", ""); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if _, err := svc.PlaceOrder(req); err != nil {
+		t.Fatalf("code should be usable again after cancellation: %v", err)
+	}
+}
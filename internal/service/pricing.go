@@ -0,0 +1,176 @@
+package service
+
+import (
+	"math/bits"
+	"strings"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/tax"
+)
+
+// priceOrder computes the pricing breakdown for index-aligned items, products
+// and selected options, all in one currency. Product prices already include
+// the options, which are listed for reference. Discounts are shared out
+// over the lines they apply to, and each line is taxed on what remains at
+// its product's rate, rounded to minor units per line. A discount worth more
+// than what is left of its lines is clipped, and its Amount (in discounts,
+// updated in place) set to what was actually taken off.
+func priceOrder(items []models.OrderItem, products []models.Product, options [][]models.PricedOption, discounts []models.DiscountLine, rules tax.Rules) models.Pricing {
+	mode := rules.Mode
+	if mode == "" {
+		mode = tax.Exclusive
+	}
+	p := models.Pricing{
+		Lines:   make([]models.PricingLine, 0, len(items)),
+		TaxMode: string(mode),
+	}
+
+	currency := ""
+	if len(products) > 0 {
+		currency = products[0].Price.Currency
+	}
+	subtotal := money.New(0, currency)
+	for i, it := range items {
+		unit := products[i].Price
+		line := unit.Mul(int64(it.Quantity))
+		subtotal = subtotal.Add(line)
+		var opts []models.PricedOption
+		if i < len(options) {
+			opts = options[i]
+		}
+		class, rate := rules.Rate(products[i])
+		p.Lines = append(p.Lines, models.PricingLine{
+			ProductID: it.ProductID,
+			Quantity:  it.Quantity,
+			Options:   opts,
+			UnitPrice: unit,
+			LineTotal: line,
+			Discount:  money.New(0, currency),
+			TaxClass:  class,
+			TaxRate:   rate,
+		})
+	}
+
+	for i := range discounts {
+		discounts[i].Amount.Amount = allocateDiscount(p.Lines, products, discounts[i])
+	}
+
+	discount := money.New(0, currency)
+	taxTotal := money.New(0, currency)
+	p.Taxes = []models.TaxLine{}
+	for i := range p.Lines {
+		l := &p.Lines[i]
+		discount = discount.Add(l.Discount)
+		var net money.Money
+		l.Tax, net = rules.Tax(l.LineTotal.Sub(l.Discount), l.TaxRate)
+		taxTotal = taxTotal.Add(l.Tax)
+		p.Taxes = addTaxLine(p.Taxes, l.TaxClass, l.TaxRate, net, l.Tax)
+	}
+
+	p.Subtotal = subtotal
+	p.DiscountTotal = discount
+	p.Tax = taxTotal
+	p.Total = subtotal.Sub(discount)
+	if mode == tax.Exclusive {
+		p.Total = p.Total.Add(taxTotal)
+	}
+	return p
+}
+
+// checkedSubtotal sums the line totals of index-aligned items and products,
+// failing with money.ErrOverflow instead of wrapping around.
+func checkedSubtotal(items []models.OrderItem, products []models.Product) (money.Money, error) {
+	subtotal := money.Money{}
+	for i, it := range items {
+		line, err := products[i].Price.CheckedMul(int64(it.Quantity))
+		if err != nil {
+			return money.Money{}, err
+		}
+		if subtotal, err = subtotal.CheckedAdd(line); err != nil {
+			return money.Money{}, err
+		}
+	}
+	return subtotal, nil
+}
+
+// allocateDiscount shares d out over the lines it applies to (those of its
+// product and category, if it names them) in proportion to what is left of each
+// line, never taking a line below zero. Remainders go to the lines with the
+// largest fractional shares so the parts add up to the discount. It returns
+// the amount allocated, in minor units.
+func allocateDiscount(lines []models.PricingLine, products []models.Product, d models.DiscountLine) int64 {
+	eligible := make([]int, 0, len(lines))
+	for i, p := range products {
+		if (d.ProductID == "" || p.ID == d.ProductID) &&
+			(d.Category == "" || strings.EqualFold(p.Category, d.Category)) {
+			eligible = append(eligible, i)
+		}
+	}
+
+	var base int64
+	for _, i := range eligible {
+		base += lines[i].LineTotal.Amount - lines[i].Discount.Amount
+	}
+	amount := min(d.Amount.Amount, base)
+	if amount <= 0 {
+		return 0
+	}
+
+	shares := make([]int64, len(eligible))
+	rems := make([]int64, len(eligible))
+	left := amount
+	for k, i := range eligible {
+		rest := lines[i].LineTotal.Amount - lines[i].Discount.Amount
+		shares[k], rems[k] = mulDiv(rest, amount, base)
+		left -= shares[k]
+	}
+	for ; left > 0; left-- {
+		best := -1
+		for k, i := range eligible {
+			rest := lines[i].LineTotal.Amount - lines[i].Discount.Amount
+			if shares[k] < rest && (best < 0 || rems[k] > rems[best]) {
+				best = k
+			}
+		}
+		shares[best]++
+		rems[best] = -1
+	}
+	for k, i := range eligible {
+		lines[i].Discount.Amount += shares[k]
+	}
+	return amount
+}
+
+// mulDiv returns a×b/c and its remainder, computing a×b in 128 bits so large
+// amounts can't wrap around. a, b >= 0 and c > 0; the quotient must fit in
+// int64, as it does for a <= c.
+func mulDiv(a, b, c int64) (q, r int64) {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	uq, ur := bits.Div64(hi, lo, uint64(c))
+	return int64(uq), int64(ur)
+}
+
+// addTaxLine adds a line's net amount and tax to the breakdown entry for its
+// class and rate, appending one on first use.
+func addTaxLine(taxes []models.TaxLine, class string, rate float64, net, amount money.Money) []models.TaxLine {
+	for i := range taxes {
+		if taxes[i].Class == class && taxes[i].Rate == rate {
+			taxes[i].Taxable = taxes[i].Taxable.Add(net)
+			taxes[i].Tax = taxes[i].Tax.Add(amount)
+			return taxes
+		}
+	}
+	return append(taxes, models.TaxLine{Class: class, Rate: rate, Taxable: net, Tax: amount})
+}
+
+// sameCurrency reports the first product priced in a different currency from
+// the first one, if any.
+func sameCurrency(products []models.Product) (int, bool) {
+	for i := 1; i < len(products); i++ {
+		if !products[i].Price.SameCurrency(products[0].Price) {
+			return i, false
+		}
+	}
+	return 0, true
+}
@@ -35,12 +35,16 @@ func (s *ProductService) GetProductByID(id string) (*models.Product, error) {
 
 // ValidateExistence checks that all IDs exist and returns a map[id]Product.
 // Using a map lets callers (e.g., OrderService) preserve item ordering.
+// Repeated IDs are looked up once.
 func (s *ProductService) ValidateProductsExist(ids []string) (map[string]models.Product, error) {
 	if len(ids) == 0 {
 		return nil, NewValidationError("no products provided")
 	}
 	out := make(map[string]models.Product, len(ids))
 	for _, id := range ids {
+		if _, ok := out[id]; ok {
+			continue
+		}
 		p, err := s.repo.GetByID(id)
 		if err != nil || p == nil {
 			return nil, NewValidationError(fmt.Sprintf("product with ID %s not found", id))
@@ -0,0 +1,125 @@
+package slots
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hours are weekly opening hours, indexed by time.Weekday.
+type Hours [7][]Interval
+
+// Interval is an opening period as offsets from midnight. End is after
+// Start and may exceed 24h for periods that run past midnight.
+type Interval struct {
+	Start, End time.Duration
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseHours parses opening hours such as
+//
+//	mon-fri 11:00-14:30,17:00-22:00; sat,sun 12:00-23:00
+//
+// Entries are separated by semicolons. Days are three-letter names, lists
+// or ranges (which may wrap, e.g. fri-mon). A closing time at or before the
+// opening time, such as 18:00-02:00, closes on the next day. Days that are
+// not listed are closed; periods of one day must not overlap.
+func ParseHours(s string) (Hours, error) {
+	var h Hours
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		daySpec, timeSpec, ok := strings.Cut(entry, " ")
+		if !ok {
+			return Hours{}, fmt.Errorf("opening hours %q: want \"<days> <open>-<close>[,...]\"", entry)
+		}
+		days, err := parseDays(daySpec)
+		if err != nil {
+			return Hours{}, fmt.Errorf("opening hours %q: %w", entry, err)
+		}
+		for _, period := range strings.Split(strings.TrimSpace(timeSpec), ",") {
+			iv, err := parseInterval(strings.TrimSpace(period))
+			if err != nil {
+				return Hours{}, fmt.Errorf("opening hours %q: %w", entry, err)
+			}
+			for _, d := range days {
+				h[d] = append(h[d], iv)
+			}
+		}
+	}
+
+	for d := range h {
+		sort.Slice(h[d], func(i, j int) bool { return h[d][i].Start < h[d][j].Start })
+		for i := 1; i < len(h[d]); i++ {
+			if h[d][i].Start < h[d][i-1].End {
+				return Hours{}, fmt.Errorf("opening hours overlap on %s", strings.ToLower(time.Weekday(d).String()[:3]))
+			}
+		}
+	}
+	return h, nil
+}
+
+func parseDays(spec string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, part := range strings.Split(strings.ToLower(spec), ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdays[from]
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[to]; !ok {
+				return nil, fmt.Errorf("unknown day %q", to)
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days = append(days, d)
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseInterval(s string) (Interval, error) {
+	open, close, ok := strings.Cut(s, "-")
+	if !ok {
+		return Interval{}, fmt.Errorf("period %q: want HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(open)
+	if err != nil {
+		return Interval{}, err
+	}
+	end, err := parseClock(close)
+	if err != nil {
+		return Interval{}, err
+	}
+	if start == 24*time.Hour {
+		return Interval{}, fmt.Errorf("period %q opens at 24:00", s)
+	}
+	if end <= start {
+		end += 24 * time.Hour
+	}
+	return Interval{Start: start, End: end}, nil
+}
+
+// parseClock parses HH:MM from 00:00 to 24:00.
+func parseClock(s string) (time.Duration, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if !ok || err1 != nil || err2 != nil || len(mm) != 2 || h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
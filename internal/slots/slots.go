@@ -0,0 +1,227 @@
+// Package slots generates pickup/delivery time slots from weekly opening
+// hours and books scheduled orders into them up to a per-slot capacity.
+//
+// Nothing here reads the clock: callers pass "now", so the catalogue is
+// driven by whatever clock they use.
+package slots
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config describes the slot catalogue.
+type Config struct {
+	Hours    Hours
+	Length   time.Duration  // slot length; slots start every Length from opening
+	Capacity int            // orders per slot (0 = unlimited)
+	LeadTime time.Duration  // slots must start at least this long after now
+	Horizon  time.Duration  // and no later than this long after now
+	Location *time.Location // time zone of Hours (default UTC)
+}
+
+// Slot is a bookable period and how full it is.
+type Slot struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Capacity int       `json:"capacity,omitempty"` // 0 = unlimited
+	Booked   int       `json:"booked"`
+}
+
+// Full reports whether the slot takes no more orders.
+func (s Slot) Full() bool { return s.Capacity > 0 && s.Booked >= s.Capacity }
+
+// Reason explains why a time cannot be booked.
+type Reason string
+
+const (
+	ReasonClosed  Reason = "closed"     // outside opening hours
+	ReasonNotSlot Reason = "not_a_slot" // open, but not the start of a slot
+	ReasonTooSoon Reason = "too_soon"   // within the lead time
+	ReasonTooFar  Reason = "too_far"    // beyond the horizon
+	ReasonFull    Reason = "full"
+)
+
+// ErrUnavailable is matched (errors.Is) by every *SlotError.
+var ErrUnavailable = errors.New("slot unavailable")
+
+// SlotError reports a time that cannot be booked.
+type SlotError struct {
+	Start    time.Time  `json:"start"`
+	Reason   Reason     `json:"reason"`
+	Earliest *time.Time `json:"earliest,omitempty"` // ReasonTooSoon: first time that could be booked
+	length   time.Duration
+	horizon  time.Duration
+}
+
+func (e *SlotError) Error() string {
+	at := e.Start.Format(time.RFC3339)
+	switch e.Reason {
+	case ReasonClosed:
+		return fmt.Sprintf("the kitchen is closed at %s", at)
+	case ReasonNotSlot:
+		return fmt.Sprintf("%s is not the start of a slot; slots are %s long", at, e.length)
+	case ReasonTooSoon:
+		return fmt.Sprintf("slot %s starts too soon; the earliest bookable time is %s", at, e.Earliest.Format(time.RFC3339))
+	case ReasonTooFar:
+		return fmt.Sprintf("slot %s is more than %s ahead", at, e.horizon)
+	}
+	return fmt.Sprintf("slot %s is full", at)
+}
+
+func (e *SlotError) Is(target error) bool { return target == ErrUnavailable }
+
+// Catalogue generates slots from Config and records bookings.
+type Catalogue struct {
+	cfg Config
+
+	mu      sync.Mutex
+	booked  map[int64]int    // slot start (Unix) → orders
+	byOrder map[string]int64 // orderID → slot start (Unix)
+}
+
+// New validates cfg and returns an empty catalogue.
+func New(cfg Config) (*Catalogue, error) {
+	if cfg.Length <= 0 {
+		return nil, fmt.Errorf("slot length must be > 0, got %s", cfg.Length)
+	}
+	if cfg.Capacity < 0 || cfg.LeadTime < 0 || cfg.Horizon <= 0 {
+		return nil, fmt.Errorf("slot capacity and lead time must be >= 0 and horizon > 0")
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	return &Catalogue{cfg: cfg, booked: make(map[int64]int), byOrder: make(map[string]int64)}, nil
+}
+
+// Location is the time zone of the opening hours.
+func (c *Catalogue) Location() *time.Location { return c.cfg.Location }
+
+// Slots returns the slots starting in [from, to) that can be booked at now
+// (within lead time and horizon), in time order, including full ones.
+func (c *Catalogue) Slots(now, from, to time.Time) []Slot {
+	if earliest := now.Add(c.cfg.LeadTime); from.Before(earliest) {
+		from = earliest
+	}
+	if latest := now.Add(c.cfg.Horizon); to.After(latest) {
+		to = latest.Add(time.Nanosecond) // the horizon itself is bookable
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var out []Slot
+	seen := make(map[int64]bool)
+	// the day before from, for periods that run past midnight
+	for day := midnight(from.In(c.cfg.Location)).AddDate(0, 0, -1); day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, iv := range c.cfg.Hours[day.Weekday()] {
+			open, close := at(day, iv.Start), at(day, iv.End)
+			for start := open; !start.Add(c.cfg.Length).After(close); start = start.Add(c.cfg.Length) {
+				if start.Before(from) || !start.Before(to) || seen[start.Unix()] {
+					continue
+				}
+				seen[start.Unix()] = true
+				out = append(out, c.slot(start))
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+// Reserve books the slot starting at start for orderID, replacing any
+// earlier booking of the order. It returns a *SlotError if start is not a
+// slot, is outside the bookable window at now, or is full.
+func (c *Catalogue) Reserve(orderID string, start, now time.Time) (Slot, error) {
+	start = start.In(c.cfg.Location)
+	if reason, ok := c.locate(start); !ok {
+		return Slot{}, &SlotError{Start: start, Reason: reason, length: c.cfg.Length}
+	}
+	if earliest := now.Add(c.cfg.LeadTime).In(c.cfg.Location); start.Before(earliest) {
+		return Slot{}, &SlotError{Start: start, Reason: ReasonTooSoon, Earliest: &earliest}
+	}
+	if start.After(now.Add(c.cfg.Horizon)) {
+		return Slot{}, &SlotError{Start: start, Reason: ReasonTooFar, horizon: c.cfg.Horizon}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sweep(now)
+	prev, had := c.byOrder[orderID]
+	if had && prev == start.Unix() {
+		return c.slot(start), nil
+	}
+	if s := c.slot(start); s.Full() {
+		return Slot{}, &SlotError{Start: start, Reason: ReasonFull}
+	}
+	if had {
+		c.unbook(orderID, prev)
+	}
+	c.booked[start.Unix()]++
+	c.byOrder[orderID] = start.Unix()
+	return c.slot(start), nil
+}
+
+// Release cancels orderID's booking, if any.
+func (c *Catalogue) Release(orderID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if start, ok := c.byOrder[orderID]; ok {
+		c.unbook(orderID, start)
+	}
+}
+
+// locate reports whether t is the start of a slot, or why not.
+func (c *Catalogue) locate(t time.Time) (Reason, bool) {
+	for day := midnight(t).AddDate(0, 0, -1); !day.After(t); day = day.AddDate(0, 0, 1) {
+		for _, iv := range c.cfg.Hours[day.Weekday()] {
+			open, close := at(day, iv.Start), at(day, iv.End)
+			if t.Before(open) || !t.Before(close) {
+				continue
+			}
+			if t.Sub(open)%c.cfg.Length != 0 || t.Add(c.cfg.Length).After(close) {
+				return ReasonNotSlot, false
+			}
+			return "", true
+		}
+	}
+	return ReasonClosed, false
+}
+
+// slot describes the slot starting at start. Callers hold c.mu.
+func (c *Catalogue) slot(start time.Time) Slot {
+	return Slot{Start: start, End: start.Add(c.cfg.Length), Capacity: c.cfg.Capacity, Booked: c.booked[start.Unix()]}
+}
+
+// unbook removes orderID's booking of start. Callers hold c.mu.
+func (c *Catalogue) unbook(orderID string, start int64) {
+	delete(c.byOrder, orderID)
+	if c.booked[start]--; c.booked[start] <= 0 {
+		delete(c.booked, start)
+	}
+}
+
+// sweep forgets bookings of slots that started over a day ago. Callers hold c.mu.
+func (c *Catalogue) sweep(now time.Time) {
+	cutoff := now.Add(-24 * time.Hour).Unix()
+	for id, start := range c.byOrder {
+		if start < cutoff {
+			c.unbook(id, start)
+		}
+	}
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// at is the wall-clock time offset from day's midnight, so opening hours
+// keep their local times across daylight saving changes.
+func at(day time.Time, offset time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, int(offset/time.Second), 0, day.Location())
+}
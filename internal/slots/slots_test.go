@@ -0,0 +1,113 @@
+package slots
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseHours(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+		check   func(Hours) bool
+	}{
+		{spec: "mon-fri 11:00-14:00,17:00-22:00; sat 12:00-23:00", check: func(h Hours) bool {
+			return len(h[time.Monday]) == 2 && len(h[time.Saturday]) == 1 && len(h[time.Sunday]) == 0
+		}},
+		{spec: "fri-mon 10:00-11:00", check: func(h Hours) bool {
+			return len(h[time.Friday]) == 1 && len(h[time.Sunday]) == 1 && len(h[time.Monday]) == 1 && len(h[time.Tuesday]) == 0
+		}},
+		{spec: "SAT 18:00-02:00", check: func(h Hours) bool {
+			return h[time.Saturday][0] == Interval{Start: 18 * time.Hour, End: 26 * time.Hour}
+		}},
+		{spec: "sun 00:00-24:00", check: func(h Hours) bool { return h[time.Sunday][0].End == 24*time.Hour }},
+		{spec: "", check: func(h Hours) bool { return len(h[time.Monday]) == 0 }},
+		{spec: "mon 11:00", wantErr: true},
+		{spec: "mon 11:00-", wantErr: true},
+		{spec: "someday 11:00-12:00", wantErr: true},
+		{spec: "mon 9:5-12:00", wantErr: true},
+		{spec: "mon 24:00-02:00", wantErr: true},
+		{spec: "mon 11:00-13:00,12:00-14:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		h, err := ParseHours(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%q: err = %v, want error %v", tt.spec, err, tt.wantErr)
+		}
+		if err == nil && !tt.check(h) {
+			t.Fatalf("%q: unexpected hours %v", tt.spec, h)
+		}
+	}
+}
+
+func TestCatalogue(t *testing.T) {
+	hours, err := ParseHours("mon-fri 11:00-14:00,17:00-22:00; sat 18:00-02:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(Config{Hours: hours, Length: 30 * time.Minute, Capacity: 2, LeadTime: 30 * time.Minute, Horizon: 48 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, min int) time.Time { return time.Date(2025, 6, day, hour, min, 0, 0, time.UTC) } // June 2 is a Monday
+	now := at(2, 10, 50)
+
+	monday := c.Slots(now, at(2, 0, 0), at(3, 0, 0))
+	if len(monday) != 15 || !monday[0].Start.Equal(at(2, 11, 30)) || !monday[14].End.Equal(at(2, 22, 0)) {
+		t.Fatalf("unexpected monday slots: %d from %v", len(monday), monday[0])
+	}
+
+	tests := []struct {
+		name   string
+		order  string
+		start  time.Time
+		now    time.Time
+		reason Reason // "" = booked
+	}{
+		{"within lead time", "a", at(2, 11, 0), now, ReasonTooSoon},
+		{"not a slot start", "a", at(2, 11, 45), now, ReasonNotSlot},
+		{"last slot must fit", "a", at(2, 13, 45), now, ReasonNotSlot},
+		{"between periods", "a", at(2, 15, 0), now, ReasonClosed},
+		{"beyond horizon", "a", at(4, 12, 0), now, ReasonTooFar},
+		{"first booking", "a", at(2, 13, 30), now, ""},
+		{"rebooking is a no-op", "a", at(2, 13, 30), now, ""},
+		{"second booking", "b", at(2, 13, 30), now, ""},
+		{"full", "c", at(2, 13, 30), now, ReasonFull},
+		{"moving frees the old slot", "a", at(2, 17, 0), now, ""},
+		{"freed place", "c", at(2, 13, 30), now, ""},
+	}
+	for _, tt := range tests {
+		slot, err := c.Reserve(tt.order, tt.start, tt.now)
+		var se *SlotError
+		switch {
+		case tt.reason == "" && err != nil:
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		case tt.reason == "" && !slot.Start.Equal(tt.start):
+			t.Fatalf("%s: booked %v", tt.name, slot)
+		case tt.reason != "" && (!errors.As(err, &se) || se.Reason != tt.reason || !errors.Is(err, ErrUnavailable)):
+			t.Fatalf("%s: err = %v, want %s", tt.name, err, tt.reason)
+		}
+	}
+
+	c.Release("b")
+	c.Release("b")
+	for _, s := range c.Slots(now, at(2, 13, 30), at(2, 17, 30)) {
+		want := map[int]int{13: 1, 17: 1}[s.Start.Hour()]
+		if s.Booked != want {
+			t.Fatalf("slot %v booked %d, want %d", s.Start, s.Booked, want)
+		}
+	}
+
+	// saturday's period closes on sunday; bookings of past days are forgotten
+	saturday := at(7, 12, 0)
+	if _, err := c.Reserve("d", at(8, 1, 30), saturday); err != nil {
+		t.Fatalf("slot past midnight: %v", err)
+	}
+	if _, err := c.Reserve("d", at(8, 2, 0), saturday); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("booked closing time: %v", err)
+	}
+	if len(c.byOrder) != 1 {
+		t.Fatalf("old bookings kept: %v", c.byOrder)
+	}
+}
@@ -0,0 +1,149 @@
+// Package tax maps products to tax rates and computes the tax of order
+// lines in tax-exclusive or tax-inclusive price mode.
+package tax
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
+)
+
+// Mode says whether product prices include tax.
+type Mode string
+
+const (
+	Exclusive Mode = "exclusive" // tax is added on top of prices
+	Inclusive Mode = "inclusive" // prices already contain the tax
+)
+
+// ParseMode validates s ("" = Exclusive).
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case "":
+		return Exclusive, nil
+	case Exclusive, Inclusive:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown tax mode %q (want exclusive or inclusive)", s)
+}
+
+// Rules maps products to tax rates. A product is taxed at the rate of its
+// TaxClass, else of its category's class, else at DefaultRate. Categories
+// match case-insensitively.
+type Rules struct {
+	Mode        Mode               `json:"mode"`
+	DefaultRate float64            `json:"defaultRate"` // percent
+	Classes     map[string]float64 `json:"classes"`     // tax class → rate in percent
+	Categories  map[string]string  `json:"categories"`  // product category → tax class
+	Rounding    money.RoundingMode `json:"rounding"`    // of each line's tax ("" = half up)
+}
+
+// Validate checks the mode, rounding, rates, that every class a category
+// refers to exists and that no two categories differ only in case.
+func (r *Rules) Validate() error {
+	if _, err := ParseMode(string(r.Mode)); err != nil {
+		return fmt.Errorf("tax: %w", err)
+	}
+	if _, err := money.ParseRoundingMode(string(r.Rounding)); err != nil {
+		return fmt.Errorf("tax: %w", err)
+	}
+	if r.DefaultRate < 0 {
+		return fmt.Errorf("tax: default rate must be >= 0, got %g", r.DefaultRate)
+	}
+	for class, rate := range r.Classes {
+		if class == "" || rate < 0 {
+			return fmt.Errorf("tax: class %q: rate must be >= 0 and the name non-empty, got %g", class, rate)
+		}
+	}
+	seen := make(map[string]string, len(r.Categories))
+	for category, class := range r.Categories {
+		if _, ok := r.Classes[class]; !ok {
+			return fmt.Errorf("tax: category %q refers to unknown class %q", category, class)
+		}
+		key := strings.ToLower(category)
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("tax: categories %q and %q differ only in case", other, category)
+		}
+		seen[key] = category
+	}
+	return nil
+}
+
+// Check reports whether p can be taxed: a TaxClass it sets must be one of
+// the rules' classes.
+func (r *Rules) Check(p models.Product) error {
+	if _, ok := r.Classes[p.TaxClass]; p.TaxClass != "" && !ok {
+		return fmt.Errorf("tax: product %s: unknown tax class %q", p.ID, p.TaxClass)
+	}
+	return nil
+}
+
+// CheckProducts runs Check on every product and returns the first error.
+func (r *Rules) CheckProducts(products []models.Product) error {
+	for _, p := range products {
+		if err := r.Check(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFile reads rules from a JSON file on top of base: fields the file
+// sets replace base's, the others are kept.
+func LoadFile(filename string, base Rules) (*Rules, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("tax: %w", err)
+	}
+	rules := base
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("tax: %s: %w", filename, err)
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// Rate returns p's tax class ("" for the default rate) and rate in percent.
+// p must pass Check.
+func (r *Rules) Rate(p models.Product) (class string, rate float64) {
+	if p.TaxClass != "" {
+		return p.TaxClass, r.Classes[p.TaxClass]
+	}
+	if class, ok := r.categoryClass(p.Category); ok {
+		return class, r.Classes[class]
+	}
+	return "", r.DefaultRate
+}
+
+// categoryClass returns the class category maps to, ignoring case.
+func (r *Rules) categoryClass(category string) (string, bool) {
+	if class, ok := r.Categories[category]; ok {
+		return class, true
+	}
+	for c, class := range r.Categories {
+		if strings.EqualFold(c, category) {
+			return class, true
+		}
+	}
+	return "", false
+}
+
+// Tax returns the tax at rate on amount: on top of it in Exclusive mode,
+// contained in it in Inclusive mode. It also returns the net amount taxed.
+func (r *Rules) Tax(amount money.Money, rate float64) (tax, net money.Money) {
+	rounding := r.Rounding
+	if rounding == "" {
+		rounding = money.HalfUp
+	}
+	if r.Mode == Inclusive {
+		tax = amount.IncludedPercent(rate, rounding)
+		return tax, amount.Sub(tax)
+	}
+	return amount.Percent(rate, rounding), amount
+}
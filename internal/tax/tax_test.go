@@ -0,0 +1,140 @@
+package tax
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Mode
+		wantErr bool
+	}{
+		{"", Exclusive, false},
+		{"exclusive", Exclusive, false},
+		{"inclusive", Inclusive, false},
+		{"Inclusive", "", true},
+		{"gross", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMode(%q) = %q, %v; want %q (err %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRules_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   Rules
+		wantErr bool
+	}{
+		{"zero value", Rules{}, false},
+		{"classes and categories", Rules{Mode: Inclusive, DefaultRate: 20,
+			Classes: map[string]float64{"food": 5}, Categories: map[string]string{"Salad": "food"}}, false},
+		{"unknown mode", Rules{Mode: "gross"}, true},
+		{"unknown rounding", Rules{Rounding: "sideways"}, true},
+		{"negative default", Rules{DefaultRate: -1}, true},
+		{"negative class rate", Rules{Classes: map[string]float64{"food": -5}}, true},
+		{"unnamed class", Rules{Classes: map[string]float64{"": 5}}, true},
+		{"category of unknown class", Rules{Categories: map[string]string{"Salad": "food"}}, true},
+		{"categories differing in case", Rules{Classes: map[string]float64{"food": 5},
+			Categories: map[string]string{"Salad": "food", "salad": "food"}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.rules.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRules_Rate(t *testing.T) {
+	r := Rules{
+		DefaultRate: 20,
+		Classes:     map[string]float64{"food": 5, "zero": 0},
+		Categories:  map[string]string{"Salad": "food"},
+	}
+	tests := []struct {
+		product   models.Product
+		wantClass string
+		wantRate  float64
+	}{
+		{models.Product{Category: "Salad"}, "food", 5},
+		{models.Product{Category: "Salad", TaxClass: "zero"}, "zero", 0}, // class beats category
+		{models.Product{Category: "SALAD"}, "food", 5},                   // categories ignore case
+		{models.Product{Category: "Dessert"}, "", 20},
+	}
+	for _, tt := range tests {
+		class, rate := r.Rate(tt.product)
+		if class != tt.wantClass || rate != tt.wantRate {
+			t.Errorf("Rate(%+v) = %q, %g; want %q, %g", tt.product, class, rate, tt.wantClass, tt.wantRate)
+		}
+	}
+}
+
+func TestRules_CheckProducts(t *testing.T) {
+	r := Rules{Classes: map[string]float64{"food": 5}}
+	known := []models.Product{{ID: "1"}, {ID: "2", TaxClass: "food"}}
+	if err := r.CheckProducts(known); err != nil {
+		t.Fatalf("CheckProducts(known classes) = %v, want nil", err)
+	}
+	err := r.CheckProducts(append(known, models.Product{ID: "3", Category: "Salad", TaxClass: "luxury"}))
+	if err == nil || !strings.Contains(err.Error(), `product 3: unknown tax class "luxury"`) {
+		t.Fatalf("CheckProducts(unknown class) = %v, want an error naming product 3", err)
+	}
+}
+
+func TestRules_Tax(t *testing.T) {
+	tests := []struct {
+		mode     Mode
+		rounding money.RoundingMode
+		amount   string
+		rate     float64
+		wantTax  string
+		wantNet  string
+	}{
+		{Exclusive, "", "25.98", 5, "1.30", "25.98"}, // 1.299
+		{Exclusive, money.Down, "25.98", 5, "1.29", "25.98"},
+		{Inclusive, "", "12.00", 20, "2.00", "10.00"}, // 12 × 20/120
+		{Inclusive, "", "25.98", 5, "1.24", "24.74"},  // 1.2371…
+		{Inclusive, "", "9.99", 0, "0.00", "9.99"},
+	}
+	for _, tt := range tests {
+		r := Rules{Mode: tt.mode, Rounding: tt.rounding}
+		tax, net := r.Tax(money.MustParse(tt.amount, "USD"), tt.rate)
+		if tax.Decimal() != tt.wantTax || net.Decimal() != tt.wantNet {
+			t.Errorf("%s Tax(%s, %g) = %s, %s; want %s, %s", tt.mode, tt.amount, tt.rate, tax.Decimal(), net.Decimal(), tt.wantTax, tt.wantNet)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tax.json")
+	data := `{"mode": "inclusive", "classes": {"food": 5}, "categories": {"Salad": "food"}}`
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := LoadFile(file, Rules{DefaultRate: 8.25, Rounding: money.HalfEven})
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if r.Mode != Inclusive || r.DefaultRate != 8.25 || r.Rounding != money.HalfEven || r.Classes["food"] != 5 {
+		t.Fatalf("rules = %+v: want the file's mode and classes on top of the base", r)
+	}
+
+	if err := os.WriteFile(file, []byte(`{"categories": {"Salad": "food"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(file, Rules{}); err == nil {
+		t.Fatalf("expected an error for a category of an unknown class")
+	}
+}
@@ -2,18 +2,22 @@
 package testutil
 
 import (
+	"context"
 	"errors"
 
+	"github.com/google/uuid"
+
 	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/money"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/promovalidator"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/repository"
 )
 
 func SeedProducts() []models.Product {
 	return []models.Product{
-		{ID: "1", Name: "Chicken Waffle", Price: 12.99, Category: "Waffle"},
-		{ID: "2", Name: "Belgian Waffle", Price: 9.99, Category: "Waffle"},
-		{ID: "3", Name: "Caesar Salad", Price: 8.99, Category: "Salad"},
+		{ID: "1", Name: "Chicken Waffle", Price: money.MustParse("12.99", ""), Category: "Waffle"},
+		{ID: "2", Name: "Belgian Waffle", Price: money.MustParse("9.99", ""), Category: "Waffle"},
+		{ID: "3", Name: "Caesar Salad", Price: money.MustParse("8.99", ""), Category: "Salad"},
 	}
 }
 
@@ -82,7 +86,7 @@ func (r *ProductRepoStub) GetByID(id string) (*models.Product, error) {
 
 type OrderRepoStub struct {
 	Stored *models.Order
-	Err    error // if set, CreateOrder returns this error
+	Err    error // if set, CreateOrder and UpdateOrder return this error
 }
 
 func NewOrderRepoStub() *OrderRepoStub { return &OrderRepoStub{} }
@@ -97,6 +101,9 @@ func (r *OrderRepoStub) CreateOrder(o *models.Order) (*models.Order, error) {
 }
 
 func (r *OrderRepoStub) FindByID(id string) (*models.Order, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, repository.ErrInvalidOrderID
+	}
 	if r.Stored != nil && r.Stored.ID == id {
 		cp := *r.Stored
 		return &cp, nil
@@ -104,15 +111,66 @@ func (r *OrderRepoStub) FindByID(id string) (*models.Order, error) {
 	return nil, repository.ErrOrderNotFound
 }
 
+func (r *OrderRepoStub) UpdateOrder(id string, update func(*models.Order) error) (*models.Order, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, repository.ErrInvalidOrderID
+	}
+	if r.Stored == nil || r.Stored.ID != id {
+		return nil, repository.ErrOrderNotFound
+	}
+	cp := *r.Stored
+	cp.StatusHistory = append([]models.StatusChange(nil), cp.StatusHistory...)
+	if err := update(&cp); err != nil {
+		return nil, err
+	}
+	r.Stored = &cp
+	out := cp
+	return &out, nil
+}
+
+// ListOrders returns the stored order if it matches q's filters.
+func (r *OrderRepoStub) ListOrders(q repository.OrderQuery) (repository.OrderPage, error) {
+	page := repository.OrderPage{Orders: []models.Order{}}
+	if r.Stored != nil && q.Matches(r.Stored) && (q.After == nil || q.After.After(r.Stored)) {
+		page.Orders = append(page.Orders, *r.Stored)
+	}
+	return page, nil
+}
+
+var _ repository.OrderRepository = (*OrderRepoStub)(nil)
+
 type ValidatorStub struct {
-	Valid bool
-	Err   error // if set, LoadCouponFiles returns this error
+	Valid  bool
+	Reason promovalidator.Reason // reported by CheckPromoCode when !Valid (default: insufficient_matches)
+	Err    error                 // if set, LoadCouponFiles returns this error
+	Files  []promovalidator.FileHealth
 }
 
 var _ promovalidator.ValidatorService = (*ValidatorStub)(nil)
 
 func (v *ValidatorStub) LoadCouponFiles() error        { return v.Err }
 func (v *ValidatorStub) ValidatePromoCode(string) bool { return v.Valid }
+func (v *ValidatorStub) ValidatePromoCodeContext(ctx context.Context, code string) bool {
+	return v.CheckPromoCode(ctx, code).Valid
+}
+func (v *ValidatorStub) Health() []promovalidator.FileHealth { return v.Files }
+func (v *ValidatorStub) CheckPromoCode(ctx context.Context, code string) promovalidator.Result {
+	res := promovalidator.Result{Code: code, MinLen: 8, MaxLen: 10, Required: 2, Files: 3}
+	switch {
+	case ctx.Err() != nil:
+		res.Reason = promovalidator.ReasonAborted
+	case v.Valid:
+		res.Valid, res.Reason, res.Hits = true, promovalidator.ReasonValid, 2
+	case v.Reason != "":
+		res.Reason = v.Reason
+	default:
+		res.Reason = promovalidator.ReasonNotEnoughHits
+	}
+	return res
+}
 
 var (
 	ErrRepoDown = errors.New("db down")
@@ -2,11 +2,21 @@ package transporthttp
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Niraj-Shaw/orderfoodonline/internal/idempotency"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/promoguard"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/promovalidator"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/repository"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/service"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/slots"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/util"
 	"github.com/gorilla/mux"
 )
@@ -14,20 +24,62 @@ import (
 type Handlers struct {
 	productService *service.ProductService
 	orderService   *service.OrderService
+	validator      promovalidator.ValidatorService
 	logger         util.Logger
+
+	guard      *promoguard.Guard // nil: no lockouts
+	trustProxy bool              // client IP from X-Forwarded-For
+
+	idempotency *idempotency.Store // nil: Idempotency-Key is ignored
+}
+
+// maxBodyBytes caps JSON request bodies; larger ones get 413.
+const maxBodyBytes = 1 << 20
+
+// HandlerOption configures optional Handlers behaviour.
+type HandlerOption func(*Handlers)
+
+// WithPromoGuard locks out API keys and client IPs that keep submitting
+// unknown promo codes. trustProxy takes the client IP from X-Forwarded-For,
+// which is only safe behind a proxy that sets it.
+func WithPromoGuard(g *promoguard.Guard, trustProxy bool) HandlerOption {
+	return func(h *Handlers) { h.guard, h.trustProxy = g, trustProxy }
+}
+
+// WithIdempotency honours Idempotency-Key on order placement using store.
+func WithIdempotency(store *idempotency.Store) HandlerOption {
+	return func(h *Handlers) { h.idempotency = store }
 }
 
 func NewHandlers(
 	productService *service.ProductService,
 	orderService *service.OrderService,
+	validator promovalidator.ValidatorService,
 	logger util.Logger,
+	opts ...HandlerOption,
 ) *Handlers {
-	return &Handlers{productService: productService, orderService: orderService, logger: logger}
+	h := &Handlers{
+		productService: productService,
+		orderService:   orderService,
+		validator:      validator,
+		logger:         logger,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // GET /healthz
+// Reports "degraded" when some coupon files are missing or unreadable and
+// "failed" when none can be read, still with 200: the API keeps serving.
+// Per-file detail (names, paths in errors) is only on GET /api/promo/status.
 func (h *Handlers) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	h.sendJSON(w, http.StatusOK, models.HealthResponse{Status: "ok"})
+	resp := models.HealthResponse{Status: promovalidator.HealthOK}
+	if hr, ok := h.validator.(promovalidator.HealthReporter); ok {
+		resp.Status = promovalidator.HealthStatus(hr.Health())
+	}
+	h.sendJSON(w, http.StatusOK, resp)
 }
 
 // GET /api/product
@@ -58,22 +110,313 @@ func (h *Handlers) GetProduct(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, p)
 }
 
+// GET /api/slots?date=YYYY-MM-DD
+// Slots that can still take a scheduled order; without a date, all of them
+// up to the booking horizon.
+func (h *Handlers) ListSlots(w http.ResponseWriter, r *http.Request) {
+	list, err := h.orderService.ListSlots(r.URL.Query().Get("date"))
+	switch {
+	case errors.Is(err, service.ErrSchedulingDisabled):
+		h.sendError(w, http.StatusNotFound, "error", "Scheduled orders not enabled")
+		return
+	case service.IsValidationError(err):
+		h.sendError(w, http.StatusBadRequest, "error", err.Error())
+		return
+	case err != nil:
+		h.logger.Errorf("list slots: %v", err)
+		h.sendError(w, http.StatusInternalServerError, "error", "internal server error")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, struct {
+		Slots []slots.Slot `json:"slots"`
+	}{list})
+}
+
 // POST /api/order  (requires api_key via middleware)
 func (h *Handlers) PlaceOrder(w http.ResponseWriter, r *http.Request) {
 	var req models.OrderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, http.StatusBadRequest, "error", "Invalid input")
+	if !h.decodeBody(w, r, &req, false) {
 		return
 	}
+	req.APIKey = r.Header.Get("api_key")
+
+	// Only promo attempts are throttled; a locked-out client can still order without a code.
+	// Check reserves the attempt, which is settled as a failure or released below.
+	var subjects []promoguard.Subject
+	failed := false
+	if req.CouponCode != "" && h.guard != nil {
+		subjects = []promoguard.Subject{promoguard.APIKey(req.APIKey), promoguard.IP(h.clientIP(r))}
+		if wait, locked := h.guard.Check(subjects...); locked {
+			secs := int((wait + time.Second - 1) / time.Second)
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+			h.sendError(w, http.StatusTooManyRequests, "too_many_requests",
+				"too many invalid promo codes; retry after "+strconv.Itoa(secs)+"s")
+			return
+		}
+		defer func() {
+			if failed {
+				h.guard.Failure(subjects...)
+			} else {
+				h.guard.Release(subjects...)
+			}
+		}()
+	}
 
-	order, err := h.orderService.PlaceOrder(req)
+	order, err := h.orderService.PlaceOrderContext(r.Context(), req)
+	if err != nil && r.Context().Err() != nil {
+		h.logger.Warnf("place order aborted: %v", err) // client went away; nobody to answer
+		return
+	}
+	if errors.Is(err, service.ErrMisconfigured) {
+		h.logger.Errorf("place order: %v", err)
+		h.sendError(w, http.StatusInternalServerError, "error", "internal server error")
+		return
+	}
 	if err != nil {
+		var ve *service.ValidationError
+		if errors.As(err, &ve) && ve.Details != nil {
+			if res, ok := ve.Details.(promovalidator.Result); ok {
+				failed = guessed(res.Reason)
+			}
+			h.logger.With("details", ve.Details).Infof("place order rejected: %v", err)
+			h.sendErrorDetails(w, http.StatusUnprocessableEntity, "validation_error", err.Error(), ve.Details)
+			return
+		}
 		h.sendError(w, http.StatusUnprocessableEntity, "validation_error", err.Error())
 		return
 	}
 	h.sendJSON(w, http.StatusOK, order)
 }
 
+// GET /api/order/{orderId}  (requires api_key via middleware; only the
+// caller's own orders)
+func (h *Handlers) GetOrder(w http.ResponseWriter, r *http.Request) {
+	order, err := h.orderService.GetOwnOrder(mux.Vars(r)["orderId"], r.Header.Get("api_key"))
+	if err != nil {
+		h.sendOrderError(w, "get order", err)
+		return
+	}
+	h.sendJSON(w, http.StatusOK, order)
+}
+
+// GET /api/order  (requires api_key via middleware; only the caller's own
+// orders) Query: status (comma-separated or repeated), from / to (RFC 3339 or
+// YYYY-MM-DD, on createdAt), sort (createdAt, -createdAt, updatedAt, -updatedAt), limit, cursor.
+func (h *Handlers) ListOrders(w http.ResponseWriter, r *http.Request) {
+	opts, bad := listOptions(r)
+	if bad != "" {
+		h.sendError(w, http.StatusBadRequest, "error", "Invalid query parameter: "+bad)
+		return
+	}
+
+	list, err := h.orderService.ListOrders(opts)
+	var ve *service.ValidationError
+	if errors.As(err, &ve) {
+		h.sendError(w, http.StatusBadRequest, "error", err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("list orders: %v", err)
+		h.sendError(w, http.StatusInternalServerError, "error", "internal server error")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, list)
+}
+
+// listOptions parses the ListOrders query, returning the name of the first
+// malformed parameter if any. Values are checked further by the service.
+func listOptions(r *http.Request) (opts service.OrderListOptions, bad string) {
+	q := r.URL.Query()
+	opts.Sort = repository.OrderSort(q.Get("sort"))
+	opts.Cursor = q.Get("cursor")
+	for _, v := range q["status"] {
+		for _, st := range strings.Split(v, ",") {
+			if st = strings.TrimSpace(st); st != "" {
+				opts.Statuses = append(opts.Statuses, models.OrderStatus(st))
+			}
+		}
+	}
+
+	var err error
+	if v := q.Get("from"); v != "" {
+		if opts.From, err = parseQueryTime(v); err != nil {
+			return opts, "from"
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if opts.To, err = parseQueryTime(v); err != nil {
+			return opts, "to"
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil {
+			return opts, "limit"
+		}
+	}
+	opts.APIKey = r.Header.Get("api_key") // callers only ever see their own orders
+	return opts, ""
+}
+
+// parseQueryTime accepts RFC 3339 timestamps and plain dates (midnight UTC).
+func parseQueryTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
+
+// PATCH /api/order/{orderId}  (requires api_key via middleware; only the
+// caller's own orders)
+// Body: {"add": [...], "remove": ["productId"], "update": [...], "revision": n}.
+// Only orders not yet accepted can be modified; a revision other than the
+// order's current one is rejected so concurrent edits don't overwrite each other.
+func (h *Handlers) ModifyOrder(w http.ResponseWriter, r *http.Request) {
+	var mod models.OrderModification
+	if !h.decodeBody(w, r, &mod, false) {
+		return
+	}
+	order, err := h.orderService.ModifyOrder(r.Context(), mux.Vars(r)["orderId"], r.Header.Get("api_key"), mod)
+	if err != nil {
+		h.sendOrderError(w, "modify order", err)
+		return
+	}
+	h.sendJSON(w, http.StatusOK, order)
+}
+
+// POST /api/order/{orderId}/advance  (requires api_key via middleware; only
+// the caller's own orders)
+// Body (optional): {"status": "...", "reason": "..."}; without a status the
+// order moves to its normal next step.
+func (h *Handlers) AdvanceOrder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["orderId"]
+	req, ok := h.decodeStatusRequest(w, r)
+	if !ok {
+		return
+	}
+	order, err := h.orderService.AdvanceOrder(id, r.Header.Get("api_key"), req.Status, req.Reason)
+	if err != nil {
+		h.sendOrderError(w, "advance order", err)
+		return
+	}
+	h.sendJSON(w, http.StatusOK, order)
+}
+
+// POST /api/order/{orderId}/cancel  (requires api_key via middleware; only
+// the caller's own orders)
+// Body (optional): {"reason": "..."}
+func (h *Handlers) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["orderId"]
+	req, ok := h.decodeStatusRequest(w, r)
+	if !ok {
+		return
+	}
+	order, err := h.orderService.CancelOrder(id, r.Header.Get("api_key"), req.Reason)
+	if err != nil {
+		h.sendOrderError(w, "cancel order", err)
+		return
+	}
+	h.sendJSON(w, http.StatusOK, order)
+}
+
+// decodeStatusRequest reads an optional OrderStatusRequest body.
+func (h *Handlers) decodeStatusRequest(w http.ResponseWriter, r *http.Request) (models.OrderStatusRequest, bool) {
+	var req models.OrderStatusRequest
+	return req, h.decodeBody(w, r, &req, true)
+}
+
+// decodeBody decodes r's JSON body, of at most maxBodyBytes, into v. On
+// failure it answers 413 or 400 and returns false. An empty body is accepted
+// when optional.
+func (h *Handlers) decodeBody(w http.ResponseWriter, r *http.Request, v any, optional bool) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(v)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil, optional && errors.Is(err, io.EOF):
+		return true
+	case errors.As(err, &tooLarge):
+		h.sendError(w, http.StatusRequestEntityTooLarge, "error", "Request body too large")
+	default:
+		h.sendError(w, http.StatusBadRequest, "error", "Invalid input")
+	}
+	return false
+}
+
+// sendOrderError maps errors of operations on an existing order to responses.
+func (h *Handlers) sendOrderError(w http.ResponseWriter, op string, err error) {
+	var te *service.TransitionError
+	var ee *service.EditConflictError
+	var ve *service.ValidationError
+	switch {
+	case errors.Is(err, repository.ErrInvalidOrderID):
+		h.sendError(w, http.StatusBadRequest, "error", "Invalid ID supplied")
+	case errors.Is(err, repository.ErrOrderNotFound):
+		h.sendError(w, http.StatusNotFound, "error", "Order not found")
+	case errors.As(err, &te):
+		h.sendErrorDetails(w, http.StatusConflict, "invalid_transition", err.Error(), te)
+	case errors.As(err, &ee):
+		h.sendErrorDetails(w, http.StatusConflict, "edit_conflict", err.Error(), ee)
+	case errors.As(err, &ve):
+		h.sendErrorDetails(w, http.StatusUnprocessableEntity, "validation_error", err.Error(), ve.Details)
+	default:
+		h.logger.Errorf("%s: %v", op, err)
+		h.sendError(w, http.StatusInternalServerError, "error", "internal server error")
+	}
+}
+
+// guessed reports whether a promo rejection counts towards a lockout: the code
+// does not exist. Codes that exist but are out of schedule or used up, and
+// validator trouble, are not the client's doing.
+func guessed(reason promovalidator.Reason) bool {
+	switch reason {
+	case promovalidator.ReasonNotEnoughHits, promovalidator.ReasonLength, promovalidator.ReasonCharset:
+		return true
+	}
+	return false
+}
+
+// clientIP is the request's peer address, or the first X-Forwarded-For hop
+// when the proxy is trusted.
+func (h *Handlers) clientIP(r *http.Request) string {
+	if h.trustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// GET /api/promo/status  (requires api_key via middleware)
+func (h *Handlers) PromoStatus(w http.ResponseWriter, r *http.Request) {
+	sr, ok := h.validator.(promovalidator.StatusReporter)
+	if !ok {
+		h.sendError(w, http.StatusNotFound, "error", "Promo status not available")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, sr.Status())
+}
+
+// GET /api/promo/lockouts  (requires api_key via middleware)
+func (h *Handlers) PromoLockouts(w http.ResponseWriter, r *http.Request) {
+	if h.guard == nil {
+		h.sendError(w, http.StatusNotFound, "error", "Promo lockouts not enabled")
+		return
+	}
+	events := h.guard.Events()
+	if events == nil {
+		events = []promoguard.LockoutEvent{}
+	}
+	h.sendJSON(w, http.StatusOK, struct {
+		Locked int                       `json:"locked"`
+		Events []promoguard.LockoutEvent `json:"events"`
+	}{h.guard.Locked(), events})
+}
+
 func (h *Handlers) sendJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -83,11 +426,19 @@ func (h *Handlers) sendJSON(w http.ResponseWriter, status int, v any) {
 }
 
 func (h *Handlers) sendError(w http.ResponseWriter, status int, typ, msg string) {
+	h.sendErrorDetails(w, status, typ, msg, nil)
+}
+
+func (h *Handlers) sendErrorDetails(w http.ResponseWriter, status int, typ, msg string, details any) {
+	if res, ok := details.(promovalidator.Result); ok {
+		details = res.Public() // file names and hit counts stay in the logs
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(models.ApiResponse{
 		Code:    status,
 		Type:    typ,
 		Message: msg,
+		Details: details,
 	})
 }
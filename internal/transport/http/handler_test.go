@@ -5,13 +5,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	"github.com/Niraj-Shaw/orderfoodonline/internal/config"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/promoguard"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/promovalidator"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/service"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/slots"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/testutil"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/util"
 )
@@ -28,7 +33,7 @@ func setupHandlers(validatorValid bool) (*Handlers, *config.Config, util.Logger)
 	logger := util.NewLogger()
 	cfg := &config.Config{APIKey: "apitest"}
 
-	return NewHandlers(prodSvc, ordSvc, logger), cfg, logger
+	return NewHandlers(prodSvc, ordSvc, validator, logger), cfg, logger
 }
 
 func TestHandlers(t *testing.T) {
@@ -97,6 +102,19 @@ func TestHandlers(t *testing.T) {
 			apiKey:     "apitest",
 			wantStatus: http.StatusOK,
 		},
+		{
+			name:       "PromoStatus missing API key",
+			method:     http.MethodGet,
+			target:     "/api/promo/status",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "PromoStatus unavailable for validator without status",
+			method:     http.MethodGet,
+			target:     "/api/promo/status",
+			apiKey:     "apitest",
+			wantStatus: http.StatusNotFound,
+		},
 	}
 
 	for _, tt := range tests {
@@ -115,6 +133,7 @@ func TestHandlers(t *testing.T) {
 			secured := api.PathPrefix("").Subrouter()
 			secured.Use(APIKeyMiddleware(cfg.APIKey, logger))
 			secured.HandleFunc("/order", h.PlaceOrder).Methods(http.MethodPost)
+			secured.HandleFunc("/promo/status", h.PromoStatus).Methods(http.MethodGet)
 
 			// build request
 			var req *http.Request
@@ -135,6 +154,22 @@ func TestHandlers(t *testing.T) {
 				t.Fatalf("want %d, got %d. Body=%s", tt.wantStatus, rec.Code, rec.Body.String())
 			}
 
+			// promo rejections carry the validator's reason
+			if tt.name == "PlaceOrder invalid promo code" {
+				var apiErr struct {
+					Details map[string]any `json:"details"`
+				}
+				_ = json.Unmarshal(rec.Body.Bytes(), &apiErr)
+				if apiErr.Details["reason"] == "" || apiErr.Details["reason"] == nil {
+					t.Errorf("expected promo rejection details, got %s", rec.Body.String())
+				}
+				for k := range apiErr.Details {
+					if k != "code" && k != "valid" && k != "reason" {
+						t.Errorf("promo rejection leaks %q to clients: %s", k, rec.Body.String())
+					}
+				}
+			}
+
 			// extra sanity checks on 200s
 			if tt.wantStatus == http.StatusOK {
 				if tt.target == "/api/product" {
@@ -155,3 +190,336 @@ func TestHandlers(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthCheck_AggregateStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []promovalidator.FileHealth
+		want  string
+	}{
+		{"all ok", []promovalidator.FileHealth{{Name: "couponbase1.gz", Status: promovalidator.FileOK}}, "ok"},
+		{"one missing", []promovalidator.FileHealth{
+			{Name: "couponbase1.gz", Status: promovalidator.FileOK},
+			{Name: "couponbase3.gz", Status: promovalidator.FileMissing},
+		}, "degraded"},
+		{"none readable", []promovalidator.FileHealth{
+			{Name: "couponbase1.gz", Status: promovalidator.FileError, LastError: "open /data/couponbase1.gz: permission denied"},
+		}, "failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prodSvc := service.NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+			validator := &testutil.ValidatorStub{Files: tt.files}
+			ordSvc := service.NewOrderService(prodSvc, testutil.NewOrderRepoStub(), validator)
+			h := NewHandlers(prodSvc, ordSvc, validator, util.NewLogger())
+
+			rec := httptest.NewRecorder()
+			h.HealthCheck(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("want 200, got %d", rec.Code)
+			}
+			if body := rec.Body.String(); strings.Contains(body, "couponbase") || strings.Contains(body, "/data") {
+				t.Fatalf("health payload names coupon files: %s", body)
+			}
+			var got models.HealthResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Status != tt.want {
+				t.Fatalf("status = %q, want %q", got.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaceOrder_BodyTooLarge(t *testing.T) {
+	h, _, _ := setupHandlers(true)
+	body := `{"items":[{"productId":"1","quantity":1}],"notes":"` + strings.Repeat("x", maxBodyBytes) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/order", strings.NewReader(body))
+	req.Header.Set("api_key", "apitest")
+	rec := httptest.NewRecorder()
+	h.PlaceOrder(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("want 413, got %d", rec.Code)
+	}
+}
+
+func TestPlaceOrder_UnknownTaxClassIs500(t *testing.T) {
+	products := testutil.SeedProducts()
+	products[0].TaxClass = "luxury" // not in the (empty) tax rules
+	prodSvc := service.NewProductService(testutil.NewProductRepoStub(products))
+	validator := &testutil.ValidatorStub{Valid: true}
+	ordSvc := service.NewOrderService(prodSvc, testutil.NewOrderRepoStub(), validator)
+	h := NewHandlers(prodSvc, ordSvc, validator, util.NewLogger())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/order", bytes.NewBufferString(`{"items":[{"productId":"1","quantity":1}]}`))
+	req.Header.Set("api_key", "apitest")
+	rec := httptest.NewRecorder()
+	h.PlaceOrder(rec, req)
+
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "luxury") {
+		t.Fatalf("want a bare 500, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPlaceOrder_PromoLockout(t *testing.T) {
+	prodSvc := service.NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+	validator := &testutil.ValidatorStub{}
+	ordSvc := service.NewOrderService(prodSvc, testutil.NewOrderRepoStub(), validator)
+	guard := promoguard.New(promoguard.Config{MaxFailures: 2, MaxKeyFailures: 4, Lockout: 90 * time.Second})
+	h := NewHandlers(prodSvc, ordSvc, validator, util.NewLogger(), WithPromoGuard(guard, false))
+
+	order := func(body, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/order", bytes.NewBufferString(body))
+		req.Header.Set("api_key", "apitest")
+		req.RemoteAddr = ip + ":41000"
+		rec := httptest.NewRecorder()
+		h.PlaceOrder(rec, req)
+		return rec
+	}
+	const withCode = `{"items":[{"productId":"1","quantity":1}],"couponCode":"GUESS123"}`
+
+	// a successful order in between does not reset the count
+	if rec := order(withCode, "10.0.0.1"); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("attempt 1: want 422, got %d", rec.Code)
+	}
+	validator.Valid = true
+	if rec := order(withCode, "10.0.0.1"); rec.Code != http.StatusOK {
+		t.Fatalf("valid code: want 200, got %d", rec.Code)
+	}
+	validator.Valid = false
+	if rec := order(withCode, "10.0.0.1"); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("attempt 2: want 422, got %d", rec.Code)
+	}
+	rec := order(withCode, "10.0.0.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "90" {
+		t.Fatalf("want 429 with Retry-After 90, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// other clients sharing the API key are not locked out until the key's own threshold
+	for _, ip := range []string{"10.0.0.2", "10.0.0.3"} {
+		if rec := order(withCode, ip); rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("other IP %s: want 422, got %d", ip, rec.Code)
+		}
+	}
+	if rec := order(withCode, "10.0.0.4"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked-out key: want 429, got %d", rec.Code)
+	}
+
+	// orders without a code are not throttled
+	validator.Valid = true
+	if rec := order(`{"items":[{"productId":"1","quantity":1}]}`, "10.0.0.1"); rec.Code != http.StatusOK {
+		t.Fatalf("want 200 for order without promo code, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.PromoLockouts(rec, httptest.NewRequest(http.MethodGet, "/api/promo/lockouts", nil))
+	var got struct {
+		Locked int                       `json:"locked"`
+		Events []promoguard.LockoutEvent `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Locked != 2 || len(got.Events) != 2 || got.Events[0].Subject != "10.0.0.1" ||
+		got.Events[1].Kind != promoguard.KindAPIKey || strings.Contains(rec.Body.String(), "apitest") {
+		t.Fatalf("want the guessing IP and the key (fingerprinted) locked out, got %s", rec.Body.String())
+	}
+}
+
+func TestOrderStatusEndpoints(t *testing.T) {
+	h, cfg, logger := setupHandlers(true)
+	r := setupRouter(h, cfg, logger)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req.Header.Set("api_key", "apitest")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/order", `{"items":[{"productId":"1","quantity":1}]}`)
+	var placed models.Order
+	if err := json.Unmarshal(rec.Body.Bytes(), &placed); err != nil || placed.Status != models.StatusPlaced {
+		t.Fatalf("place order: %d %s", rec.Code, rec.Body.String())
+	}
+	base := "/api/order/" + placed.ID
+
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+		wantOrder  models.OrderStatus
+	}{
+		{"advance to next", base + "/advance", "", http.StatusOK, models.StatusAccepted},
+		{"advance to named status", base + "/advance", `{"status":"preparing"}`, http.StatusOK, models.StatusPreparing},
+		{"illegal transition", base + "/advance", `{"status":"delivered"}`, http.StatusConflict, ""},
+		{"unknown status", base + "/advance", `{"status":"eaten"}`, http.StatusUnprocessableEntity, ""},
+		{"bad body", base + "/cancel", `{"reason":`, http.StatusBadRequest, ""},
+		{"cancel", base + "/cancel", `{"reason":"too slow"}`, http.StatusOK, models.StatusCancelled},
+		{"cancel twice", base + "/cancel", "", http.StatusConflict, ""},
+		{"invalid id", "/api/order/abc/cancel", "", http.StatusBadRequest, ""},
+		{"unknown order", "/api/order/0b8f3a36-4c1e-4a5e-9a53-0f1f1f1f1f1f/advance", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := do(http.MethodPost, tt.target, tt.body)
+		if rec.Code != tt.wantStatus {
+			t.Fatalf("%s: want %d, got %d. Body=%s", tt.name, tt.wantStatus, rec.Code, rec.Body.String())
+		}
+		if tt.wantOrder != "" {
+			var got models.Order
+			_ = json.Unmarshal(rec.Body.Bytes(), &got)
+			if got.Status != tt.wantOrder {
+				t.Fatalf("%s: status = %s, want %s", tt.name, got.Status, tt.wantOrder)
+			}
+		}
+	}
+}
+
+func TestOrderLookupEndpoints(t *testing.T) {
+	h, cfg, logger := setupHandlers(true)
+	r := setupRouter(h, cfg, logger)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req.Header.Set("api_key", "apitest")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var placed models.Order
+	rec := do(http.MethodPost, "/api/order", `{"items":[{"productId":"1","quantity":1}]}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &placed); err != nil || placed.ID == "" {
+		t.Fatalf("place order: %d %s", rec.Code, rec.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantOrders int // list responses only
+	}{
+		{"get order", "/api/order/" + placed.ID, http.StatusOK, -1},
+		{"get invalid id", "/api/order/abc", http.StatusBadRequest, -1},
+		{"get unknown order", "/api/order/0b8f3a36-4c1e-4a5e-9a53-0f1f1f1f1f1f", http.StatusNotFound, -1},
+		{"list", "/api/order", http.StatusOK, 1},
+		{"list filtered", "/api/order?status=placed,accepted&from=2000-01-01&sort=createdAt&limit=5", http.StatusOK, 1},
+		{"list other status", "/api/order?status=delivered", http.StatusOK, 0},
+		{"list bad limit", "/api/order?limit=ten", http.StatusBadRequest, -1},
+		{"list bad date", "/api/order?from=yesterday", http.StatusBadRequest, -1},
+		{"list unknown sort", "/api/order?sort=price", http.StatusBadRequest, -1},
+	}
+	for _, tt := range tests {
+		rec := do(http.MethodGet, tt.target, "")
+		if rec.Code != tt.wantStatus {
+			t.Fatalf("%s: want %d, got %d. Body=%s", tt.name, tt.wantStatus, rec.Code, rec.Body.String())
+		}
+		if tt.wantOrders >= 0 {
+			var got models.OrderList
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got.Orders) != tt.wantOrders {
+				t.Fatalf("%s: want %d orders, got %s", tt.name, tt.wantOrders, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestModifyOrderEndpoint(t *testing.T) {
+	h, cfg, logger := setupHandlers(true)
+	r := setupRouter(h, cfg, logger)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req.Header.Set("api_key", "apitest")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var placed models.Order
+	rec := do(http.MethodPost, "/api/order", `{"items":[{"productId":"1","quantity":1}]}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &placed); err != nil || placed.ID == "" {
+		t.Fatalf("place order: %d %s", rec.Code, rec.Body.String())
+	}
+	base := "/api/order/" + placed.ID
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		wantStatus   int
+		wantRevision int
+	}{
+		{"add a drink", http.MethodPatch, base, `{"add":[{"productId":"2","quantity":1}],"revision":1}`, http.StatusOK, 2},
+		{"change quantity", http.MethodPatch, base, `{"update":[{"productId":"1","quantity":3}]}`, http.StatusOK, 3},
+		{"stale revision", http.MethodPatch, base, `{"remove":["2"],"revision":2}`, http.StatusConflict, 0},
+		{"product not in order", http.MethodPatch, base, `{"remove":["3"]}`, http.StatusUnprocessableEntity, 0},
+		{"bad body", http.MethodPatch, base, `{"add":`, http.StatusBadRequest, 0},
+		{"invalid id", http.MethodPatch, "/api/order/abc", `{"remove":["1"]}`, http.StatusBadRequest, 0},
+		{"accept", http.MethodPost, base + "/advance", "", http.StatusOK, 3},
+		{"too late", http.MethodPatch, base, `{"remove":["2"]}`, http.StatusConflict, 0},
+	}
+	for _, tt := range tests {
+		rec := do(tt.method, tt.target, tt.body)
+		if rec.Code != tt.wantStatus {
+			t.Fatalf("%s: want %d, got %d. Body=%s", tt.name, tt.wantStatus, rec.Code, rec.Body.String())
+		}
+		if tt.wantRevision != 0 {
+			var got models.Order
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got.Revision != tt.wantRevision || len(got.Revisions) != tt.wantRevision {
+				t.Fatalf("%s: want revision %d, got %s", tt.name, tt.wantRevision, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestListSlotsEndpoint(t *testing.T) {
+	hours, _ := slots.ParseHours("mon-sun 11:00-12:00")
+	catalogue, err := slots.New(slots.Config{Hours: hours, Length: 30 * time.Minute, Horizon: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prodSvc := service.NewProductService(testutil.NewProductRepoStub(testutil.SeedProducts()))
+	validator := &testutil.ValidatorStub{Valid: true}
+	now := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	ordSvc := service.NewOrderService(prodSvc, testutil.NewOrderRepoStub(), validator,
+		service.WithSlots(catalogue), service.WithClock(func() time.Time { return now }))
+	cfg := &config.Config{APIKey: "apitest"}
+	logger := util.NewLogger()
+	withSlots := setupRouter(NewHandlers(prodSvc, ordSvc, validator, logger), cfg, logger)
+
+	h, _, _ := setupHandlers(true)
+	withoutSlots := setupRouter(h, cfg, logger)
+
+	tests := []struct {
+		name       string
+		router     *mux.Router
+		target     string
+		wantStatus int
+		wantSlots  int
+	}{
+		{"today", withSlots, "/api/slots?date=2025-06-02", http.StatusOK, 2},
+		{"up to the horizon", withSlots, "/api/slots", http.StatusOK, 2},
+		{"closed day", withSlots, "/api/slots?date=2025-06-05", http.StatusOK, 0},
+		{"bad date", withSlots, "/api/slots?date=today", http.StatusBadRequest, -1},
+		{"not enabled", withoutSlots, "/api/slots", http.StatusNotFound, -1},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != tt.wantStatus {
+			t.Fatalf("%s: want %d, got %d. Body=%s", tt.name, tt.wantStatus, rec.Code, rec.Body.String())
+		}
+		if tt.wantSlots >= 0 {
+			var got struct{ Slots []slots.Slot }
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got.Slots == nil || len(got.Slots) != tt.wantSlots {
+				t.Fatalf("%s: want %d slots, got %s", tt.name, tt.wantSlots, rec.Body.String())
+			}
+		}
+	}
+}
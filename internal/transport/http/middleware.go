@@ -1,11 +1,17 @@
 package transporthttp
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"runtime/debug"
 	"time"
 
+	"github.com/Niraj-Shaw/orderfoodonline/internal/idempotency"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/util"
 )
@@ -54,8 +60,8 @@ func CORSMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, api_key")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, api_key, Idempotency-Key")
 			w.Header().Set("Access-Control-Max-Age", "3600")
 
 			if r.Method == http.MethodOptions {
@@ -95,15 +101,120 @@ func APIKeyMiddleware(requiredAPIKey string, logger util.Logger) func(http.Handl
 }
 
 func sendUnauthorized(w http.ResponseWriter, message string) {
+	sendMiddlewareError(w, http.StatusUnauthorized, "error", message)
+}
+
+func sendMiddlewareError(w http.ResponseWriter, status int, typ, message string) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
+	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(models.ApiResponse{
-		Code:    http.StatusUnauthorized,
-		Type:    "error",
+		Code:    status,
+		Type:    typ,
 		Message: message,
 	})
 }
 
+// --- Idempotency ---
+
+const maxIdempotencyKeyLen = 255
+
+// IdempotencyMiddleware makes requests carrying an "Idempotency-Key" header
+// safe to retry. Keys are scoped to the api_key. The first request's response
+// is stored and replayed (with "Idempotent-Replayed: true") for repeats with
+// the same method, path and body; a repeat with a different request gets 409.
+// Repeats arriving while the first is still running wait for its response.
+// Server errors and 429s are not stored, so those requests can be retried.
+// Bodies over maxBodyBytes, which the handlers would refuse anyway, get 413
+// rather than being buffered.
+func IdempotencyMiddleware(store *idempotency.Store, logger util.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLen {
+				sendMiddlewareError(w, http.StatusBadRequest, "error", "Idempotency-Key is too long")
+				return
+			}
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				sendMiddlewareError(w, http.StatusRequestEntityTooLarge, "error", "Request body too large")
+				return
+			}
+			if err != nil {
+				sendMiddlewareError(w, http.StatusBadRequest, "error", "Invalid input")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			h := sha256.New()
+			h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+			h.Write(body)
+			fingerprint := hex.EncodeToString(h.Sum(nil))
+			scoped := r.Header.Get("api_key") + "\x00" + key
+
+			stored, owner, err := store.Begin(r.Context(), scoped, fingerprint)
+			switch {
+			case errors.Is(err, idempotency.ErrConflict):
+				logger.Warnf("idempotency key reused with a different request: %s %s", r.Method, r.URL.Path)
+				sendMiddlewareError(w, http.StatusConflict, "idempotency_conflict", err.Error())
+				return
+			case err != nil:
+				return // client went away while waiting for the first request
+			case !owner:
+				for k, v := range stored.Header {
+					w.Header()[k] = v
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.Status)
+				_, _ = w.Write(stored.Body)
+				return
+			}
+
+			rec := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if !completed {
+					store.Abort(scoped) // panic, aborted request or uncacheable status
+				}
+			}()
+			next.ServeHTTP(rec, r)
+
+			if !rec.wrote || rec.status >= 500 || rec.status == http.StatusTooManyRequests {
+				return
+			}
+			header := http.Header{}
+			if ct := rec.Header().Get("Content-Type"); ct != "" {
+				header.Set("Content-Type", ct)
+			}
+			store.Complete(scoped, idempotency.Response{Status: rec.status, Header: header, Body: rec.body.Bytes()})
+			completed = true
+		})
+	}
+}
+
+// captureWriter passes a response through while keeping a copy.
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+	body   bytes.Buffer
+}
+
+func (cw *captureWriter) WriteHeader(code int) {
+	cw.status, cw.wrote = code, true
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	cw.wrote = true
+	cw.body.Write(b)
+	return cw.ResponseWriter.Write(b)
+}
+
 // responseWriter captures status code for logging.
 type responseWriter struct {
 	http.ResponseWriter
@@ -2,11 +2,17 @@ package transporthttp
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/Niraj-Shaw/orderfoodonline/internal/idempotency"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/models"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/util"
 )
@@ -196,3 +202,81 @@ func TestAPIKeyMiddleware_Table(t *testing.T) {
 		})
 	}
 }
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		<-release // hold the first request in flight
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"call":%d}`, n)
+	})
+	h := chain(final, IdempotencyMiddleware(idempotency.NewStore(time.Hour), util.NewLogger()))
+
+	send := func(key, apiKey, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/order", strings.NewReader(body))
+		req.Header.Set("api_key", apiKey)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// concurrent duplicates: one runs, the rest wait and replay its response
+	var wg sync.WaitGroup
+	recs := make([]*httptest.ResponseRecorder, 3)
+	for i := range recs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recs[i] = send("k1", "apitest", `{"items":[]}`)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	replayed := 0
+	for _, rec := range recs {
+		if rec.Code != http.StatusOK || rec.Body.String() != `{"call":1}` {
+			t.Fatalf("duplicate got %d %s", rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Idempotent-Replayed") == "true" {
+			replayed++
+		}
+	}
+	if calls.Load() != 1 || replayed != 2 {
+		t.Fatalf("handler ran %d times, %d replays; want 1 and 2", calls.Load(), replayed)
+	}
+
+	tests := []struct {
+		name       string
+		key, api   string
+		body       string
+		wantStatus int
+		wantCalls  int32
+	}{
+		{"different body", "k1", "apitest", `{"items":[1]}`, http.StatusConflict, 1},
+		{"same key, other api_key", "k1", "other", `{"items":[]}`, http.StatusOK, 2},
+		{"no key is not deduplicated", "", "apitest", `{"items":[]}`, http.StatusOK, 3},
+		{"server errors are not stored", "k2", "apitest", "fail", http.StatusInternalServerError, 4},
+		{"so the retry runs again", "k2", "apitest", "fail", http.StatusInternalServerError, 5},
+		{"key too long", strings.Repeat("x", 256), "apitest", "", http.StatusBadRequest, 5},
+		{"body too large", "k3", "apitest", strings.Repeat(" ", maxBodyBytes+1), http.StatusRequestEntityTooLarge, 5},
+	}
+	for _, tt := range tests {
+		rec := send(tt.key, tt.api, tt.body)
+		if rec.Code != tt.wantStatus || calls.Load() != tt.wantCalls {
+			t.Fatalf("%s: got %d after %d calls, want %d after %d", tt.name, rec.Code, calls.Load(), tt.wantStatus, tt.wantCalls)
+		}
+	}
+}
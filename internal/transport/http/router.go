@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Niraj-Shaw/orderfoodonline/internal/config"
+	"github.com/Niraj-Shaw/orderfoodonline/internal/promovalidator"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/service"
 	"github.com/Niraj-Shaw/orderfoodonline/internal/util"
 
@@ -24,9 +25,11 @@ func NewServer(
 	cfg *config.Config,
 	productRepo *service.ProductService,
 	orderService *service.OrderService,
+	validator promovalidator.ValidatorService,
 	logger util.Logger,
+	opts ...HandlerOption,
 ) *Server {
-	h := NewHandlers(productRepo, orderService, logger)
+	h := NewHandlers(productRepo, orderService, validator, logger, opts...)
 	r := setupRouter(h, cfg, logger)
 
 	s := &http.Server{
@@ -58,10 +61,26 @@ func setupRouter(h *Handlers, cfg *config.Config, logger util.Logger) *mux.Route
 	api.HandleFunc("/product", h.ListProducts).Methods(http.MethodGet)
 	api.HandleFunc("/product/{productId}", h.GetProduct).Methods(http.MethodGet)
 
+	// Slots for scheduled orders (public)
+	api.HandleFunc("/slots", h.ListSlots).Methods(http.MethodGet)
+
 	// Order (secured via api_key header)
 	order := api.PathPrefix("").Subrouter()
 	order.Use(APIKeyMiddleware(cfg.APIKey, logger)) // checks header: "api_key"
-	order.HandleFunc("/order", h.PlaceOrder).Methods(http.MethodPost)
+	var placeOrder http.Handler = http.HandlerFunc(h.PlaceOrder)
+	if h.idempotency != nil {
+		placeOrder = IdempotencyMiddleware(h.idempotency, logger)(placeOrder) // retries don't duplicate orders
+	}
+	order.Handle("/order", placeOrder).Methods(http.MethodPost)
+	order.HandleFunc("/order", h.ListOrders).Methods(http.MethodGet)
+	order.HandleFunc("/order/{orderId}", h.GetOrder).Methods(http.MethodGet)
+	order.HandleFunc("/order/{orderId}", h.ModifyOrder).Methods(http.MethodPatch)
+	order.HandleFunc("/order/{orderId}/advance", h.AdvanceOrder).Methods(http.MethodPost)
+	order.HandleFunc("/order/{orderId}/cancel", h.CancelOrder).Methods(http.MethodPost)
+
+	// Promo validator status (secured; reload/checksum info for ops)
+	order.HandleFunc("/promo/status", h.PromoStatus).Methods(http.MethodGet)
+	order.HandleFunc("/promo/lockouts", h.PromoLockouts).Methods(http.MethodGet)
 
 	return router
 }